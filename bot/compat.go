@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package bot
+
+import (
+	"context"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/bot/v1alpha2"
+)
+
+// v1alpha1Plugin adapts v1alpha1 plugins to the v1alpha2 API.
+//
+// The contexts are passed on to the IM provider and forge clients where
+// possible, but v1alpha1 plugins cannot observe them themselves.
+type v1alpha1Plugin struct {
+	inner v1alpha1.IPlugin
+}
+
+var (
+	_ v1alpha2.IPlugin              = (*v1alpha1Plugin)(nil)
+	_ v1alpha2.IForgeClientConsumer = (*v1alpha1Plugin)(nil)
+)
+
+func (p *v1alpha1Plugin) Setup(_ context.Context) error {
+	return p.inner.Setup()
+}
+
+func (p *v1alpha1Plugin) ProcessEvent(ctx context.Context, e *v1alpha1.Event, im v1alpha2.IIMProvider) error {
+	var imV1 v1alpha1.IIMProvider
+	if im != nil {
+		imV1 = &v1alpha1IMProvider{ctx: ctx, inner: im}
+	}
+	return p.inner.ProcessEvent(e, imV1)
+}
+
+func (p *v1alpha1Plugin) Teardown(_ context.Context) error {
+	return p.inner.Teardown()
+}
+
+func (p *v1alpha1Plugin) SetForgeClients(clients map[string]v1alpha2.IForgeClient) {
+	c, ok := p.inner.(v1alpha1.IForgeClientConsumer)
+	if !ok {
+		return
+	}
+
+	clientsV1 := make(map[string]v1alpha1.IForgeClient, len(clients))
+	for k, v := range clients {
+		// Clients are set once for all, so there is no context to bind
+		// other than the background one.
+		clientsV1[k] = &v1alpha1ForgeClient{ctx: context.Background(), inner: v}
+	}
+	c.SetForgeClients(clientsV1)
+}
+
+// v1alpha1IMProvider adapts v1alpha2 IM providers to the v1alpha1 API, with
+// the given context bound.
+type v1alpha1IMProvider struct {
+	ctx   context.Context
+	inner v1alpha2.IIMProvider
+}
+
+var _ v1alpha1.IIMProvider = (*v1alpha1IMProvider)(nil)
+
+func (p *v1alpha1IMProvider) SendTextToPerson(userID string, text string) error {
+	return p.inner.SendTextToPerson(p.ctx, userID, text)
+}
+
+func (p *v1alpha1IMProvider) SendTextToChat(chatID string, text string) error {
+	return p.inner.SendTextToChat(p.ctx, chatID, text)
+}
+
+func (p *v1alpha1IMProvider) SendMarkdownToPerson(userID string, md string) error {
+	return p.inner.SendMarkdownToPerson(p.ctx, userID, md)
+}
+
+func (p *v1alpha1IMProvider) SendMarkdownToChat(chatID string, md string) error {
+	return p.inner.SendMarkdownToChat(p.ctx, chatID, md)
+}
+
+// v1alpha1ForgeClient adapts v1alpha2 forge clients to the v1alpha1 API, with
+// the given context bound.
+type v1alpha1ForgeClient struct {
+	ctx   context.Context
+	inner v1alpha2.IForgeClient
+}
+
+var _ v1alpha1.IForgeClient = (*v1alpha1ForgeClient)(nil)
+
+func (c *v1alpha1ForgeClient) PostComment(
+	repo v1alpha1.Repo,
+	target v1alpha1.CommentTargetType,
+	number int,
+	body string,
+) error {
+	return c.inner.PostComment(c.ctx, repo, target, number, body)
+}
+
+func (c *v1alpha1ForgeClient) AddLabels(
+	repo v1alpha1.Repo,
+	target v1alpha1.CommentTargetType,
+	number int,
+	labels []string,
+) error {
+	return c.inner.AddLabels(c.ctx, repo, target, number, labels)
+}
+
+func (c *v1alpha1ForgeClient) RemoveLabels(
+	repo v1alpha1.Repo,
+	target v1alpha1.CommentTargetType,
+	number int,
+	labels []string,
+) error {
+	return c.inner.RemoveLabels(c.ctx, repo, target, number, labels)
+}
+
+func (c *v1alpha1ForgeClient) RequestReviewers(repo v1alpha1.Repo, number int, reviewers []string) error {
+	return c.inner.RequestReviewers(c.ctx, repo, number, reviewers)
+}
+
+func (c *v1alpha1ForgeClient) SetCommitStatus(repo v1alpha1.Repo, sha string, status v1alpha1.CommitStatus) error {
+	return c.inner.SetCommitStatus(c.ctx, repo, sha, status)
+}
+
+func (c *v1alpha1ForgeClient) GetPR(repo v1alpha1.Repo, number int) (*v1alpha1.PR, error) {
+	return c.inner.GetPR(c.ctx, repo, number)
+}
+
+func (c *v1alpha1ForgeClient) ListReviews(repo v1alpha1.Repo, number int) ([]v1alpha1.Review, error) {
+	return c.inner.ListReviews(c.ctx, repo, number)
+}
+
+func (c *v1alpha1ForgeClient) ListChecks(repo v1alpha1.Repo, sha string) ([]v1alpha1.Check, error) {
+	return c.inner.ListChecks(c.ctx, repo, sha)
+}
@@ -10,13 +10,19 @@ import (
 	"github.com/BurntSushi/toml"
 
 	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/bot/v1alpha2"
 )
 
 type LoadedPlugin struct {
-	configFactoryFn v1alpha1.IPluginConfigFactoryFunc
-	factoryFn       v1alpha1.IPluginFactoryFunc
+	apiVersion      int
+	configFactoryFn func() interface{}
+	// factoryFn is either v1alpha1.IPluginFactoryFunc or
+	// v1alpha2.IPluginFactoryFunc according to apiVersion.
+	factoryFn interface{}
 }
 
+// LoadPlugin loads the plugin at the given path, which may be of any of the
+// supported plugin API versions.
 func LoadPlugin(pluginPath string) (*LoadedPlugin, error) {
 	pl, err := plugin.Open(pluginPath)
 	if err != nil {
@@ -33,7 +39,8 @@ func LoadPlugin(pluginPath string) (*LoadedPlugin, error) {
 		return nil, errors.New("wrong type of BrickbotPluginAPIVersion symbol")
 	}
 
-	if apiVersion == nil || *apiVersion != v1alpha1.PluginAPIVersion {
+	if apiVersion == nil ||
+		(*apiVersion != v1alpha1.PluginAPIVersion && *apiVersion != v1alpha2.PluginAPIVersion) {
 		return nil, errors.New("plugin API version mismatch")
 	}
 
@@ -42,6 +49,7 @@ func LoadPlugin(pluginPath string) (*LoadedPlugin, error) {
 		return nil, err
 	}
 
+	// both versions share the same signature
 	pluginConfigFactoryFn, ok := pluginConfigFactoryFnSym.(v1alpha1.IPluginConfigFactoryFunc)
 	if !ok {
 		return nil, errors.New("wrong type of BrickbotPluginConfigFactory symbol")
@@ -52,18 +60,29 @@ func LoadPlugin(pluginPath string) (*LoadedPlugin, error) {
 		return nil, err
 	}
 
-	pluginFactoryFn, ok := pluginFactoryFnSym.(v1alpha1.IPluginFactoryFunc)
+	var pluginFactoryFn interface{}
+	switch *apiVersion {
+	case v1alpha1.PluginAPIVersion:
+		pluginFactoryFn, ok = pluginFactoryFnSym.(v1alpha1.IPluginFactoryFunc)
+	case v1alpha2.PluginAPIVersion:
+		pluginFactoryFn, ok = pluginFactoryFnSym.(v1alpha2.IPluginFactoryFunc)
+	default:
+		panic("should never happen")
+	}
 	if !ok {
 		return nil, errors.New("wrong type of BrickbotPluginFactory symbol")
 	}
 
 	return &LoadedPlugin{
+		apiVersion:      *apiVersion,
 		configFactoryFn: pluginConfigFactoryFn,
 		factoryFn:       pluginFactoryFn,
 	}, nil
 }
 
-func (p *LoadedPlugin) InitWithConfigTOML(configPath string) (v1alpha1.IPlugin, error) {
+// InitWithConfigTOML constructs the plugin with its config read from the
+// given TOML file. v1alpha1 plugins are adapted to the v1alpha2 API.
+func (p *LoadedPlugin) InitWithConfigTOML(configPath string) (v1alpha2.IPlugin, error) {
 	// get concrete type for unmarshaling
 	configTypeTemplate := p.configFactoryFn()
 	rv := reflect.New(reflect.TypeOf(configTypeTemplate))
@@ -73,5 +92,18 @@ func (p *LoadedPlugin) InitWithConfigTOML(configPath string) (v1alpha1.IPlugin,
 		return nil, err
 	}
 
-	return p.factoryFn(rv.Elem().Interface())
+	switch fn := p.factoryFn.(type) {
+	case v1alpha1.IPluginFactoryFunc:
+		inner, err := fn(rv.Elem().Interface())
+		if err != nil {
+			return nil, err
+		}
+		return &v1alpha1Plugin{inner: inner}, nil
+
+	case v1alpha2.IPluginFactoryFunc:
+		return fn(rv.Elem().Interface())
+
+	default:
+		panic("should never happen")
+	}
 }
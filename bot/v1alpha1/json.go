@@ -0,0 +1,217 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// EventJSONVersion is the version of the JSON encoding of events, bumped on
+// incompatible changes.
+const EventJSONVersion = 1
+
+var (
+	ErrUnknownEventType       = errors.New("v1alpha1: unknown event type")
+	ErrUnsupportedJSONVersion = errors.New("v1alpha1: unsupported event JSON version")
+)
+
+// eventTypeNames are the type discriminators of events in JSON.
+var eventTypeNames = map[EventType]string{
+	EventTypeWebhookInstalled:        "webhook_installed",
+	EventTypePROpened:                "pr_opened",
+	EventTypePRClosed:                "pr_closed",
+	EventTypePRMerged:                "pr_merged",
+	EventTypePRRenamed:               "pr_renamed",
+	EventTypePRReviewed:              "pr_reviewed",
+	EventTypePRReady:                 "pr_ready",
+	EventTypePRWithdrawn:             "pr_withdrawn",
+	EventTypeCIFinished:              "ci_finished",
+	EventTypeReviewPing:              "review_ping",
+	EventTypePRReviewRequested:       "pr_review_requested",
+	EventTypePRReviewRequestRemoved:  "pr_review_request_removed",
+	EventTypeIssueOpened:             "issue_opened",
+	EventTypeIssueClosed:             "issue_closed",
+	EventTypeIssueReopened:           "issue_reopened",
+	EventTypeIssueAssigned:           "issue_assigned",
+	EventTypeIssueLabeled:            "issue_labeled",
+	EventTypeIssueRenamed:            "issue_renamed",
+	EventTypeCommentCreated:          "comment_created",
+	EventTypePush:                    "push",
+	EventTypeBranchCreated:           "branch_created",
+	EventTypeBranchDeleted:           "branch_deleted",
+	EventTypeTagCreated:              "tag_created",
+	EventTypeReleasePublished:        "release_published",
+	EventTypeDeploymentCreated:       "deployment_created",
+	EventTypeDeploymentStatusChanged: "deployment_status_changed",
+	EventTypePRUpdated:               "pr_updated",
+	EventTypeAppInstalled:            "app_installed",
+	EventTypeAppUninstalled:          "app_uninstalled",
+	EventTypeAppReposAdded:           "app_repos_added",
+	EventTypeAppReposRemoved:         "app_repos_removed",
+}
+
+// newParamsForEventType returns a pointer to the zero value of the event
+// type's params, or nil if the type is unknown.
+func newParamsForEventType(t EventType) interface{} {
+	switch t {
+	case EventTypeWebhookInstalled:
+		return &WebhookInstalledParams{}
+	case EventTypePROpened:
+		return &PROpenedParams{}
+	case EventTypePRClosed:
+		return &PRClosedParams{}
+	case EventTypePRMerged:
+		return &PRMergedParams{}
+	case EventTypePRRenamed:
+		return &PRRenamedParams{}
+	case EventTypePRReviewed:
+		return &PRReviewedParams{}
+	case EventTypePRReady:
+		return &PRReadyParams{}
+	case EventTypePRWithdrawn:
+		return &PRWithdrawnParams{}
+	case EventTypeCIFinished:
+		return &CIFinishedParams{}
+	case EventTypeReviewPing:
+		return &ReviewPingParams{}
+	case EventTypePRReviewRequested:
+		return &PRReviewRequestedParams{}
+	case EventTypePRReviewRequestRemoved:
+		return &PRReviewRequestRemovedParams{}
+	case EventTypeIssueOpened:
+		return &IssueOpenedParams{}
+	case EventTypeIssueClosed:
+		return &IssueClosedParams{}
+	case EventTypeIssueReopened:
+		return &IssueReopenedParams{}
+	case EventTypeIssueAssigned:
+		return &IssueAssignedParams{}
+	case EventTypeIssueLabeled:
+		return &IssueLabeledParams{}
+	case EventTypeIssueRenamed:
+		return &IssueRenamedParams{}
+	case EventTypeCommentCreated:
+		return &CommentCreatedParams{}
+	case EventTypePush:
+		return &PushParams{}
+	case EventTypeBranchCreated:
+		return &BranchCreatedParams{}
+	case EventTypeBranchDeleted:
+		return &BranchDeletedParams{}
+	case EventTypeTagCreated:
+		return &TagCreatedParams{}
+	case EventTypeReleasePublished:
+		return &ReleasePublishedParams{}
+	case EventTypeDeploymentCreated:
+		return &DeploymentCreatedParams{}
+	case EventTypeDeploymentStatusChanged:
+		return &DeploymentStatusChangedParams{}
+	case EventTypePRUpdated:
+		return &PRUpdatedParams{}
+	case EventTypeAppInstalled:
+		return &AppInstalledParams{}
+	case EventTypeAppUninstalled:
+		return &AppUninstalledParams{}
+	case EventTypeAppReposAdded:
+		return &AppReposAddedParams{}
+	case EventTypeAppReposRemoved:
+		return &AppReposRemovedParams{}
+	default:
+		return nil
+	}
+}
+
+func eventTypeFromName(name string) (EventType, bool) {
+	for t, n := range eventTypeNames {
+		if n == name {
+			return t, true
+		}
+	}
+	return EventTypeUnknown, false
+}
+
+// eventJSON is the JSON representation of events.
+//
+// The params are encoded with the Go field names of the params structs.
+type eventJSON struct {
+	Version int             `json:"version"`
+	Type    string          `json:"type"`
+	Params  json.RawMessage `json:"params"`
+
+	ID            string    `json:"id,omitempty"`
+	ReceivedAt    time.Time `json:"received_at"`
+	ForgeInstance string    `json:"forge_instance,omitempty"`
+	ForgeEvent    string    `json:"forge_event,omitempty"`
+	ForgeAction   string    `json:"forge_action,omitempty"`
+	DeliveryID    string    `json:"delivery_id,omitempty"`
+	// RawPayload is base64-encoded, as payloads are not necessarily JSON.
+	RawPayload []byte `json:"raw_payload,omitempty"`
+}
+
+// MarshalJSON encodes the event along with its metadata, with its type as the
+// discriminator of the params.
+func (e *Event) MarshalJSON() ([]byte, error) {
+	name, ok := eventTypeNames[e.Type()]
+	if !ok {
+		return nil, ErrUnknownEventType
+	}
+
+	params, err := json.Marshal(e.inner)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(&eventJSON{
+		Version: EventJSONVersion,
+		Type:    name,
+		Params:  params,
+
+		ID:            e.id,
+		ReceivedAt:    e.receivedAt,
+		ForgeInstance: e.forgeInstance,
+		ForgeEvent:    e.forgeEvent,
+		ForgeAction:   e.forgeAction,
+		DeliveryID:    e.deliveryID,
+		RawPayload:    e.rawPayload,
+	})
+}
+
+// UnmarshalJSON decodes events encoded by MarshalJSON.
+func (e *Event) UnmarshalJSON(b []byte) error {
+	var x eventJSON
+	err := json.Unmarshal(b, &x)
+	if err != nil {
+		return err
+	}
+
+	if x.Version != EventJSONVersion {
+		return fmt.Errorf("%w: %d", ErrUnsupportedJSONVersion, x.Version)
+	}
+
+	t, ok := eventTypeFromName(x.Type)
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownEventType, x.Type)
+	}
+
+	params := newParamsForEventType(t)
+	err = json.Unmarshal(x.Params, params)
+	if err != nil {
+		return err
+	}
+
+	*e = Event{
+		inner: params,
+
+		id:            x.ID,
+		receivedAt:    x.ReceivedAt,
+		forgeInstance: x.ForgeInstance,
+		forgeEvent:    x.ForgeEvent,
+		forgeAction:   x.ForgeAction,
+		rawPayload:    x.RawPayload,
+		deliveryID:    x.DeliveryID,
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// fillValue sets v and everything in it to distinct non-zero values, so that
+// fields lost in encoding do not go unnoticed.
+func fillValue(v reflect.Value, n *int) {
+	*n++
+
+	if v.Type() == reflect.TypeOf(time.Time{}) {
+		t := time.Date(2023, 9, 12, 8, 15, 3, 0, time.UTC).Add(time.Duration(*n) * time.Second)
+		v.Set(reflect.ValueOf(t))
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString("s" + strconv.Itoa(*n))
+	case reflect.Bool:
+		v.SetBool(true)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v.SetInt(int64(*n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(*n))
+	case reflect.Float32, reflect.Float64:
+		v.SetFloat(float64(*n) + 0.5)
+	case reflect.Ptr:
+		v.Set(reflect.New(v.Type().Elem()))
+		fillValue(v.Elem(), n)
+	case reflect.Slice:
+		v.Set(reflect.MakeSlice(v.Type(), 2, 2))
+		for i := 0; i < v.Len(); i++ {
+			fillValue(v.Index(i), n)
+		}
+	case reflect.Map:
+		v.Set(reflect.MakeMap(v.Type()))
+		key := reflect.New(v.Type().Key()).Elem()
+		elem := reflect.New(v.Type().Elem()).Elem()
+		fillValue(key, n)
+		fillValue(elem, n)
+		v.SetMapIndex(key, elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			fillValue(v.Field(i), n)
+		}
+	default:
+		panic("unsupported kind " + v.Kind().String())
+	}
+}
+
+func TestEventJSONRoundTrip(t *testing.T) {
+	// not valid JSON nor UTF-8, as payloads are kept as received
+	rawPayload := []byte("event=push&sig=\xff\x00\x80")
+	receivedAt := time.Date(2023, 9, 12, 9, 2, 41, 123456789, time.UTC)
+
+	for et, name := range eventTypeNames {
+		et, name := et, name
+		t.Run(name, func(t *testing.T) {
+			params := newParamsForEventType(et)
+			n := 0
+			fillValue(reflect.ValueOf(params).Elem(), &n)
+
+			e := params.(interface{ IntoEvent() *Event }).IntoEvent()
+			e.SetID("0b5f3a8e-4c1d-4e0f-9a57-3f1c2d7e8b90")
+			e.SetReceivedAt(receivedAt)
+			e.SetForgeInstance("github-acme")
+			e.SetForgeEvent("pull_request", "opened")
+			e.SetDeliveryID("72d3162e-cc78-11e3-81ab-4c9367dc0958")
+			e.SetRawPayload(rawPayload)
+
+			b, err := json.Marshal(e)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+
+			var got Event
+			err = json.Unmarshal(b, &got)
+			if err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+
+			if got.Type() != et {
+				t.Errorf("got type %d, want %d", got.Type(), et)
+			}
+			if !reflect.DeepEqual(&got, e) {
+				t.Errorf("round trip mismatch\n got: %+v\nwant: %+v", &got, e)
+			}
+		})
+	}
+}
+
+func TestEventJSONErrors(t *testing.T) {
+	_, err := json.Marshal(&Event{})
+	if !errors.Is(err, ErrUnknownEventType) {
+		t.Errorf("Marshal of event without params: want ErrUnknownEventType, got %v", err)
+	}
+
+	testcases := []struct {
+		name string
+		in   string
+		want error
+	}{
+		{
+			name: "unknown type",
+			in:   `{"version":1,"type":"pr_exploded","params":{}}`,
+			want: ErrUnknownEventType,
+		},
+		{
+			name: "newer version",
+			in:   `{"version":2,"type":"pr_opened","params":{}}`,
+			want: ErrUnsupportedJSONVersion,
+		},
+		{
+			name: "missing version",
+			in:   `{"type":"pr_opened","params":{}}`,
+			want: ErrUnsupportedJSONVersion,
+		},
+	}
+
+	for _, tc := range testcases {
+		var e Event
+		err := json.Unmarshal([]byte(tc.in), &e)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+// TestEventTypeCoverage checks that every event type with params has a JSON
+// name, and the other way round.
+func TestEventTypeCoverage(t *testing.T) {
+	// event types are small integers assigned explicitly
+	for et := EventType(0); et < 256; et++ {
+		params := newParamsForEventType(et)
+		_, hasName := eventTypeNames[et]
+
+		switch {
+		case params == nil && hasName:
+			t.Errorf("event type %d has a JSON name but no params in newParamsForEventType", et)
+		case params != nil && !hasName:
+			t.Errorf("event type %d has params but no JSON name in eventTypeNames", et)
+		case params != nil:
+			got := params.(interface{ IntoEvent() *Event }).IntoEvent().Type()
+			if got != et {
+				t.Errorf("newParamsForEventType(%d) returned params of event type %d", et, got)
+			}
+		}
+	}
+}
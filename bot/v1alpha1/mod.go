@@ -2,6 +2,8 @@
 
 package v1alpha1
 
+import "time"
+
 const PluginAPIVersion = 1
 
 type EventType int
@@ -19,6 +21,34 @@ const (
 	EventTypePRWithdrawn      EventType = 8
 	EventTypeCIFinished       EventType = 9
 	EventTypeReviewPing       EventType = 10
+
+	EventTypePRReviewRequested      EventType = 11
+	EventTypePRReviewRequestRemoved EventType = 12
+
+	EventTypeIssueOpened   EventType = 13
+	EventTypeIssueClosed   EventType = 14
+	EventTypeIssueReopened EventType = 15
+	EventTypeIssueAssigned EventType = 16
+	EventTypeIssueLabeled  EventType = 17
+	EventTypeIssueRenamed  EventType = 18
+
+	EventTypeCommentCreated EventType = 19
+
+	EventTypePush          EventType = 20
+	EventTypeBranchCreated EventType = 21
+	EventTypeBranchDeleted EventType = 22
+	EventTypeTagCreated    EventType = 23
+
+	EventTypeReleasePublished        EventType = 24
+	EventTypeDeploymentCreated       EventType = 25
+	EventTypeDeploymentStatusChanged EventType = 26
+
+	EventTypePRUpdated EventType = 27
+
+	EventTypeAppInstalled    EventType = 28
+	EventTypeAppUninstalled  EventType = 29
+	EventTypeAppReposAdded   EventType = 30
+	EventTypeAppReposRemoved EventType = 31
 )
 
 type IssueState int
@@ -35,10 +65,18 @@ type CIState int
 
 // All CI states.
 const (
-	CIStateUnknown CIState = 0
-	CIStatePassed  CIState = 1
-	CIStateFailed  CIState = 2
-	CIStateErrored CIState = 3
+	CIStateUnknown  CIState = 0
+	CIStatePassed   CIState = 1
+	CIStateFailed   CIState = 2
+	CIStateErrored  CIState = 3
+	CIStateCanceled CIState = 4
+	CIStateSkipped  CIState = 5
+	// CIStateActionRequired means the run needs manual intervention to
+	// proceed.
+	CIStateActionRequired CIState = 6
+	// CIStatePending means the run is not finished yet. Events are only
+	// sent for finished runs, so this is only seen with IForgeClient.
+	CIStatePending CIState = 7
 )
 
 type ReviewType int
@@ -49,6 +87,30 @@ const (
 	ReviewTypeComment        ReviewType = 1
 	ReviewTypeApprove        ReviewType = 2
 	ReviewTypeRequestChanges ReviewType = 3
+	ReviewTypeDismiss        ReviewType = 4
+)
+
+type DeploymentState int
+
+// All deployment states.
+const (
+	DeploymentStateUnknown    DeploymentState = 0
+	DeploymentStatePending    DeploymentState = 1
+	DeploymentStateInProgress DeploymentState = 2
+	DeploymentStateSuccess    DeploymentState = 3
+	DeploymentStateFailure    DeploymentState = 4
+	DeploymentStateError      DeploymentState = 5
+	DeploymentStateCanceled   DeploymentState = 6
+	DeploymentStateInactive   DeploymentState = 7
+)
+
+type CommentTargetType int
+
+// All comment target types.
+const (
+	CommentTargetTypeUnknown CommentTargetType = 0
+	CommentTargetTypeIssue   CommentTargetType = 1
+	CommentTargetTypePR      CommentTargetType = 2
 )
 
 type ForgeUser struct {
@@ -68,12 +130,136 @@ type PR struct {
 	Author ForgeUser
 	State  IssueState
 	URL    string
+	Body   string
+
+	// BaseBranch is the branch the PR is to be merged into.
+	BaseBranch string
+	// HeadBranch is the branch containing the PR's changes.
+	HeadBranch string
+	HeadSHA    string
+	IsDraft    bool
+
+	Labels             []string
+	Assignees          []ForgeUser
+	RequestedReviewers []ForgeUser
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+
+	// Additions and Deletions are the changed line counts, or zero if the
+	// forge does not provide them.
+	Additions int
+	Deletions int
+}
+
+type Issue struct {
+	Repo      Repo
+	Number    int
+	Title     string
+	Author    ForgeUser
+	State     IssueState
+	URL       string
+	Body      string
+	Labels    []string
+	Assignees []ForgeUser
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type Comment struct {
+	Author    ForgeUser
+	Body      string
+	URL       string
+	CreatedAt time.Time
+
+	// Path and Line are the file path and line number commented on, for
+	// inline review comments. Both are zero values otherwise.
+	Path string
+	Line int
+}
+
+type Commit struct {
+	SHA     string
+	Message string
+	URL     string
+	// Author is the forge user matching the commit's author, if the forge
+	// could determine one; AuthorName and AuthorEmail are always from the
+	// commit itself.
+	Author      ForgeUser
+	AuthorName  string
+	AuthorEmail string
+	Timestamp   time.Time
+}
+
+type Release struct {
+	Repo         Repo
+	Tag          string
+	Name         string
+	Notes        string
+	IsPrerelease bool
+	// Author is the zero value if the forge does not report it.
+	Author    ForgeUser
+	URL       string
+	CreatedAt time.Time
+}
+
+// AppInstallation is an installation of the bot as a forge app (e.g. a GitHub
+// App) on some account.
+type AppInstallation struct {
+	ID int64
+	// Account is the user or organization the app is installed on.
+	Account ForgeUser
+}
+
+type Deployment struct {
+	Repo        Repo
+	ID          int64
+	Environment string
+	Ref         string
+	SHA         string
+	// Creator is the zero value if the forge does not report it.
+	Creator ForgeUser
+	URL     string
 }
 
 type CIRun struct {
-	Repo  Repo
-	PR    PR
+	Repo Repo
+	// PR is the first of PRs, or the zero value if the run is not associated
+	// with any PR.
+	PR PR
+	// PRs are all the open PRs the run is associated with, as a commit can be
+	// the head of several PRs.
+	PRs   []PR
 	State CIState
+	// SHA is the commit the run is for.
+	SHA string
+	URL string
+}
+
+// Review is a submitted review of a PR, as returned by IForgeClient.
+type Review struct {
+	Author      ForgeUser
+	Type        ReviewType
+	Body        string
+	URL         string
+	SubmittedAt time.Time
+}
+
+// Check is a CI check or commit status reported for a commit, as returned by
+// IForgeClient.
+type Check struct {
+	Name  string
+	State CIState
+	URL   string
+}
+
+// CommitStatus is a commit status to report with IForgeClient.
+type CommitStatus struct {
+	State CIState
+	// Context tells statuses of different CI jobs or tools apart.
+	Context     string
+	Description string
+	URL         string
 }
 
 type WebhookInstalledParams struct {
@@ -89,6 +275,8 @@ func (x *WebhookInstalledParams) IntoEvent() *Event {
 type PROpenedParams struct {
 	Actor ForgeUser
 	PR    PR
+	// IsDraft is whether the PR is opened as a draft.
+	IsDraft bool
 }
 
 func (x *PROpenedParams) IntoEvent() *Event {
@@ -120,8 +308,9 @@ func (x *PRMergedParams) IntoEvent() *Event {
 }
 
 type PRRenamedParams struct {
-	Actor ForgeUser
-	PR    PR
+	Actor    ForgeUser
+	PR       PR
+	OldTitle string
 }
 
 func (x *PRRenamedParams) IntoEvent() *Event {
@@ -163,6 +352,25 @@ func (x *PRWithdrawnParams) IntoEvent() *Event {
 	}
 }
 
+// PRUpdatedParams is for when new commits are pushed to a PR.
+//
+// This is only sent by the Bitbucket and Gerrit forges. With other forges,
+// watch for PushParams of the PR head branch instead.
+//
+// Bitbucket Cloud does not tell what is updated, so it also sends this for
+// other changes such as editing the description. Gerrit sends this for every
+// new patch set after the first.
+type PRUpdatedParams struct {
+	Actor ForgeUser
+	PR    PR
+}
+
+func (x *PRUpdatedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
 type CIFinishedParams struct {
 	Run CIRun
 }
@@ -184,8 +392,364 @@ func (x *ReviewPingParams) IntoEvent() *Event {
 	}
 }
 
+type PRReviewRequestedParams struct {
+	Actor ForgeUser
+	PR    PR
+	// Reviewers are the users whose reviews are newly requested.
+	Reviewers []ForgeUser
+	// Teams are the names of the teams whose reviews are newly requested.
+	Teams []string
+}
+
+func (x *PRReviewRequestedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type PRReviewRequestRemovedParams struct {
+	Actor ForgeUser
+	PR    PR
+	// Reviewers are the users whose review requests are removed.
+	Reviewers []ForgeUser
+	// Teams are the names of the teams whose review requests are removed.
+	Teams []string
+}
+
+func (x *PRReviewRequestRemovedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type IssueOpenedParams struct {
+	Actor ForgeUser
+	Issue Issue
+}
+
+func (x *IssueOpenedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type IssueClosedParams struct {
+	Actor ForgeUser
+	Issue Issue
+}
+
+func (x *IssueClosedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type IssueReopenedParams struct {
+	Actor ForgeUser
+	Issue Issue
+}
+
+func (x *IssueReopenedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type IssueAssignedParams struct {
+	Actor ForgeUser
+	Issue Issue
+	// Assignees are the users newly assigned to the issue.
+	Assignees []ForgeUser
+}
+
+func (x *IssueAssignedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type IssueLabeledParams struct {
+	Actor ForgeUser
+	Issue Issue
+	// Labels are the labels newly added to the issue.
+	Labels []string
+}
+
+func (x *IssueLabeledParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type IssueRenamedParams struct {
+	Actor    ForgeUser
+	Issue    Issue
+	OldTitle string
+}
+
+func (x *IssueRenamedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type CommentCreatedParams struct {
+	Actor  ForgeUser
+	Target CommentTargetType
+	// Issue is only valid if Target is CommentTargetTypeIssue.
+	Issue Issue
+	// PR is only valid if Target is CommentTargetTypePR.
+	PR      PR
+	Comment Comment
+}
+
+func (x *CommentCreatedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+// PushParams is for pushes to existing branches. Creation and deletion of
+// branches are reported with separate events.
+type PushParams struct {
+	Actor ForgeUser
+	Repo  Repo
+	// Ref is the full ref pushed to, e.g. "refs/heads/main".
+	Ref     string
+	Before  string
+	After   string
+	Commits []Commit
+	// Forced is whether the push is a force-push. Not all forges report
+	// this, in which case it is always false.
+	Forced bool
+}
+
+func (x *PushParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type BranchCreatedParams struct {
+	Actor  ForgeUser
+	Repo   Repo
+	Branch string
+	// SHA is the commit the new branch points to, if known.
+	SHA string
+}
+
+func (x *BranchCreatedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type BranchDeletedParams struct {
+	Actor  ForgeUser
+	Repo   Repo
+	Branch string
+}
+
+func (x *BranchDeletedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type TagCreatedParams struct {
+	Actor ForgeUser
+	Repo  Repo
+	Tag   string
+	// SHA is the object the new tag points to, if known.
+	SHA string
+}
+
+func (x *TagCreatedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type ReleasePublishedParams struct {
+	Actor   ForgeUser
+	Release Release
+}
+
+func (x *ReleasePublishedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type DeploymentCreatedParams struct {
+	Actor      ForgeUser
+	Deployment Deployment
+}
+
+func (x *DeploymentCreatedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type DeploymentStatusChangedParams struct {
+	Actor      ForgeUser
+	Deployment Deployment
+	State      DeploymentState
+	// Description is the status' description, if any.
+	Description string
+	// URL is where the deployment's output or logs can be seen, if any.
+	URL string
+}
+
+func (x *DeploymentStatusChangedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type AppInstalledParams struct {
+	Actor        ForgeUser
+	Installation AppInstallation
+	// Repos are the repos the app is granted access to, which is empty if
+	// the app is granted access to all repos of the account.
+	Repos []Repo
+}
+
+func (x *AppInstalledParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type AppUninstalledParams struct {
+	Actor        ForgeUser
+	Installation AppInstallation
+}
+
+func (x *AppUninstalledParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type AppReposAddedParams struct {
+	Actor        ForgeUser
+	Installation AppInstallation
+	Repos        []Repo
+}
+
+func (x *AppReposAddedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
+type AppReposRemovedParams struct {
+	Actor        ForgeUser
+	Installation AppInstallation
+	Repos        []Repo
+}
+
+func (x *AppReposRemovedParams) IntoEvent() *Event {
+	return &Event{
+		inner: x,
+	}
+}
+
 type Event struct {
 	inner interface{}
+
+	id            string
+	receivedAt    time.Time
+	forgeInstance string
+	forgeEvent    string
+	forgeAction   string
+	rawPayload    []byte
+	deliveryID    string
+}
+
+// ID returns the unique ID of the event, for correlating log entries and the
+// like.
+func (e *Event) ID() string {
+	return e.id
+}
+
+// SetID sets the unique ID of the event. It is meant for the bot server.
+func (e *Event) SetID(id string) {
+	e.id = id
+}
+
+// ReceivedAt returns when the webhook delivery the event comes from is
+// received.
+func (e *Event) ReceivedAt() time.Time {
+	return e.receivedAt
+}
+
+// SetReceivedAt records when the event is received. It is meant for the bot
+// server.
+func (e *Event) SetReceivedAt(t time.Time) {
+	e.receivedAt = t
+}
+
+// ForgeInstance returns the name of the configured forge endpoint the event
+// comes from, e.g. "github", or the name of a generic webhook endpoint.
+func (e *Event) ForgeInstance() string {
+	return e.forgeInstance
+}
+
+// SetForgeInstance records the forge endpoint the event comes from. It is
+// meant for the bot server.
+func (e *Event) SetForgeInstance(name string) {
+	e.forgeInstance = name
+}
+
+// ForgeEvent returns the forge-specific name of the webhook event, e.g.
+// "pull_request" for GitHub, or the empty string if unknown.
+func (e *Event) ForgeEvent() string {
+	return e.forgeEvent
+}
+
+// ForgeAction returns the forge-specific action of the webhook event, e.g.
+// "opened" for GitHub, or the empty string if unknown or if the forge has no
+// such concept for the event.
+func (e *Event) ForgeAction() string {
+	return e.forgeAction
+}
+
+// SetForgeEvent records the forge-specific name and action of the webhook
+// event. It is meant for forge hooks.
+func (e *Event) SetForgeEvent(name string, action string) {
+	e.forgeEvent = name
+	e.forgeAction = action
+}
+
+// RawPayload returns the raw payload of the webhook delivery, usually JSON,
+// for accessing information not carried by the event params.
+//
+// The returned slice must not be modified.
+func (e *Event) RawPayload() []byte {
+	return e.rawPayload
+}
+
+// SetRawPayload records the raw payload of the webhook delivery. It is meant
+// for forge hooks.
+func (e *Event) SetRawPayload(payload []byte) {
+	e.rawPayload = payload
+}
+
+// DeliveryID returns the forge-assigned ID of the webhook delivery the event
+// comes from, or the empty string if the forge does not provide one.
+//
+// Retried deliveries share the same ID.
+func (e *Event) DeliveryID() string {
+	return e.deliveryID
+}
+
+// SetDeliveryID records the ID of the webhook delivery the event comes from.
+// It is meant for forge hooks.
+func (e *Event) SetDeliveryID(id string) {
+	e.deliveryID = id
 }
 
 func (e *Event) Type() EventType {
@@ -210,6 +774,48 @@ func (e *Event) Type() EventType {
 		return EventTypeCIFinished
 	case *ReviewPingParams:
 		return EventTypeReviewPing
+	case *PRReviewRequestedParams:
+		return EventTypePRReviewRequested
+	case *PRReviewRequestRemovedParams:
+		return EventTypePRReviewRequestRemoved
+	case *IssueOpenedParams:
+		return EventTypeIssueOpened
+	case *IssueClosedParams:
+		return EventTypeIssueClosed
+	case *IssueReopenedParams:
+		return EventTypeIssueReopened
+	case *IssueAssignedParams:
+		return EventTypeIssueAssigned
+	case *IssueLabeledParams:
+		return EventTypeIssueLabeled
+	case *IssueRenamedParams:
+		return EventTypeIssueRenamed
+	case *CommentCreatedParams:
+		return EventTypeCommentCreated
+	case *PushParams:
+		return EventTypePush
+	case *BranchCreatedParams:
+		return EventTypeBranchCreated
+	case *BranchDeletedParams:
+		return EventTypeBranchDeleted
+	case *TagCreatedParams:
+		return EventTypeTagCreated
+	case *ReleasePublishedParams:
+		return EventTypeReleasePublished
+	case *DeploymentCreatedParams:
+		return EventTypeDeploymentCreated
+	case *DeploymentStatusChangedParams:
+		return EventTypeDeploymentStatusChanged
+	case *PRUpdatedParams:
+		return EventTypePRUpdated
+	case *AppInstalledParams:
+		return EventTypeAppInstalled
+	case *AppUninstalledParams:
+		return EventTypeAppUninstalled
+	case *AppReposAddedParams:
+		return EventTypeAppReposAdded
+	case *AppReposRemovedParams:
+		return EventTypeAppReposRemoved
 	default:
 		return EventTypeUnknown
 	}
@@ -265,6 +871,111 @@ func (e *Event) ReviewPing() (*ReviewPingParams, bool) {
 	return params, ok
 }
 
+func (e *Event) PRReviewRequested() (*PRReviewRequestedParams, bool) {
+	params, ok := e.inner.(*PRReviewRequestedParams)
+	return params, ok
+}
+
+func (e *Event) PRReviewRequestRemoved() (*PRReviewRequestRemovedParams, bool) {
+	params, ok := e.inner.(*PRReviewRequestRemovedParams)
+	return params, ok
+}
+
+func (e *Event) IssueOpened() (*IssueOpenedParams, bool) {
+	params, ok := e.inner.(*IssueOpenedParams)
+	return params, ok
+}
+
+func (e *Event) IssueClosed() (*IssueClosedParams, bool) {
+	params, ok := e.inner.(*IssueClosedParams)
+	return params, ok
+}
+
+func (e *Event) IssueReopened() (*IssueReopenedParams, bool) {
+	params, ok := e.inner.(*IssueReopenedParams)
+	return params, ok
+}
+
+func (e *Event) IssueAssigned() (*IssueAssignedParams, bool) {
+	params, ok := e.inner.(*IssueAssignedParams)
+	return params, ok
+}
+
+func (e *Event) IssueLabeled() (*IssueLabeledParams, bool) {
+	params, ok := e.inner.(*IssueLabeledParams)
+	return params, ok
+}
+
+func (e *Event) IssueRenamed() (*IssueRenamedParams, bool) {
+	params, ok := e.inner.(*IssueRenamedParams)
+	return params, ok
+}
+
+func (e *Event) CommentCreated() (*CommentCreatedParams, bool) {
+	params, ok := e.inner.(*CommentCreatedParams)
+	return params, ok
+}
+
+func (e *Event) Push() (*PushParams, bool) {
+	params, ok := e.inner.(*PushParams)
+	return params, ok
+}
+
+func (e *Event) BranchCreated() (*BranchCreatedParams, bool) {
+	params, ok := e.inner.(*BranchCreatedParams)
+	return params, ok
+}
+
+func (e *Event) BranchDeleted() (*BranchDeletedParams, bool) {
+	params, ok := e.inner.(*BranchDeletedParams)
+	return params, ok
+}
+
+func (e *Event) TagCreated() (*TagCreatedParams, bool) {
+	params, ok := e.inner.(*TagCreatedParams)
+	return params, ok
+}
+
+func (e *Event) ReleasePublished() (*ReleasePublishedParams, bool) {
+	params, ok := e.inner.(*ReleasePublishedParams)
+	return params, ok
+}
+
+func (e *Event) DeploymentCreated() (*DeploymentCreatedParams, bool) {
+	params, ok := e.inner.(*DeploymentCreatedParams)
+	return params, ok
+}
+
+func (e *Event) DeploymentStatusChanged() (*DeploymentStatusChangedParams, bool) {
+	params, ok := e.inner.(*DeploymentStatusChangedParams)
+	return params, ok
+}
+
+func (e *Event) PRUpdated() (*PRUpdatedParams, bool) {
+	params, ok := e.inner.(*PRUpdatedParams)
+	return params, ok
+}
+
+func (e *Event) AppInstalled() (*AppInstalledParams, bool) {
+	params, ok := e.inner.(*AppInstalledParams)
+	return params, ok
+}
+
+func (e *Event) AppUninstalled() (*AppUninstalledParams, bool) {
+	params, ok := e.inner.(*AppUninstalledParams)
+	return params, ok
+}
+
+func (e *Event) AppReposAdded() (*AppReposAddedParams, bool) {
+	params, ok := e.inner.(*AppReposAddedParams)
+	return params, ok
+}
+
+func (e *Event) AppReposRemoved() (*AppReposRemovedParams, bool) {
+	params, ok := e.inner.(*AppReposRemovedParams)
+	return params, ok
+}
+
 type IIMProvider interface {
 	SendTextToPerson(userID string, text string) error
 	SendTextToChat(chatID string, text string) error
@@ -272,6 +983,21 @@ type IIMProvider interface {
 	SendMarkdownToChat(chatID string, md string) error
 }
 
+// IForgeClient is abstraction for acting on forges through their APIs.
+//
+// The number is that of an issue or PR according to target, for methods
+// accepting both.
+type IForgeClient interface {
+	PostComment(repo Repo, target CommentTargetType, number int, body string) error
+	AddLabels(repo Repo, target CommentTargetType, number int, labels []string) error
+	RemoveLabels(repo Repo, target CommentTargetType, number int, labels []string) error
+	RequestReviewers(repo Repo, number int, reviewers []string) error
+	SetCommitStatus(repo Repo, sha string, status CommitStatus) error
+	GetPR(repo Repo, number int) (*PR, error)
+	ListReviews(repo Repo, number int) ([]Review, error)
+	ListChecks(repo Repo, sha string) ([]Check, error)
+}
+
 // IPlugin is the interface all plugins must implement.
 type IPlugin interface {
 	Setup() error
@@ -279,6 +1005,15 @@ type IPlugin interface {
 	Teardown() error
 }
 
+// IForgeClientConsumer is optionally implemented by plugins wishing to act on
+// forges.
+//
+// SetForgeClients is called before Setup with the clients of all forges
+// configured with API access, keyed by forge name as in ForgeUser.Forge.
+type IForgeClientConsumer interface {
+	SetForgeClients(clients map[string]IForgeClient)
+}
+
 // IPluginConfigFactoryFunc is signature for the plugin's exported
 // "BrickbotPluginConfigFactory" function.
 //
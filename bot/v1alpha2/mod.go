@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package v1alpha2 is the context-aware revision of the plugin API.
+//
+// Compared to v1alpha1, all methods of plugins, IM providers and forge
+// clients take a context.Context, for cancellation on shutdown, deadlines,
+// and request-scoped values like the zerolog logger of the event being
+// processed, retrievable with zerolog.Ctx.
+//
+// The data model is unchanged and shared with v1alpha1; the most commonly
+// used types are aliased here for convenience.
+package v1alpha2
+
+import (
+	"context"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+const PluginAPIVersion = 2
+
+type (
+	Event             = v1alpha1.Event
+	EventType         = v1alpha1.EventType
+	Repo              = v1alpha1.Repo
+	PR                = v1alpha1.PR
+	Review            = v1alpha1.Review
+	Check             = v1alpha1.Check
+	CommitStatus      = v1alpha1.CommitStatus
+	CommentTargetType = v1alpha1.CommentTargetType
+)
+
+type IIMProvider interface {
+	SendTextToPerson(ctx context.Context, userID string, text string) error
+	SendTextToChat(ctx context.Context, chatID string, text string) error
+	SendMarkdownToPerson(ctx context.Context, userID string, md string) error
+	SendMarkdownToChat(ctx context.Context, chatID string, md string) error
+}
+
+// IForgeClient is abstraction for acting on forges through their APIs.
+//
+// The number is that of an issue or PR according to target, for methods
+// accepting both.
+type IForgeClient interface {
+	PostComment(ctx context.Context, repo Repo, target CommentTargetType, number int, body string) error
+	AddLabels(ctx context.Context, repo Repo, target CommentTargetType, number int, labels []string) error
+	RemoveLabels(ctx context.Context, repo Repo, target CommentTargetType, number int, labels []string) error
+	RequestReviewers(ctx context.Context, repo Repo, number int, reviewers []string) error
+	SetCommitStatus(ctx context.Context, repo Repo, sha string, status CommitStatus) error
+	GetPR(ctx context.Context, repo Repo, number int) (*PR, error)
+	ListReviews(ctx context.Context, repo Repo, number int) ([]Review, error)
+	ListChecks(ctx context.Context, repo Repo, sha string) ([]Check, error)
+}
+
+// IPlugin is the interface all plugins must implement.
+//
+// The context of ProcessEvent is canceled on shutdown or when the deadline of
+// the event is exceeded.
+type IPlugin interface {
+	Setup(ctx context.Context) error
+	ProcessEvent(ctx context.Context, e *Event, im IIMProvider) error
+	Teardown(ctx context.Context) error
+}
+
+// IForgeClientConsumer is optionally implemented by plugins wishing to act on
+// forges.
+//
+// SetForgeClients is called before Setup with the clients of all forges
+// configured with API access, keyed by forge name as in ForgeUser.Forge.
+type IForgeClientConsumer interface {
+	SetForgeClients(clients map[string]IForgeClient)
+}
+
+// IPluginConfigFactoryFunc is signature for the plugin's exported
+// "BrickbotPluginConfigFactory" function.
+//
+// You should return the zero value of your desired config struct.
+type IPluginConfigFactoryFunc = func() interface{}
+
+// IPluginFactoryFunc is signature for the plugin's exported
+// "BrickbotPluginFactory" function.
+type IPluginFactoryFunc = func(config interface{}) (IPlugin, error)
@@ -0,0 +1,147 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Command brickbot-admin talks to the admin API of brickbot-server.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/xen0n/brickbot/queue"
+)
+
+const usage = `usage: brickbot-admin [flags] <command>
+
+commands:
+  list              list dead-lettered events
+  redrive <id>|all  put dead-lettered events back into the queue
+
+flags:
+`
+
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func main() {
+	var addr, token string
+	flag.StringVar(&addr, "addr", "localhost:23334", "address of brickbot-server's admin API")
+	flag.StringVar(
+		&token,
+		"token",
+		os.Getenv("BRICKBOT_ADMIN_TOKEN"),
+		"admin API token, defaults to $BRICKBOT_ADMIN_TOKEN",
+	)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if token == "" {
+		fmt.Fprintln(os.Stderr, "brickbot-admin: -token or $BRICKBOT_ADMIN_TOKEN is required")
+		os.Exit(1)
+	}
+
+	c := &client{
+		baseURL: "http://" + addr,
+		token:   token,
+		http:    &http.Client{Timeout: 30 * time.Second},
+	}
+
+	var err error
+	switch flag.Arg(0) {
+	case "list":
+		err = c.list()
+	case "redrive":
+		if flag.NArg() != 2 {
+			flag.Usage()
+			os.Exit(1)
+		}
+		err = c.redrive(flag.Arg(1))
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "brickbot-admin:", err)
+		os.Exit(2)
+	}
+}
+
+func (c *client) list() error {
+	var letters []queue.DeadLetter
+	err := c.do(http.MethodGet, "/dead-letters", &letters)
+	if err != nil {
+		return err
+	}
+
+	for _, l := range letters {
+		// undecodable events are listed as such
+		eventID, forgeInstance, forgeEvent := "-", "-", "-"
+		if l.Event != nil {
+			eventID = l.Event.ID()
+			forgeInstance = l.Event.ForgeInstance()
+			forgeEvent = l.Event.ForgeEvent()
+		}
+
+		fmt.Printf(
+			"%d\t%s\t%s\t%s\t%s\t%s\t%d attempts\t%s\n",
+			l.ID,
+			l.FailedAt.Format(time.RFC3339),
+			l.Target,
+			eventID,
+			forgeInstance,
+			forgeEvent,
+			l.Attempts,
+			l.LastError,
+		)
+	}
+	return nil
+}
+
+func (c *client) redrive(id string) error {
+	var resp struct {
+		Redriven int `json:"redriven"`
+	}
+	err := c.do(http.MethodPost, "/dead-letters/redrive?id="+url.QueryEscape(id), &resp)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("re-drove %d event(s)\n", resp.Redriven)
+	return nil
+}
+
+func (c *client) do(method string, path string, out interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
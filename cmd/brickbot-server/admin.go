@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/xen0n/brickbot/queue"
+)
+
+var errAdminTokenRequired = errors.New("admin API token is required when the admin API is enabled")
+
+// makeAdminServer returns the server of the admin API, or nil if the admin
+// API is disabled. The API exposes raw webhook payloads, so it refuses to go
+// without a token.
+func makeAdminServer(conf *adminConfig, q *queue.Queue) (*http.Server, error) {
+	if conf.ListenAddr == "" {
+		return nil, nil
+	}
+	if conf.Token == "" {
+		return nil, errAdminTokenRequired
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/dead-letters", makeListDeadLettersHandler(q))
+	mux.HandleFunc("/dead-letters/redrive", makeRedriveHandler(q))
+
+	return &http.Server{
+		Addr:        conf.ListenAddr,
+		Handler:     requireAdminToken(conf.Token, mux),
+		ReadTimeout: 1 * time.Minute,
+	}, nil
+}
+
+func requireAdminToken(token string, next http.Handler) http.Handler {
+	expected := []byte("Bearer " + token)
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, expected) != 1 {
+			rw.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// makeListDeadLettersHandler returns the handler of "GET /dead-letters",
+// responding with the JSON array of dead-lettered events.
+func makeListDeadLettersHandler(q *queue.Queue) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		letters, err := q.ListDeadLetters()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to list dead letters")
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if letters == nil {
+			letters = []queue.DeadLetter{}
+		}
+		writeAdminJSON(rw, http.StatusOK, letters)
+	}
+}
+
+// makeRedriveHandler returns the handler of "POST /dead-letters/redrive",
+// which puts the dead-lettered event of the "id" query parameter back into
+// the queue, or all of them if "id" is "all".
+func makeRedriveHandler(q *queue.Queue) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+
+		idStr := r.URL.Query().Get("id")
+		if idStr == "all" {
+			n, err := q.RedriveAll()
+			if err != nil {
+				log.Error().Err(err).Msg("failed to re-drive dead letters")
+				rw.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+
+			log.Info().Int("count", n).Msg("re-drove all dead letters")
+			writeAdminJSON(rw, http.StatusOK, map[string]int{"redriven": n})
+			return
+		}
+
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		err = q.Redrive(id)
+		if err != nil {
+			if errors.Is(err, queue.ErrDeadLetterNotFound) {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			log.Error().Err(err).Uint64("id", id).Msg("failed to re-drive dead letter")
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		log.Info().Uint64("id", id).Msg("re-drove dead letter")
+		writeAdminJSON(rw, http.StatusOK, map[string]int{"redriven": 1})
+	}
+}
+
+func writeAdminJSON(rw http.ResponseWriter, status int, v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode admin API response")
+		rw.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	_, _ = rw.Write(b)
+}
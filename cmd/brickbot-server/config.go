@@ -3,15 +3,26 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
 	"github.com/BurntSushi/toml"
 )
 
 type config struct {
-	Server serverConfig `toml:"server"`
-	GitHub githubConfig `toml:"github"`
-	GitLab gitlabConfig `toml:"gitlab"`
-	WeCom  wecomConfig  `toml:"wecom"`
-	Bot    botConfig    `toml:"bot"`
+	Server    serverConfig    `toml:"server"`
+	GitHub    githubConfig    `toml:"github"`
+	GitLab    gitlabConfig    `toml:"gitlab"`
+	Gitea     giteaConfig     `toml:"gitea"`
+	Bitbucket bitbucketConfig `toml:"bitbucket"`
+	Gerrit    gerritConfig    `toml:"gerrit"`
+	Generic   []genericConfig `toml:"generic"`
+	Queue     queueConfig     `toml:"queue"`
+	Admin     adminConfig     `toml:"admin"`
+	WeCom     wecomConfig     `toml:"wecom"`
+	Bot       botConfig       `toml:"bot"`
 }
 
 type serverConfig struct {
@@ -19,16 +30,113 @@ type serverConfig struct {
 	//
 	// The format is just what net.Listen accepts for TCP.
 	ListenAddr string `toml:"listen_addr"`
+	// DeliveryDedupTTL is how long webhook delivery IDs are remembered for
+	// dropping duplicate deliveries, in the format time.ParseDuration
+	// accepts. Defaults to 24 hours.
+	//
+	// Delivery IDs are remembered in the queue database.
+	DeliveryDedupTTL string `toml:"delivery_dedup_ttl"`
 }
 
 type githubConfig struct {
 	Enabled bool   `toml:"enabled"`
 	Secret  string `toml:"secret"`
+	Token   string `toml:"token"`
+	// AppID and PrivateKeyPath are for running as a GitHub App, in place of
+	// Token.
+	AppID          int64  `toml:"app_id"`
+	PrivateKeyPath string `toml:"private_key_path"`
 }
 
 type gitlabConfig struct {
 	Enabled bool   `toml:"enabled"`
 	Secret  string `toml:"secret"`
+	Token   string `toml:"token"`
+	// APIBaseURL is the GitLab REST API endpoint, defaulting to gitlab.com's.
+	APIBaseURL string `toml:"api_base_url"`
+}
+
+type giteaConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Secret  string `toml:"secret"`
+	Token   string `toml:"token"`
+	// APIBaseURL is the Gitea REST API endpoint, which is required as Gitea
+	// is self-hosted.
+	APIBaseURL string `toml:"api_base_url"`
+}
+
+type bitbucketConfig struct {
+	Enabled bool   `toml:"enabled"`
+	Secret  string `toml:"secret"`
+}
+
+type gerritConfig struct {
+	Enabled bool `toml:"enabled"`
+	// Secret is checked against the "token" query parameter, as Gerrit's
+	// webhooks plugin cannot sign requests.
+	Secret string `toml:"secret"`
+}
+
+type genericConfig struct {
+	// Name identifies the endpoint, and is used as the forge name of users
+	// and repos in the events produced. It must be unique, and different
+	// from the names of the other forges.
+	Name string `toml:"name"`
+	// Path is the HTTP path the endpoint is mounted at, which must start with
+	// "/" and not clash with the other endpoints.
+	Path string `toml:"path"`
+	// Auth is either "hmac" or "bearer".
+	Auth            string `toml:"auth"`
+	Secret          string `toml:"secret"`
+	SignatureHeader string `toml:"signature_header"`
+	// DeliveryIDHeader and EventHeader are the request headers carrying the
+	// delivery ID and the event name, if any.
+	DeliveryIDHeader string              `toml:"delivery_id_header"`
+	EventHeader      string              `toml:"event_header"`
+	Rules            []genericRuleConfig `toml:"rules"`
+}
+
+type genericRuleConfig struct {
+	Event  string            `toml:"event"`
+	Match  map[string]string `toml:"match"`
+	Fields map[string]string `toml:"fields"`
+	Values map[string]string `toml:"values"`
+}
+
+type queueConfig struct {
+	// Path is the database file persisting queued events, defaulting to
+	// "brickbot-queue.db" in the working directory.
+	Path string `toml:"path"`
+	// Concurrency is the maximum number of events handled by the bot plugin
+	// at the same time, defaulting to 4.
+	Concurrency int `toml:"concurrency"`
+	// MaxAttempts is the number of attempts at handling an event before it
+	// is dead-lettered, including the first one. Defaults to 5.
+	MaxAttempts int `toml:"max_attempts"`
+	// RetryInitialInterval and RetryMaxInterval are the initial and maximum
+	// delay between attempts before jitter, in the format time.ParseDuration
+	// accepts. Default to 10 seconds and 10 minutes respectively.
+	RetryInitialInterval string `toml:"retry_initial_interval"`
+	RetryMaxInterval     string `toml:"retry_max_interval"`
+}
+
+const defaultQueuePath = "brickbot-queue.db"
+
+func (c *queueConfig) pathOrDefault() string {
+	if c.Path == "" {
+		return defaultQueuePath
+	}
+	return c.Path
+}
+
+type adminConfig struct {
+	// ListenAddr is the address the admin API listens at, disabled if empty.
+	//
+	// The admin API is not meant to be exposed publicly.
+	ListenAddr string `toml:"listen_addr"`
+	// Token is required as the "Authorization: Bearer <token>" header of
+	// admin API requests, and must be set if the admin API is enabled.
+	Token string `toml:"token"`
 }
 
 type wecomConfig struct {
@@ -39,15 +147,139 @@ type wecomConfig struct {
 }
 
 type botConfig struct {
+	Plugins []pluginConfig `toml:"plugins"`
+	// PluginPath and ConfigPath configure a single plugin named "default",
+	// in place of Plugins.
 	PluginPath string `toml:"plugin_path"`
 	ConfigPath string `toml:"config_path"`
+	// EventTimeout is the deadline of the bot plugin handling each event, in
+	// the format time.ParseDuration accepts. Defaults to 5 minutes.
+	EventTimeout string `toml:"event_timeout"`
+}
+
+type pluginConfig struct {
+	// Name identifies the plugin in logs and metrics, and must be unique.
+	//
+	// Queued events are tied to plugins by name, so renaming plugins causes
+	// events queued for them to fail.
+	Name       string `toml:"name"`
+	Path       string `toml:"path"`
+	ConfigPath string `toml:"config_path"`
+}
+
+const defaultPluginName = "default"
+
+// pluginConfigs returns the configs of all plugins, checking the names.
+func (c *botConfig) pluginConfigs() ([]pluginConfig, error) {
+	if len(c.Plugins) == 0 {
+		if c.PluginPath == "" {
+			return nil, errors.New("no bot plugins configured")
+		}
+
+		return []pluginConfig{{
+			Name:       defaultPluginName,
+			Path:       c.PluginPath,
+			ConfigPath: c.ConfigPath,
+		}}, nil
+	}
+
+	if c.PluginPath != "" {
+		return nil, errors.New("plugin_path cannot be used along with bot.plugins")
+	}
+
+	seen := make(map[string]struct{}, len(c.Plugins))
+	for _, p := range c.Plugins {
+		if p.Name == "" {
+			return nil, errors.New("bot plugin name cannot be empty")
+		}
+		if strings.HasPrefix(p.Name, "@") {
+			return nil, fmt.Errorf("bot plugin name %q cannot start with \"@\"", p.Name)
+		}
+		if _, ok := seen[p.Name]; ok {
+			return nil, fmt.Errorf("duplicate bot plugin name %q", p.Name)
+		}
+		seen[p.Name] = struct{}{}
+	}
+
+	return c.Plugins, nil
+}
+
+func (c *botConfig) eventTimeout() (time.Duration, error) {
+	if c.EventTimeout == "" {
+		return defaultEventTimeout, nil
+	}
+
+	d, err := time.ParseDuration(c.EventTimeout)
+	if err != nil {
+		return 0, err
+	}
+	if d <= 0 {
+		return 0, errors.New("event_timeout must be positive")
+	}
+	return d, nil
+}
+
+// builtinEndpoints maps the names of the forges with dedicated config sections
+// to the paths of their endpoints, as mounted by makeServer.
+var builtinEndpoints = map[string]string{
+	"github":    "/github",
+	"gitlab":    "/gitlab",
+	"gitea":     "/gitea",
+	"bitbucket": "/bitbucket",
+	"gerrit":    "/gerrit",
+}
+
+// reservedPaths is the paths of the endpoints other than forge hooks.
+var reservedPaths = []string{"/healthz", "/livez", "/readyz", "/metrics"}
+
+// checkGenericEndpoints checks that the generic endpoints have names and paths
+// unique among all endpoints.
+func checkGenericEndpoints(endpoints []genericConfig) error {
+	names := make(map[string]struct{}, len(builtinEndpoints)+len(endpoints))
+	paths := make(map[string]struct{}, len(builtinEndpoints)+len(reservedPaths)+len(endpoints))
+	for name, path := range builtinEndpoints {
+		names[name] = struct{}{}
+		paths[path] = struct{}{}
+	}
+	for _, path := range reservedPaths {
+		paths[path] = struct{}{}
+	}
+
+	for _, c := range endpoints {
+		if c.Name == "" {
+			return errors.New("generic endpoint name cannot be empty")
+		}
+		if _, ok := names[c.Name]; ok {
+			return fmt.Errorf("generic endpoint name %q is already in use", c.Name)
+		}
+		names[c.Name] = struct{}{}
+
+		if !strings.HasPrefix(c.Path, "/") {
+			return fmt.Errorf("path of generic endpoint %q must start with \"/\"", c.Name)
+		}
+		if _, ok := paths[c.Path]; ok {
+			return fmt.Errorf("path %q of generic endpoint %q is already in use", c.Path, c.Name)
+		}
+		paths[c.Path] = struct{}{}
+	}
+
+	return nil
 }
 
 func parseConfig(path string) (config, error) {
-	var result config
+	result := config{
+		Queue: queueConfig{
+			Concurrency: 4,
+		},
+	}
 	_, err := toml.DecodeFile(path, &result)
 	if err != nil {
 		return config{}, err
 	}
+
+	err = checkGenericEndpoints(result.Generic)
+	if err != nil {
+		return config{}, err
+	}
 	return result, nil
 }
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/queue"
+)
+
+const defaultDeliveryDedupTTL = 24 * time.Hour
+
+// deliveryDeduper drops duplicate webhook deliveries, e.g. ones retried by
+// the forge, by remembering recent delivery IDs in the queue database, so
+// they survive restarts and crashes.
+type deliveryDeduper struct {
+	q   *queue.Queue
+	ttl time.Duration
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+func newDeliveryDeduper(q *queue.Queue, ttl time.Duration) *deliveryDeduper {
+	if ttl <= 0 {
+		ttl = defaultDeliveryDedupTTL
+	}
+
+	return &deliveryDeduper{
+		q:   q,
+		ttl: ttl,
+	}
+}
+
+// enqueue queues the event for the targets, unless the delivery of the given
+// key is already seen within the TTL. It returns whether the delivery is a
+// duplicate.
+//
+// The key should be namespaced by the endpoint, as delivery IDs are only
+// unique within a forge.
+func (d *deliveryDeduper) enqueue(key string, e *v1alpha1.Event, targets ...string) (bool, error) {
+	d.maybePrune()
+	return d.q.EnqueueDelivery(key, d.ttl, e, targets...)
+}
+
+// maybePrune forgets expired deliveries every tenth of the TTL.
+func (d *deliveryDeduper) maybePrune() {
+	now := time.Now()
+
+	d.mu.Lock()
+	due := now.Sub(d.lastPrune) > d.ttl/10
+	if due {
+		d.lastPrune = now
+	}
+	d.mu.Unlock()
+
+	if !due {
+		return
+	}
+
+	// expired deliveries only take up space, so failures are not fatal
+	n, err := d.q.PruneDeliveries(d.ttl)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to prune remembered delivery IDs")
+		return
+	}
+	log.Debug().Int("count", n).Msg("pruned remembered delivery IDs")
+}
@@ -0,0 +1,79 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+const defaultEventTimeout = 5 * time.Minute
+
+var (
+	errPluginPanicked = errors.New("bot plugin panicked")
+	errPluginTimedOut = errors.New("bot plugin timed out")
+)
+
+// guardPluginCall calls fn on behalf of the event and the named plugin with a
+// context of the given deadline, recovering panics and giving up after
+// timeout, so that misbehaving plugins cannot bring down the server or stall
+// the queue.
+//
+// Plugins not honoring the context are left running in the background after
+// timeout, but the event is considered failed.
+func guardPluginCall(
+	ctx context.Context,
+	e *v1alpha1.Event,
+	pluginName string,
+	timeout time.Duration,
+	fn func(ctx context.Context) error,
+) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Error().
+					Str("event_id", e.ID()).
+					Str("plugin", pluginName).
+					Str("panic", fmt.Sprint(r)).
+					Str("stack", string(debug.Stack())).
+					Msg("bot plugin panicked")
+				metricPluginPanics.WithLabelValues(pluginName).Inc()
+				done <- fmt.Errorf("%w: %v", errPluginPanicked, r)
+			}
+		}()
+
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		// plugins honoring the context return early with errors
+		if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return err
+		}
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			// shutting down
+			return ctx.Err()
+		}
+	}
+
+	log.Error().
+		Str("event_id", e.ID()).
+		Str("plugin", pluginName).
+		Dur("timeout", timeout).
+		Msg("bot plugin timed out")
+	metricPluginTimeouts.WithLabelValues(pluginName).Inc()
+	return errPluginTimedOut
+}
@@ -4,6 +4,8 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
@@ -18,15 +20,24 @@ import (
 	"github.com/rs/zerolog/log"
 	"github.com/rs/zerolog/pkgerrors"
 
-	"github.com/xen0n/brickbot/bot"
 	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/bot/v1alpha2"
 	"github.com/xen0n/brickbot/forge"
+	forgeBB "github.com/xen0n/brickbot/forge/bitbucket"
+	forgeGeneric "github.com/xen0n/brickbot/forge/generic"
+	forgeGerrit "github.com/xen0n/brickbot/forge/gerrit"
+	forgeGitea "github.com/xen0n/brickbot/forge/gitea"
 	forgeGH "github.com/xen0n/brickbot/forge/github"
 	forgeGL "github.com/xen0n/brickbot/forge/gitlab"
 	"github.com/xen0n/brickbot/im"
 	imWeCom "github.com/xen0n/brickbot/im/wecom"
+	"github.com/xen0n/brickbot/queue"
 )
 
+// shutdownTimeout is how long the HTTP servers wait for ongoing requests when
+// shutting down.
+const shutdownTimeout = 30 * time.Second
+
 func main() {
 	//nolint:reassign // Overwriting external global vars is the expected usage pattern
 	{
@@ -51,30 +62,99 @@ func main() {
 		os.Exit(1)
 	}
 
-	botPlugin, err := bot.LoadPlugin(conf.Bot.PluginPath)
+	pluginConfs, err := conf.Bot.pluginConfigs()
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to load bot plugin")
+		log.Fatal().Err(err).Msg("failed to parse bot config")
 		os.Exit(1)
 	}
 
-	bot, err := botPlugin.InitWithConfigTOML(conf.Bot.ConfigPath)
+	// Shared by the GitHub webhook endpoint and API client, for consistent
+	// caching of app installation tokens.
+	var githubCreds *forgeGH.Credentials
+	if conf.GitHub.Enabled {
+		githubCreds, err = githubCredentialsFromConfig(&conf.GitHub)
+		if err != nil {
+			log.Fatal().Err(err).Msg("failed to load GitHub credentials")
+			os.Exit(1)
+		}
+	}
+
+	forgeClients, err := makeForgeClients(&conf, githubCreds)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to construct bot plugin")
-		os.Exit(2)
+		log.Fatal().Err(err).Msg("failed to initialize forge API clients")
+		os.Exit(1)
 	}
 
-	err = bot.Setup()
+	plugins, err := loadPlugins(pluginConfs, forgeClients)
 	if err != nil {
-		log.Fatal().Err(err).Msg("failed to setup bot plugin")
+		log.Fatal().Err(err).Msg("failed to load bot plugins")
 		os.Exit(2)
 	}
 
-	srv, err := makeServer(&conf, bot)
+	imProvider, err := makeIMProvider(&conf)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize IM integration")
+		os.Exit(1)
+	}
+
+	q, err := queue.Open(conf.Queue.pathOrDefault())
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to open event queue")
+		os.Exit(1)
+	}
+	registerQueueMetrics(q)
+
+	dedup, err := makeDeliveryDeduper(&conf.Server, q)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize delivery deduplication")
+		os.Exit(1)
+	}
+
+	srv, resolvers, err := makeServer(&conf, q, dedup, pluginNames(plugins), githubCreds)
 	if err != nil {
 		log.Fatal().Err(err).Msg("failed to initialize server")
 		os.Exit(1)
 	}
 
+	eventTimeout, err := conf.Bot.eventTimeout()
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to parse bot config")
+		os.Exit(1)
+	}
+
+	adminSrv, err := makeAdminServer(&conf.Admin, q)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to initialize admin API")
+		os.Exit(1)
+	}
+
+	runOpts, err := makeRunOptions(&conf.Queue)
+	if err != nil {
+		log.Fatal().Err(err).Msg("failed to parse queue config")
+		os.Exit(1)
+	}
+
+	// Dispatch queued events to the bot plugins, including those left over
+	// from the last run.
+	dispatchCtx, stopDispatch := context.WithCancel(context.Background())
+	dispatchDone := make(chan error, 1)
+	go func() {
+		dispatchDone <- q.Run(
+			dispatchCtx,
+			runOpts,
+			makeEventHandler(plugins, resolvers, q, imProvider, eventTimeout),
+		)
+	}()
+
+	if adminSrv != nil {
+		go func() {
+			err := adminSrv.ListenAndServe()
+			if err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Error().Err(err).Msg("failed to serve admin API")
+			}
+		}()
+	}
+
 	signalChan := make(chan os.Signal, 1)
 	signal.Notify(signalChan, syscall.SIGINT)
 
@@ -87,16 +167,43 @@ func main() {
 
 		log.Info().Msg("caught SIGINT")
 
-		// TODO: timeout for graceful quit
-		err := srv.Shutdown(context.Background())
+		// Every step is taken even if earlier ones fail, so that plugins are
+		// torn down and state is persisted regardless.
+		exitcode := 0
+
+		// Hook requests only enqueue their events, so waiting for them
+		// should never take long, but do not hang on stuck clients.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		err := srv.Shutdown(shutdownCtx)
 		if err != nil {
 			log.Error().Err(err).Msg("error occurred during shutdown")
-			exitcodeChan <- 10
-			return
+			exitcode = 10
 		}
 
-		// shutdown successful
-		exitcodeChan <- 0
+		if adminSrv != nil {
+			err = adminSrv.Shutdown(shutdownCtx)
+			if err != nil {
+				log.Error().Err(err).Msg("error occurred during admin API shutdown")
+			}
+		}
+		cancel()
+
+		// Let the events being handled finish, the rest stay queued for the
+		// next run.
+		stopDispatch()
+		err = <-dispatchDone
+		if err != nil {
+			log.Error().Err(err).Msg("error occurred while dispatching events")
+		}
+
+		err = q.Close()
+		if err != nil {
+			log.Error().Err(err).Msg("failed to close event queue")
+		}
+
+		teardownPlugins(plugins)
+
+		exitcodeChan <- exitcode
 	}()
 
 	err = srv.ListenAndServe()
@@ -114,9 +221,70 @@ func main() {
 	os.Exit(exitcode)
 }
 
-func makeServer(conf *config, bot v1alpha1.IPlugin) (*http.Server, error) {
-	// IM integration.
-	var wecom im.IProvider
+func makeDeliveryDeduper(conf *serverConfig, q *queue.Queue) (*deliveryDeduper, error) {
+	var ttl time.Duration
+	if conf.DeliveryDedupTTL != "" {
+		var err error
+		ttl, err = time.ParseDuration(conf.DeliveryDedupTTL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return newDeliveryDeduper(q, ttl), nil
+}
+
+func makeRunOptions(conf *queueConfig) (queue.RunOptions, error) {
+	retry := queue.RetryPolicy{MaxAttempts: conf.MaxAttempts}
+	if conf.RetryInitialInterval != "" {
+		var err error
+		retry.InitialInterval, err = time.ParseDuration(conf.RetryInitialInterval)
+		if err != nil {
+			return queue.RunOptions{}, err
+		}
+	}
+	if conf.RetryMaxInterval != "" {
+		var err error
+		retry.MaxInterval, err = time.ParseDuration(conf.RetryMaxInterval)
+		if err != nil {
+			return queue.RunOptions{}, err
+		}
+	}
+
+	return queue.RunOptions{
+		Concurrency: conf.Concurrency,
+		Retry:       retry,
+		OnRetry: func(e *v1alpha1.Event, target string, attempts int, err error, delay time.Duration) {
+			log.Warn().
+				Err(err).
+				Str("event_id", e.ID()).
+				Str("plugin", target).
+				Int("attempts", attempts).
+				Dur("delay", delay).
+				Msg("bot returned failure, retrying later")
+			metricEventRetries.WithLabelValues(target).Inc()
+		},
+		OnDead: func(e *v1alpha1.Event, target string, attempts int, err error) {
+			log.Error().
+				Err(err).
+				Str("event_id", e.ID()).
+				Str("plugin", target).
+				Int("attempts", attempts).
+				Msg("bot returned failure, giving up and dead-lettering event")
+			metricEventsDeadLettered.WithLabelValues(target).Inc()
+		},
+		OnUndecodable: func(id uint64, target string, err error) {
+			log.Error().
+				Err(err).
+				Uint64("dead_letter_id", id).
+				Str("plugin", target).
+				Msg("failed to decode queued event, dead-lettering it")
+			metricEventsDeadLettered.WithLabelValues(target).Inc()
+		},
+	}, nil
+}
+
+func makeIMProvider(conf *config) (im.IProvider, error) {
 	if conf.WeCom.Enabled {
 		p, err := imWeCom.New(
 			conf.WeCom.CorpID,
@@ -128,10 +296,32 @@ func makeServer(conf *config, bot v1alpha1.IPlugin) (*http.Server, error) {
 			return nil, err
 		}
 
-		wecom = p
+		return p, nil
 	}
 
+	return nil, nil
+}
+
+// makeServer returns the server of webhook endpoints, which queue events for
+// each of the targets, along with the forge hooks resolving events keyed by
+// forge instance name.
+func makeServer(
+	conf *config,
+	q *queue.Queue,
+	dedup *deliveryDeduper,
+	targets []string,
+	githubCreds *forgeGH.Credentials,
+) (*http.Server, map[string]forge.IEventResolver, error) {
 	mux := http.NewServeMux()
+	resolvers := make(map[string]forge.IEventResolver)
+
+	handleForgeHook := func(pattern string, instance string, fh forge.IForgeHook) {
+		resolver, _ := fh.(forge.IEventResolver)
+		if resolver != nil {
+			resolvers[instance] = resolver
+		}
+		mux.HandleFunc(pattern, makeForgeHookHandler(instance, fh, resolver, q, dedup, targets))
+	}
 
 	// Health check endpoints.
 	mux.HandleFunc("/healthz", dummyHealthzHandler)
@@ -144,23 +334,64 @@ func makeServer(conf *config, bot v1alpha1.IPlugin) (*http.Server, error) {
 	// Webhook endpoints.
 	{
 		if conf.GitHub.Enabled {
-			fh, err := forgeGH.New(conf.GitHub.Secret)
+			fh, err := forgeGH.New(conf.GitHub.Secret, githubCreds)
 			if err != nil {
 				log.Error().Err(err).Msg("failed to initialize GitHub integration")
-				return nil, err
+				return nil, nil, err
 			}
 
-			mux.HandleFunc("/github", makeForgeHookHandler(fh, bot, wecom))
+			handleForgeHook("/github", "github", fh)
 		}
 
 		if conf.GitLab.Enabled {
-			fh, err := forgeGL.New(conf.GitLab.Secret)
+			fh, err := forgeGL.New(conf.GitLab.Secret, conf.GitLab.Token, conf.GitLab.APIBaseURL)
 			if err != nil {
 				log.Error().Err(err).Msg("failed to initialize GitLab integration")
-				return nil, err
+				return nil, nil, err
+			}
+
+			handleForgeHook("/gitlab", "gitlab", fh)
+		}
+
+		if conf.Gitea.Enabled {
+			fh, err := forgeGitea.New(conf.Gitea.Secret, conf.Gitea.Token, conf.Gitea.APIBaseURL)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to initialize Gitea integration")
+				return nil, nil, err
+			}
+
+			handleForgeHook("/gitea", "gitea", fh)
+		}
+
+		if conf.Bitbucket.Enabled {
+			fh, err := forgeBB.New(conf.Bitbucket.Secret)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to initialize Bitbucket integration")
+				return nil, nil, err
 			}
 
-			mux.HandleFunc("/gitlab", makeForgeHookHandler(fh, bot, wecom))
+			handleForgeHook("/bitbucket", "bitbucket", fh)
+		}
+
+		if conf.Gerrit.Enabled {
+			fh, err := forgeGerrit.New(conf.Gerrit.Secret)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to initialize Gerrit integration")
+				return nil, nil, err
+			}
+
+			handleForgeHook("/gerrit", "gerrit", fh)
+		}
+
+		for i := range conf.Generic {
+			c := &conf.Generic[i]
+			fh, err := forgeGeneric.New(intoGenericOptions(c))
+			if err != nil {
+				log.Error().Err(err).Str("name", c.Name).Msg("failed to initialize generic webhook endpoint")
+				return nil, nil, err
+			}
+
+			handleForgeHook(c.Path, c.Name, fh)
 		}
 	}
 
@@ -168,7 +399,71 @@ func makeServer(conf *config, bot v1alpha1.IPlugin) (*http.Server, error) {
 		Addr:        conf.Server.ListenAddr,
 		Handler:     mux,
 		ReadTimeout: 1 * time.Minute,
-	}, nil
+	}, resolvers, nil
+}
+
+// makeForgeClients returns API clients of the forges configured with tokens,
+// keyed by forge name.
+func makeForgeClients(
+	conf *config,
+	githubCreds *forgeGH.Credentials,
+) (map[string]v1alpha2.IForgeClient, error) {
+	result := make(map[string]v1alpha2.IForgeClient)
+
+	if conf.GitHub.Enabled && (conf.GitHub.Token != "" || conf.GitHub.AppID != 0) {
+		c, err := forgeGH.NewClient(githubCreds)
+		if err != nil {
+			return nil, err
+		}
+		result["github"] = c
+	}
+
+	if conf.GitLab.Enabled && conf.GitLab.Token != "" {
+		c, err := forgeGL.NewClient(conf.GitLab.APIBaseURL, conf.GitLab.Token)
+		if err != nil {
+			return nil, err
+		}
+		result["gitlab"] = c
+	}
+
+	return result, nil
+}
+
+func githubCredentialsFromConfig(c *githubConfig) (*forgeGH.Credentials, error) {
+	auth := forgeGH.Auth{Token: c.Token}
+	if c.AppID != 0 {
+		key, err := os.ReadFile(c.PrivateKeyPath)
+		if err != nil {
+			return nil, err
+		}
+
+		auth.AppID = c.AppID
+		auth.PrivateKey = key
+	}
+
+	return forgeGH.NewCredentials(auth)
+}
+
+func intoGenericOptions(c *genericConfig) forgeGeneric.Options {
+	rules := make([]forgeGeneric.Rule, len(c.Rules))
+	for i, r := range c.Rules {
+		rules[i] = forgeGeneric.Rule{
+			Match:  r.Match,
+			Event:  r.Event,
+			Fields: r.Fields,
+			Values: r.Values,
+		}
+	}
+
+	return forgeGeneric.Options{
+		Name:             c.Name,
+		Auth:             c.Auth,
+		Secret:           c.Secret,
+		SignatureHeader:  c.SignatureHeader,
+		DeliveryIDHeader: c.DeliveryIDHeader,
+		EventHeader:      c.EventHeader,
+		Rules:            rules,
+	}
 }
 
 func dummyHealthzHandler(rw http.ResponseWriter, _ *http.Request) {
@@ -176,12 +471,20 @@ func dummyHealthzHandler(rw http.ResponseWriter, _ *http.Request) {
 	rw.WriteHeader(http.StatusOK)
 }
 
+// makeForgeHookHandler returns the handler of the webhook endpoint, which
+// queues the events for the targets, or for resolverTarget first if the
+// resolver, which may be nil, needs to resolve them.
 func makeForgeHookHandler(
+	instance string,
 	fh forge.IForgeHook,
-	bot v1alpha1.IPlugin,
-	imProvider im.IProvider,
+	resolver forge.IEventResolver,
+	q *queue.Queue,
+	dedup *deliveryDeduper,
+	targets []string,
 ) http.HandlerFunc {
 	return func(rw http.ResponseWriter, r *http.Request) {
+		receivedAt := time.Now()
+
 		// Invoke the forge-specific logic.
 		botEvent, err := fh.HookRequest(r)
 		if err != nil {
@@ -198,18 +501,136 @@ func makeForgeHookHandler(
 			return
 		}
 
-		log.Debug().Str("event", fmt.Sprintf("%+v", botEvent)).Msg("parsed incoming event")
+		botEvent.SetID(newEventID())
+		botEvent.SetReceivedAt(receivedAt)
+		botEvent.SetForgeInstance(instance)
 
-		// Call bot plugin asynchronously.
-		go func(e *v1alpha1.Event) {
-			err := bot.ProcessEvent(e, imProvider)
-			if err != nil {
-				log.Error().Err(err).Msg("bot returned failure")
-			}
-		}(botEvent)
+		log.Debug().
+			Str("event_id", botEvent.ID()).
+			Str("event", fmt.Sprintf("%+v", botEvent)).
+			Msg("parsed incoming event")
+
+		eventTargets := targets
+		if resolver != nil && resolver.NeedsResolving(botEvent) {
+			// API lookups are slow and may fail, so do them off the
+			// request path.
+			eventTargets = []string{resolverTarget}
+		}
+
+		// Persist the event before acknowledging, so it survives restarts.
+		// The delivery is only remembered along with the event, so failed
+		// deliveries can be retried by the forge.
+		var duplicate bool
+		if botEvent.DeliveryID() != "" {
+			dedupKey := r.URL.Path + ":" + botEvent.DeliveryID()
+			duplicate, err = dedup.enqueue(dedupKey, botEvent, eventTargets...)
+		} else {
+			err = q.Enqueue(botEvent, eventTargets...)
+		}
+		if err != nil {
+			log.Error().Err(err).Str("event_id", botEvent.ID()).Msg("failed to enqueue event")
+
+			// Let the forge retry the delivery.
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		if duplicate {
+			log.Info().Str("forge", instance).Str("delivery_id", botEvent.DeliveryID()).Msg("dropping duplicate delivery")
+			metricDuplicateDeliveries.WithLabelValues(r.URL.Path).Inc()
+
+			// Acknowledge, so the forge stops retrying.
+			rw.WriteHeader(http.StatusNoContent)
+			return
+		}
 
 		// Most webhooks ignore the response body, but might retry in case of
 		// failed deliveries, so send 204.
 		rw.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// resolverTarget is the queue target of events to be resolved by their forge
+// hooks, before being queued for the bot plugins. Plugin names cannot start
+// with "@", so this never clashes with them.
+const resolverTarget = "@resolve"
+
+var errUnknownForgeInstance = errors.New("no such forge instance resolving events")
+
+// makeEventHandler returns the handler of queued events, which hands them to
+// the bot plugin they are queued for with panics recovered and the given
+// deadline, or resolves and queues them for all the plugins if they are
+// queued for resolverTarget. Failures are retried by the queue.
+//
+// The logger of the event is put into the context, for plugins to retrieve
+// with zerolog.Ctx.
+func makeEventHandler(
+	plugins []namedPlugin,
+	resolvers map[string]forge.IEventResolver,
+	q *queue.Queue,
+	imProvider im.IProvider,
+	timeout time.Duration,
+) func(ctx context.Context, target string, e *v1alpha1.Event) error {
+	targets := pluginNames(plugins)
+
+	return func(ctx context.Context, target string, e *v1alpha1.Event) error {
+		if target == resolverTarget {
+			return resolveQueuedEvent(ctx, e, resolvers, q, targets)
+		}
+
+		targetPlugins, err := findPlugins(plugins, target)
+		if err != nil {
+			return err
+		}
+
+		var errs []error
+		for _, p := range targetPlugins {
+			p := p
+			logger := log.With().Str("event_id", e.ID()).Str("plugin", p.name).Logger()
+			pluginCtx := logger.WithContext(ctx)
+
+			err := guardPluginCall(pluginCtx, e, p.name, timeout, func(ctx context.Context) error {
+				return p.plugin.ProcessEvent(ctx, e, imProvider)
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("plugin %q: %w", p.name, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
+
+// resolveQueuedEvent resolves the event with the forge hook it comes from, and
+// queues the result for the targets.
+func resolveQueuedEvent(
+	ctx context.Context,
+	e *v1alpha1.Event,
+	resolvers map[string]forge.IEventResolver,
+	q *queue.Queue,
+	targets []string,
+) error {
+	resolver, ok := resolvers[e.ForgeInstance()]
+	if !ok {
+		return fmt.Errorf("%w: %q", errUnknownForgeInstance, e.ForgeInstance())
+	}
+
+	resolved, err := resolver.ResolveEvent(ctx, e)
+	if err != nil {
+		return err
+	}
+
+	if resolved == nil {
+		log.Debug().Str("event_id", e.ID()).Msg("dropping event of no interest after resolving")
+		return nil
+	}
+
+	return q.Enqueue(resolved, targets...)
+}
+
+// newEventID returns a random ID for identifying events.
+func newEventID() string {
+	var b [16]byte
+	// crypto/rand never fails on supported platforms
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
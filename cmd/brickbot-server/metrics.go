@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/xen0n/brickbot/queue"
+)
+
+var metricDuplicateDeliveries = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "brickbot",
+		Name:      "duplicate_deliveries_dropped_total",
+		Help:      "Number of webhook deliveries dropped for being duplicates of earlier ones.",
+	},
+	[]string{"endpoint"},
+)
+
+func registerQueueMetrics(q *queue.Queue) {
+	promauto.NewGaugeFunc(
+		prometheus.GaugeOpts{
+			Namespace: "brickbot",
+			Name:      "queued_events",
+			Help:      "Number of events queued for the bot plugin, including those being handled.",
+		},
+		func() float64 {
+			n, err := q.Len()
+			if err != nil {
+				return 0
+			}
+			return float64(n)
+		},
+	)
+}
+
+var metricEventRetries = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "brickbot",
+		Name:      "event_retries_total",
+		Help:      "Number of retries scheduled for events the bot plugin failed to handle.",
+	},
+	[]string{"plugin"},
+)
+
+var metricEventsDeadLettered = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "brickbot",
+		Name:      "events_dead_lettered_total",
+		Help:      "Number of events dead-lettered after exhausting retries or failing to decode.",
+	},
+	[]string{"plugin"},
+)
+
+var metricPluginPanics = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "brickbot",
+		Name:      "plugin_panics_total",
+		Help:      "Number of panics recovered from the bot plugin while handling events.",
+	},
+	[]string{"plugin"},
+)
+
+var metricPluginTimeouts = promauto.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "brickbot",
+		Name:      "plugin_timeouts_total",
+		Help:      "Number of events the bot plugin failed to handle within the deadline.",
+	},
+	[]string{"plugin"},
+)
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/xen0n/brickbot/bot"
+	"github.com/xen0n/brickbot/bot/v1alpha2"
+)
+
+// namedPlugin is a loaded bot plugin along with its configured name, which
+// identifies it in the queue, logs and metrics.
+type namedPlugin struct {
+	name   string
+	plugin v1alpha2.IPlugin
+}
+
+// loadPlugins loads and sets up all configured bot plugins. Plugins already
+// set up are torn down if any of the rest fails.
+func loadPlugins(
+	confs []pluginConfig,
+	forgeClients map[string]v1alpha2.IForgeClient,
+) ([]namedPlugin, error) {
+	result := make([]namedPlugin, 0, len(confs))
+	for i := range confs {
+		c := &confs[i]
+
+		p, err := loadPlugin(c, forgeClients)
+		if err != nil {
+			teardownPlugins(result)
+			return nil, fmt.Errorf("plugin %q: %w", c.Name, err)
+		}
+
+		result = append(result, namedPlugin{name: c.Name, plugin: p})
+	}
+	return result, nil
+}
+
+func loadPlugin(c *pluginConfig, forgeClients map[string]v1alpha2.IForgeClient) (v1alpha2.IPlugin, error) {
+	loaded, err := bot.LoadPlugin(c.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	p, err := loaded.InitWithConfigTOML(c.ConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if consumer, ok := p.(v1alpha2.IForgeClientConsumer); ok {
+		consumer.SetForgeClients(forgeClients)
+	}
+
+	err = p.Setup(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// teardownPlugins tears down all the plugins, logging failures.
+func teardownPlugins(plugins []namedPlugin) {
+	for _, p := range plugins {
+		err := p.plugin.Teardown(context.Background())
+		if err != nil {
+			log.Error().Err(err).Str("plugin", p.name).Msg("failed to tear down bot plugin")
+		}
+	}
+}
+
+func pluginNames(plugins []namedPlugin) []string {
+	result := make([]string, len(plugins))
+	for i, p := range plugins {
+		result[i] = p.name
+	}
+	return result
+}
+
+var errUnknownPlugin = errors.New("no such bot plugin")
+
+// findPlugins returns the plugins the event queued for the given target goes
+// to: all of them for events queued without targets by older versions, or
+// the one of the target's name.
+func findPlugins(plugins []namedPlugin, target string) ([]namedPlugin, error) {
+	if target == "" {
+		return plugins, nil
+	}
+
+	for _, p := range plugins {
+		if p.name == target {
+			return []namedPlugin{p}, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", errUnknownPlugin, target)
+}
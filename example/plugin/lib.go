@@ -88,6 +88,21 @@ func (p *plugin) ProcessEvent(e *v1alpha1.Event, im v1alpha1.IIMProvider) error
 			return err
 		}
 
+	case v1alpha1.EventTypeIssueOpened:
+		ee, _ := e.IssueOpened()
+		err := im.SendTextToChat(
+			p.teamChatID,
+			fmt.Sprintf(
+				"%s 报告了 %s\n\n%s",
+				ee.Actor.UserName,
+				ee.Issue.URL,
+				ee.Issue.Title,
+			),
+		)
+		if err != nil {
+			return err
+		}
+
 	case v1alpha1.EventTypePRReady:
 		ee, _ := e.PRReady()
 		err := im.SendTextToChat(
@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package bitbucket
+
+import (
+	"strings"
+
+	"github.com/go-playground/webhooks/v6/bitbucket"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// Adapters for whole event param structs
+
+func intoPROpenedParamsFromCloud(x *bitbucket.PullRequestCreatedPayload) v1alpha1.PROpenedParams {
+	return v1alpha1.PROpenedParams{
+		Actor: botModelFromCloudSenderContainingPayload(x),
+		PR:    botModelFromCloudPRContainingPayload(x),
+	}
+}
+
+func intoPRUpdatedParamsFromCloud(x *bitbucket.PullRequestUpdatedPayload) v1alpha1.PRUpdatedParams {
+	return v1alpha1.PRUpdatedParams{
+		Actor: botModelFromCloudSenderContainingPayload(x),
+		PR:    botModelFromCloudPRContainingPayload(x),
+	}
+}
+
+func intoPRReviewedParamsFromCloud(x *bitbucket.PullRequestApprovedPayload) v1alpha1.PRReviewedParams {
+	return v1alpha1.PRReviewedParams{
+		Actor:  botModelFromCloudSenderContainingPayload(x),
+		PR:     botModelFromCloudPRContainingPayload(x),
+		Review: v1alpha1.ReviewTypeApprove,
+	}
+}
+
+func intoPRMergedParamsFromCloud(x *bitbucket.PullRequestMergedPayload) v1alpha1.PRMergedParams {
+	return v1alpha1.PRMergedParams{
+		Actor: botModelFromCloudSenderContainingPayload(x),
+		PR:    botModelFromCloudPRContainingPayload(x),
+	}
+}
+
+func intoPRClosedParamsFromCloud(x *bitbucket.PullRequestDeclinedPayload) v1alpha1.PRClosedParams {
+	return v1alpha1.PRClosedParams{
+		Actor: botModelFromCloudSenderContainingPayload(x),
+		PR:    botModelFromCloudPRContainingPayload(x),
+	}
+}
+
+func intoCIFinishedParamsFromCloud(x interface{}) v1alpha1.CIFinishedParams {
+	var state, commitURL, url string
+	switch x := x.(type) {
+	case *bitbucket.RepoCommitStatusCreatedPayload:
+		state = x.CommitStatus.State
+		commitURL = x.CommitStatus.Links.Commit.Href
+		url = x.CommitStatus.URL
+
+	case *bitbucket.RepoCommitStatusUpdatedPayload:
+		state = x.CommitStatus.State
+		commitURL = x.CommitStatus.Links.Commit.Href
+		url = x.CommitStatus.URL
+
+	default:
+		panic("should never happen")
+	}
+
+	// The commit is only available as an API link ending with the SHA.
+	sha := commitURL[strings.LastIndexByte(commitURL, '/')+1:]
+
+	return v1alpha1.CIFinishedParams{
+		Run: v1alpha1.CIRun{
+			Repo: botModelFromCloudRepoContainingPayload(x),
+			// Bitbucket Cloud does not tell which PR the commit belongs to
+			PR:    v1alpha1.PR{},
+			State: ciStateFromCommitStatusState(state),
+			SHA:   sha,
+			URL:   url,
+		},
+	}
+}
+
+// Adapters for component fields
+
+func botModelFromCloudSenderContainingPayload(x interface{}) v1alpha1.ForgeUser {
+	switch x := x.(type) {
+	case *bitbucket.PullRequestCreatedPayload:
+		return botModelFromCloudOwner(&x.Actor)
+
+	case *bitbucket.PullRequestUpdatedPayload:
+		return botModelFromCloudOwner(&x.Actor)
+
+	case *bitbucket.PullRequestApprovedPayload:
+		return botModelFromCloudOwner(&x.Actor)
+
+	case *bitbucket.PullRequestMergedPayload:
+		return botModelFromCloudOwner(&x.Actor)
+
+	case *bitbucket.PullRequestDeclinedPayload:
+		return botModelFromCloudOwner(&x.Actor)
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromCloudRepoContainingPayload(x interface{}) v1alpha1.Repo {
+	switch x := x.(type) {
+	case *bitbucket.PullRequestCreatedPayload:
+		return botModelFromCloudRepo(&x.Repository)
+
+	case *bitbucket.PullRequestUpdatedPayload:
+		return botModelFromCloudRepo(&x.Repository)
+
+	case *bitbucket.PullRequestApprovedPayload:
+		return botModelFromCloudRepo(&x.Repository)
+
+	case *bitbucket.PullRequestMergedPayload:
+		return botModelFromCloudRepo(&x.Repository)
+
+	case *bitbucket.PullRequestDeclinedPayload:
+		return botModelFromCloudRepo(&x.Repository)
+
+	case *bitbucket.RepoCommitStatusCreatedPayload:
+		return botModelFromCloudRepo(&x.Repository)
+
+	case *bitbucket.RepoCommitStatusUpdatedPayload:
+		return botModelFromCloudRepo(&x.Repository)
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromCloudPRContainingPayload(x interface{}) v1alpha1.PR {
+	var pr *bitbucket.PullRequest
+	switch x := x.(type) {
+	case *bitbucket.PullRequestCreatedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucket.PullRequestUpdatedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucket.PullRequestApprovedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucket.PullRequestMergedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucket.PullRequestDeclinedPayload:
+		pr = &x.PullRequest
+
+	default:
+		panic("should never happen")
+	}
+
+	reviewers := make([]v1alpha1.ForgeUser, len(pr.Reviewers))
+	for i := range pr.Reviewers {
+		reviewers[i] = botModelFromCloudOwner(&pr.Reviewers[i])
+	}
+
+	return v1alpha1.PR{
+		Repo:   botModelFromCloudRepoContainingPayload(x),
+		Number: int(pr.ID),
+		Title:  pr.Title,
+		Author: botModelFromCloudOwner(&pr.Author),
+		State:  issueStateFromPRState(pr.State),
+		URL:    pr.Links.HTML.Href,
+		Body:   pr.Description,
+
+		BaseBranch: pr.Destination.Branch.Name,
+		HeadBranch: pr.Source.Branch.Name,
+		// Bitbucket Cloud only gives the abbreviated hash
+		HeadSHA: pr.Source.Commit.Hash,
+
+		RequestedReviewers: reviewers,
+
+		CreatedAt: pr.CreatedOn,
+		UpdatedAt: pr.UpdatedOn,
+	}
+}
+
+func botModelFromCloudOwner(x *bitbucket.Owner) v1alpha1.ForgeUser {
+	return v1alpha1.ForgeUser{
+		Forge:    forgeTypeCloud,
+		UserName: x.NickName,
+	}
+}
+
+func botModelFromCloudRepo(x *bitbucket.Repository) v1alpha1.Repo {
+	// The owner is the workspace, which is only available in the full name.
+	workspace, _, _ := strings.Cut(x.FullName, "/")
+	return v1alpha1.Repo{
+		User: v1alpha1.ForgeUser{
+			Forge:    forgeTypeCloud,
+			UserName: workspace,
+		},
+		RepoName: x.Name,
+	}
+}
+
+func isFinishedCommitStatusState(state string) bool {
+	switch state {
+	case "SUCCESSFUL", "FAILED", "STOPPED":
+		return true
+	default:
+		return false
+	}
+}
+
+func ciStateFromCommitStatusState(state string) v1alpha1.CIState {
+	switch state {
+	case "SUCCESSFUL":
+		return v1alpha1.CIStatePassed
+	case "FAILED":
+		return v1alpha1.CIStateFailed
+	case "STOPPED":
+		return v1alpha1.CIStateCanceled
+	default:
+		return v1alpha1.CIStateUnknown
+	}
+}
@@ -0,0 +1,235 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package bitbucket
+
+import (
+	"time"
+
+	bitbucketserver "github.com/go-playground/webhooks/v6/bitbucket-server"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// Adapters for whole event param structs
+
+func intoPROpenedParamsFromServer(x *bitbucketserver.PullRequestOpenedPayload) v1alpha1.PROpenedParams {
+	return v1alpha1.PROpenedParams{
+		Actor: botModelFromServerSenderContainingPayload(x),
+		PR:    botModelFromServerPRContainingPayload(x),
+	}
+}
+
+func intoPRUpdatedParamsFromServer(
+	x *bitbucketserver.PullRequestFromReferenceUpdatedPayload,
+) v1alpha1.PRUpdatedParams {
+	return v1alpha1.PRUpdatedParams{
+		Actor: botModelFromServerSenderContainingPayload(x),
+		PR:    botModelFromServerPRContainingPayload(x),
+	}
+}
+
+func intoPRRenamedParamsFromServer(x *bitbucketserver.PullRequestModifiedPayload) v1alpha1.PRRenamedParams {
+	return v1alpha1.PRRenamedParams{
+		Actor:    botModelFromServerSenderContainingPayload(x),
+		PR:       botModelFromServerPRContainingPayload(x),
+		OldTitle: x.PreviousTitle,
+	}
+}
+
+func intoPRReviewedParamsFromServer(x interface{}, review v1alpha1.ReviewType) v1alpha1.PRReviewedParams {
+	return v1alpha1.PRReviewedParams{
+		Actor:  botModelFromServerSenderContainingPayload(x),
+		PR:     botModelFromServerPRContainingPayload(x),
+		Review: review,
+	}
+}
+
+func intoPRMergedParamsFromServer(x *bitbucketserver.PullRequestMergedPayload) v1alpha1.PRMergedParams {
+	return v1alpha1.PRMergedParams{
+		Actor: botModelFromServerSenderContainingPayload(x),
+		PR:    botModelFromServerPRContainingPayload(x),
+	}
+}
+
+func intoPRClosedParamsFromServer(x *bitbucketserver.PullRequestDeclinedPayload) v1alpha1.PRClosedParams {
+	return v1alpha1.PRClosedParams{
+		Actor: botModelFromServerSenderContainingPayload(x),
+		PR:    botModelFromServerPRContainingPayload(x),
+	}
+}
+
+func intoPRReviewRequestedParamsFromServer(
+	x *bitbucketserver.PullRequestReviewerUpdatedPayload,
+) v1alpha1.PRReviewRequestedParams {
+	return v1alpha1.PRReviewRequestedParams{
+		Actor:     botModelFromServerSenderContainingPayload(x),
+		PR:        botModelFromServerPRContainingPayload(x),
+		Reviewers: botModelFromServerUsers(x.AddedReviewers),
+	}
+}
+
+func intoPRReviewRequestRemovedParamsFromServer(
+	x *bitbucketserver.PullRequestReviewerUpdatedPayload,
+) v1alpha1.PRReviewRequestRemovedParams {
+	return v1alpha1.PRReviewRequestRemovedParams{
+		Actor:     botModelFromServerSenderContainingPayload(x),
+		PR:        botModelFromServerPRContainingPayload(x),
+		Reviewers: botModelFromServerUsers(x.RemovedReviewers),
+	}
+}
+
+// Adapters for component fields
+
+func botModelFromServerSenderContainingPayload(x interface{}) v1alpha1.ForgeUser {
+	switch x := x.(type) {
+	case *bitbucketserver.PullRequestOpenedPayload:
+		return botModelFromServerUser(&x.Actor)
+
+	case *bitbucketserver.PullRequestFromReferenceUpdatedPayload:
+		return botModelFromServerUser(&x.Actor)
+
+	case *bitbucketserver.PullRequestModifiedPayload:
+		return botModelFromServerUser(&x.Actor)
+
+	case *bitbucketserver.PullRequestReviewerApprovedPayload:
+		return botModelFromServerUser(&x.Actor)
+
+	case *bitbucketserver.PullRequestReviewerNeedsWorkPayload:
+		return botModelFromServerUser(&x.Actor)
+
+	case *bitbucketserver.PullRequestMergedPayload:
+		return botModelFromServerUser(&x.Actor)
+
+	case *bitbucketserver.PullRequestDeclinedPayload:
+		return botModelFromServerUser(&x.Actor)
+
+	case *bitbucketserver.PullRequestReviewerUpdatedPayload:
+		return botModelFromServerUser(&x.Actor)
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromServerPRContainingPayload(x interface{}) v1alpha1.PR {
+	var pr *bitbucketserver.PullRequest
+	switch x := x.(type) {
+	case *bitbucketserver.PullRequestOpenedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucketserver.PullRequestFromReferenceUpdatedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucketserver.PullRequestModifiedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucketserver.PullRequestReviewerApprovedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucketserver.PullRequestReviewerNeedsWorkPayload:
+		pr = &x.PullRequest
+
+	case *bitbucketserver.PullRequestMergedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucketserver.PullRequestDeclinedPayload:
+		pr = &x.PullRequest
+
+	case *bitbucketserver.PullRequestReviewerUpdatedPayload:
+		pr = &x.PullRequest
+
+	default:
+		panic("should never happen")
+	}
+
+	reviewers := make([]v1alpha1.ForgeUser, len(pr.Reviewers))
+	for i := range pr.Reviewers {
+		reviewers[i] = botModelFromServerUser(&pr.Reviewers[i].User)
+	}
+
+	return v1alpha1.PR{
+		Repo:   botModelFromServerRepo(&pr.ToRef.Repository),
+		Number: int(pr.ID),
+		Title:  pr.Title,
+		Author: botModelFromServerUser(&pr.Author.User),
+		State:  issueStateFromPRState(pr.State),
+		URL:    selfLinkFromServerLinks(pr.Links),
+		Body:   pr.Description,
+
+		BaseBranch: pr.ToRef.DisplayID,
+		HeadBranch: pr.FromRef.DisplayID,
+		HeadSHA:    pr.FromRef.LatestCommit,
+
+		RequestedReviewers: reviewers,
+
+		CreatedAt: timeFromServerTimestamp(pr.CreatedDate),
+		UpdatedAt: timeFromServerTimestamp(pr.UpdatedDate),
+	}
+}
+
+func botModelFromServerUser(x *bitbucketserver.User) v1alpha1.ForgeUser {
+	return v1alpha1.ForgeUser{
+		Forge:    forgeTypeServer,
+		UserName: x.Name,
+	}
+}
+
+func botModelFromServerUsers(x []bitbucketserver.User) []v1alpha1.ForgeUser {
+	result := make([]v1alpha1.ForgeUser, len(x))
+	for i := range x {
+		result[i] = botModelFromServerUser(&x[i])
+	}
+	return result
+}
+
+func botModelFromServerRepo(x *bitbucketserver.Repository) v1alpha1.Repo {
+	// Repos belong to projects, or users' personal projects ("~USER").
+	return v1alpha1.Repo{
+		User: v1alpha1.ForgeUser{
+			Forge:    forgeTypeServer,
+			UserName: x.Project.Key,
+		},
+		RepoName: x.Slug,
+	}
+}
+
+// selfLinkFromServerLinks returns the first "self" link in the links object
+// of Bitbucket Server entities, or the empty string if there is none.
+func selfLinkFromServerLinks(links map[string]interface{}) string {
+	self, ok := links["self"].([]interface{})
+	if !ok || len(self) == 0 {
+		return ""
+	}
+
+	link, ok := self[0].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+
+	href, _ := link["href"].(string)
+	return href
+}
+
+// timeFromServerTimestamp converts Bitbucket Server timestamps, which are in
+// milliseconds since the epoch, into time.Time.
+func timeFromServerTimestamp(ts uint64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(ts))
+}
+
+// issueStateFromPRState maps PR states, which are the same for both Bitbucket
+// Cloud and Server.
+func issueStateFromPRState(state string) v1alpha1.IssueState {
+	switch state {
+	case "OPEN":
+		return v1alpha1.IssueStateOpen
+	case "MERGED":
+		return v1alpha1.IssueStateMerged
+	case "DECLINED", "SUPERSEDED":
+		return v1alpha1.IssueStateClosed
+	default:
+		return v1alpha1.IssueStateUnknown
+	}
+}
@@ -0,0 +1,295 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package bitbucket implements the forge hook for both Bitbucket Cloud and
+// Bitbucket Server (Data Center), which are told apart by the event keys.
+package bitbucket
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/webhooks/v6/bitbucket"
+	bitbucketserver "github.com/go-playground/webhooks/v6/bitbucket-server"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+const (
+	forgeTypeCloud  = "bitbucket"
+	forgeTypeServer = "bitbucket-server"
+)
+
+var (
+	ErrMissingEventKeyHeader  = errors.New("bitbucket: missing X-Event-Key header")
+	ErrMissingSignatureHeader = errors.New("bitbucket: missing X-Hub-Signature header")
+	ErrHMACVerificationFailed = errors.New("bitbucket: HMAC verification failed")
+	ErrSecretRequired         = errors.New("bitbucket: secret is required")
+)
+
+// cloudEvents is all Bitbucket Cloud events known to the upstream library.
+var cloudEvents = []bitbucket.Event{
+	bitbucket.RepoPushEvent,
+	bitbucket.RepoForkEvent,
+	bitbucket.RepoUpdatedEvent,
+	bitbucket.RepoCommitCommentCreatedEvent,
+	bitbucket.RepoCommitStatusCreatedEvent,
+	bitbucket.RepoCommitStatusUpdatedEvent,
+	bitbucket.IssueCreatedEvent,
+	bitbucket.IssueUpdatedEvent,
+	bitbucket.IssueCommentCreatedEvent,
+	bitbucket.PullRequestCreatedEvent,
+	bitbucket.PullRequestUpdatedEvent,
+	bitbucket.PullRequestApprovedEvent,
+	bitbucket.PullRequestUnapprovedEvent,
+	bitbucket.PullRequestMergedEvent,
+	bitbucket.PullRequestDeclinedEvent,
+	bitbucket.PullRequestCommentCreatedEvent,
+	bitbucket.PullRequestCommentUpdatedEvent,
+	bitbucket.PullRequestCommentDeletedEvent,
+}
+
+// serverEvents is all Bitbucket Server events known to the upstream library.
+var serverEvents = []bitbucketserver.Event{
+	bitbucketserver.RepositoryReferenceChangedEvent,
+	bitbucketserver.RepositoryModifiedEvent,
+	bitbucketserver.RepositoryForkedEvent,
+	bitbucketserver.RepositoryCommentAddedEvent,
+	bitbucketserver.RepositoryCommentEditedEvent,
+	bitbucketserver.RepositoryCommentDeletedEvent,
+	bitbucketserver.PullRequestOpenedEvent,
+	bitbucketserver.PullRequestFromReferenceUpdatedEvent,
+	bitbucketserver.PullRequestModifiedEvent,
+	bitbucketserver.PullRequestMergedEvent,
+	bitbucketserver.PullRequestDeclinedEvent,
+	bitbucketserver.PullRequestDeletedEvent,
+	bitbucketserver.PullRequestReviewerUpdatedEvent,
+	bitbucketserver.PullRequestReviewerApprovedEvent,
+	bitbucketserver.PullRequestReviewerUnapprovedEvent,
+	bitbucketserver.PullRequestReviewerNeedsWorkEvent,
+	bitbucketserver.PullRequestCommentAddedEvent,
+	bitbucketserver.PullRequestCommentEditedEvent,
+	bitbucketserver.PullRequestCommentDeletedEvent,
+	bitbucketserver.DiagnosticsPingEvent,
+}
+
+type bitbucketForge struct {
+	secret     []byte
+	cloudHook  *bitbucket.Webhook
+	serverHook *bitbucketserver.Webhook
+}
+
+var _ forge.IForgeHook = (*bitbucketForge)(nil)
+
+// New returns a new Bitbucket forge hook instance, serving both Bitbucket
+// Cloud and Bitbucket Server.
+func New(secret string) (forge.IForgeHook, error) {
+	if secret == "" {
+		return nil, ErrSecretRequired
+	}
+
+	// Both flavors sign the payloads in the same way nowadays, but the
+	// upstream library only knows about Bitbucket Server doing so, so the
+	// signature is checked here for both instead.
+	cloudHook, err := bitbucket.New()
+	if err != nil {
+		return nil, err
+	}
+
+	serverHook, err := bitbucketserver.New()
+	if err != nil {
+		return nil, err
+	}
+
+	return &bitbucketForge{
+		secret:     []byte(secret),
+		cloudHook:  cloudHook,
+		serverHook: serverHook,
+	}, nil
+}
+
+// HookRequest hooks an incoming webhook request to trigger actions.
+func (f *bitbucketForge) HookRequest(req *http.Request) (*v1alpha1.Event, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	e, err := f.hookRequest(req, body)
+	if e != nil {
+		// Bitbucket Cloud and Server use different headers.
+		id := req.Header.Get("X-Request-UUID")
+		if id == "" {
+			id = req.Header.Get("X-Request-Id")
+		}
+		e.SetDeliveryID(id)
+
+		// Event keys look like "pullrequest:created", with the action last.
+		eventKey := req.Header.Get("X-Event-Key")
+		if i := strings.LastIndexByte(eventKey, ':'); i >= 0 {
+			e.SetForgeEvent(eventKey[:i], eventKey[i+1:])
+		} else {
+			e.SetForgeEvent(eventKey, "")
+		}
+		e.SetRawPayload(body)
+	}
+	return e, err
+}
+
+func (f *bitbucketForge) hookRequest(req *http.Request, body []byte) (*v1alpha1.Event, error) {
+	event := req.Header.Get("X-Event-Key")
+	if event == "" {
+		return nil, ErrMissingEventKeyHeader
+	}
+
+	// Bitbucket Server sends pings without signing them.
+	if bitbucketserver.Event(event) != bitbucketserver.DiagnosticsPingEvent {
+		err := f.verify(req, body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, e := range cloudEvents {
+		if bitbucket.Event(event) == e {
+			return f.hookCloudRequest(req)
+		}
+	}
+
+	return f.hookServerRequest(req)
+}
+
+// verify checks the request's HMAC-SHA256 signature.
+func (f *bitbucketForge) verify(req *http.Request, body []byte) error {
+	signature := req.Header.Get("X-Hub-Signature")
+	if signature == "" {
+		return ErrMissingSignatureHeader
+	}
+
+	got, err := hex.DecodeString(strings.TrimPrefix(signature, "sha256="))
+	if err != nil {
+		return ErrHMACVerificationFailed
+	}
+
+	mac := hmac.New(sha256.New, f.secret)
+	_, _ = mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrHMACVerificationFailed
+	}
+
+	return nil
+}
+
+func (f *bitbucketForge) hookCloudRequest(req *http.Request) (*v1alpha1.Event, error) {
+	payload, err := f.cloudHook.Parse(req, cloudEvents...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := payload.(type) {
+	case bitbucket.PullRequestCreatedPayload:
+		params := intoPROpenedParamsFromCloud(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucket.PullRequestUpdatedPayload:
+		params := intoPRUpdatedParamsFromCloud(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucket.PullRequestApprovedPayload:
+		params := intoPRReviewedParamsFromCloud(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucket.PullRequestMergedPayload:
+		params := intoPRMergedParamsFromCloud(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucket.PullRequestDeclinedPayload:
+		params := intoPRClosedParamsFromCloud(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucket.RepoCommitStatusCreatedPayload:
+		if !isFinishedCommitStatusState(p.CommitStatus.State) {
+			return nil, nil
+		}
+
+		params := intoCIFinishedParamsFromCloud(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucket.RepoCommitStatusUpdatedPayload:
+		if !isFinishedCommitStatusState(p.CommitStatus.State) {
+			return nil, nil
+		}
+
+		params := intoCIFinishedParamsFromCloud(&p)
+		return params.IntoEvent(), nil
+	}
+
+	// Currently not handled
+	return nil, nil
+}
+
+func (f *bitbucketForge) hookServerRequest(req *http.Request) (*v1alpha1.Event, error) {
+	payload, err := f.serverHook.Parse(req, serverEvents...)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p := payload.(type) {
+	case bitbucketserver.PullRequestOpenedPayload:
+		params := intoPROpenedParamsFromServer(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucketserver.PullRequestFromReferenceUpdatedPayload:
+		params := intoPRUpdatedParamsFromServer(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucketserver.PullRequestModifiedPayload:
+		if p.PreviousTitle == p.PullRequest.Title {
+			// Only the title is interesting
+			return nil, nil
+		}
+
+		params := intoPRRenamedParamsFromServer(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucketserver.PullRequestReviewerApprovedPayload:
+		params := intoPRReviewedParamsFromServer(&p, v1alpha1.ReviewTypeApprove)
+		return params.IntoEvent(), nil
+
+	case bitbucketserver.PullRequestReviewerNeedsWorkPayload:
+		params := intoPRReviewedParamsFromServer(&p, v1alpha1.ReviewTypeRequestChanges)
+		return params.IntoEvent(), nil
+
+	case bitbucketserver.PullRequestMergedPayload:
+		params := intoPRMergedParamsFromServer(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucketserver.PullRequestDeclinedPayload:
+		params := intoPRClosedParamsFromServer(&p)
+		return params.IntoEvent(), nil
+
+	case bitbucketserver.PullRequestReviewerUpdatedPayload:
+		if len(p.AddedReviewers) > 0 {
+			params := intoPRReviewRequestedParamsFromServer(&p)
+			return params.IntoEvent(), nil
+		}
+
+		if len(p.RemovedReviewers) > 0 {
+			params := intoPRReviewRequestRemovedParamsFromServer(&p)
+			return params.IntoEvent(), nil
+		}
+
+		return nil, nil
+	}
+
+	// Currently not handled; Bitbucket Server does not send hooks for build
+	// statuses.
+	return nil, nil
+}
@@ -0,0 +1,374 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package bitbucket
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge/internal/hooktest"
+)
+
+const testSecret = "Sup3rS3cr3tStr1ng"
+
+func sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	_, _ = mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTestRequest(eventKey string, body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/bitbucket", bytes.NewReader(body))
+	req.Header.Set("X-Event-Key", eventKey)
+	req.Header.Set("X-Hub-Signature", sign(body))
+	req.Header.Set("X-Request-UUID", "3b9f2c1d-7e6a-4f5b-8c0d-1e2f3a4b5c6d")
+	return req
+}
+
+func newTestHook(t *testing.T) *bitbucketForge {
+	t.Helper()
+
+	fh, err := New(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fh.(*bitbucketForge)
+}
+
+// hookFixture feeds the recorded hook payload in testdata to a new forge hook
+// instance.
+func hookFixture(t *testing.T, eventKey string, name string) *v1alpha1.Event {
+	t.Helper()
+
+	e, err := newTestHook(t).HookRequest(newTestRequest(eventKey, hooktest.Fixture(t, name)))
+	if err != nil {
+		t.Fatalf("HookRequest: %v", err)
+	}
+	return e
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("")
+	if !errors.Is(err, ErrSecretRequired) {
+		t.Errorf("want %v, got %v", ErrSecretRequired, err)
+	}
+}
+
+func TestHookRequestRejected(t *testing.T) {
+	body := hooktest.Fixture(t, "cloud-pr-created.json")
+
+	testcases := []struct {
+		name   string
+		modify func(req *http.Request)
+		want   error
+	}{
+		{
+			name:   "no event key",
+			modify: func(req *http.Request) { req.Header.Del("X-Event-Key") },
+			want:   ErrMissingEventKeyHeader,
+		},
+		{
+			name:   "no signature",
+			modify: func(req *http.Request) { req.Header.Del("X-Hub-Signature") },
+			want:   ErrMissingSignatureHeader,
+		},
+		{
+			name:   "wrong signature",
+			modify: func(req *http.Request) { req.Header.Set("X-Hub-Signature", sign([]byte("{}"))) },
+			want:   ErrHMACVerificationFailed,
+		},
+		{
+			name:   "malformed signature",
+			modify: func(req *http.Request) { req.Header.Set("X-Hub-Signature", "sha256=xyz") },
+			want:   ErrHMACVerificationFailed,
+		},
+		{
+			// only pings are exempt
+			name: "unsigned Server event",
+			modify: func(req *http.Request) {
+				req.Header.Set("X-Event-Key", "pr:opened")
+				req.Header.Del("X-Hub-Signature")
+			},
+			want: ErrMissingSignatureHeader,
+		},
+	}
+
+	f := newTestHook(t)
+	for _, tc := range testcases {
+		req := newTestRequest("pullrequest:created", body)
+		tc.modify(req)
+
+		e, err := f.HookRequest(req)
+		if e != nil || !errors.Is(err, tc.want) {
+			t.Errorf("%s: want %v, got %v, %v", tc.name, tc.want, e, err)
+		}
+	}
+}
+
+func TestHookRequestPing(t *testing.T) {
+	// Bitbucket Server does not sign pings
+	req := httptest.NewRequest(http.MethodPost, "/bitbucket", bytes.NewReader(nil))
+	req.Header.Set("X-Event-Key", "diagnostics:ping")
+
+	e, err := newTestHook(t).HookRequest(req)
+	if e != nil || err != nil {
+		t.Errorf("want nothing, got %v, %v", e, err)
+	}
+}
+
+func mustParseTime(t *testing.T, s string) time.Time {
+	t.Helper()
+
+	result, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestCloudHooks(t *testing.T) {
+	user := func(name string) v1alpha1.ForgeUser {
+		return v1alpha1.ForgeUser{Forge: forgeTypeCloud, UserName: name}
+	}
+	repo := v1alpha1.Repo{User: user("acme"), RepoName: "widget"}
+
+	pr := func(state v1alpha1.IssueState) v1alpha1.PR {
+		return v1alpha1.PR{
+			Repo:   repo,
+			Number: 17,
+			Title:  "Retry uploads on transient errors",
+			Author: user("alice"),
+			State:  state,
+			URL:    "https://bitbucket.org/acme/widget/pull-requests/17",
+			Body:   "Retries the upload on transient errors.",
+
+			BaseBranch: "main",
+			HeadBranch: "fix-upload-retry",
+			HeadSHA:    "5f1c0e7b6d3a",
+
+			RequestedReviewers: []v1alpha1.ForgeUser{user("carol")},
+
+			CreatedAt: mustParseTime(t, "2023-09-12T08:15:03.123456+00:00"),
+			UpdatedAt: mustParseTime(t, "2023-09-12T09:02:41.654321+00:00"),
+		}
+	}
+
+	testcases := []struct {
+		eventKey string
+		fixture  string
+		want     hooktest.Params
+	}{
+		{
+			eventKey: "pullrequest:created",
+			fixture:  "cloud-pr-created.json",
+			want: &v1alpha1.PROpenedParams{
+				Actor: user("alice"),
+				PR:    pr(v1alpha1.IssueStateOpen),
+			},
+		},
+		{
+			eventKey: "pullrequest:updated",
+			fixture:  "cloud-pr-updated.json",
+			want: &v1alpha1.PRUpdatedParams{
+				Actor: user("alice"),
+				PR:    pr(v1alpha1.IssueStateOpen),
+			},
+		},
+		{
+			eventKey: "pullrequest:approved",
+			fixture:  "cloud-pr-approved.json",
+			want: &v1alpha1.PRReviewedParams{
+				Actor:  user("carol"),
+				PR:     pr(v1alpha1.IssueStateOpen),
+				Review: v1alpha1.ReviewTypeApprove,
+			},
+		},
+		{
+			eventKey: "pullrequest:fulfilled",
+			fixture:  "cloud-pr-fulfilled.json",
+			want: &v1alpha1.PRMergedParams{
+				Actor: user("bob"),
+				PR:    pr(v1alpha1.IssueStateMerged),
+			},
+		},
+		{
+			eventKey: "pullrequest:rejected",
+			fixture:  "cloud-pr-rejected.json",
+			want: &v1alpha1.PRClosedParams{
+				Actor: user("bob"),
+				PR:    pr(v1alpha1.IssueStateClosed),
+			},
+		},
+		{
+			eventKey: "repo:commit_status_updated",
+			fixture:  "cloud-status-updated.json",
+			want: &v1alpha1.CIFinishedParams{
+				Run: v1alpha1.CIRun{
+					Repo:  repo,
+					State: v1alpha1.CIStateFailed,
+					SHA:   "5f1c0e7b6d3a8e4f2b9c1d0a7e6f5b4c3d2e1f0a",
+					URL:   "https://ci.example.com/builds/7",
+				},
+			},
+		},
+		{
+			// still running
+			eventKey: "repo:commit_status_created",
+			fixture:  "cloud-status-created.json",
+			want:     nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.fixture, func(t *testing.T) {
+			e := hookFixture(t, tc.eventKey, tc.fixture)
+			hooktest.AssertEvent(t, e, tc.want)
+		})
+	}
+}
+
+func TestServerHooks(t *testing.T) {
+	user := func(name string) v1alpha1.ForgeUser {
+		return v1alpha1.ForgeUser{Forge: forgeTypeServer, UserName: name}
+	}
+
+	pr := func(state v1alpha1.IssueState, reviewers ...string) v1alpha1.PR {
+		result := v1alpha1.PR{
+			Repo: v1alpha1.Repo{
+				User:     user("ACME"),
+				RepoName: "widget",
+			},
+			Number: 17,
+			Title:  "Retry uploads on transient errors",
+			Author: user("alice"),
+			State:  state,
+			URL:    "https://bitbucket.example.com/projects/ACME/repos/widget/pull-requests/17",
+			Body:   "Retries the upload on transient errors.",
+
+			BaseBranch: "main",
+			HeadBranch: "fix-upload-retry",
+			HeadSHA:    "5f1c0e7b6d3a8e4f2b9c1d0a7e6f5b4c3d2e1f0a",
+
+			RequestedReviewers: []v1alpha1.ForgeUser{user("carol")},
+
+			CreatedAt: time.UnixMilli(1694506503000),
+			UpdatedAt: time.UnixMilli(1694509361000),
+		}
+		for _, r := range reviewers {
+			result.RequestedReviewers = append(result.RequestedReviewers, user(r))
+		}
+		return result
+	}
+
+	opened := pr(v1alpha1.IssueStateOpen)
+	opened.UpdatedAt = opened.CreatedAt
+
+	testcases := []struct {
+		eventKey string
+		fixture  string
+		want     hooktest.Params
+	}{
+		{
+			eventKey: "pr:opened",
+			fixture:  "server-pr-opened.json",
+			want: &v1alpha1.PROpenedParams{
+				Actor: user("alice"),
+				PR:    opened,
+			},
+		},
+		{
+			eventKey: "pr:from_ref_updated",
+			fixture:  "server-pr-from-ref-updated.json",
+			want: &v1alpha1.PRUpdatedParams{
+				Actor: user("alice"),
+				PR:    pr(v1alpha1.IssueStateOpen),
+			},
+		},
+		{
+			eventKey: "pr:modified",
+			fixture:  "server-pr-modified-title.json",
+			want: &v1alpha1.PRRenamedParams{
+				Actor:    user("alice"),
+				PR:       pr(v1alpha1.IssueStateOpen),
+				OldTitle: "Retry uploads",
+			},
+		},
+		{
+			// only the title is interesting
+			eventKey: "pr:modified",
+			fixture:  "server-pr-modified-description.json",
+			want:     nil,
+		},
+		{
+			eventKey: "pr:reviewer:approved",
+			fixture:  "server-pr-approved.json",
+			want: &v1alpha1.PRReviewedParams{
+				Actor:  user("carol"),
+				PR:     pr(v1alpha1.IssueStateOpen),
+				Review: v1alpha1.ReviewTypeApprove,
+			},
+		},
+		{
+			eventKey: "pr:reviewer:needs_work",
+			fixture:  "server-pr-needs-work.json",
+			want: &v1alpha1.PRReviewedParams{
+				Actor:  user("carol"),
+				PR:     pr(v1alpha1.IssueStateOpen),
+				Review: v1alpha1.ReviewTypeRequestChanges,
+			},
+		},
+		{
+			eventKey: "pr:merged",
+			fixture:  "server-pr-merged.json",
+			want: &v1alpha1.PRMergedParams{
+				Actor: user("bob"),
+				PR:    pr(v1alpha1.IssueStateMerged),
+			},
+		},
+		{
+			eventKey: "pr:declined",
+			fixture:  "server-pr-declined.json",
+			want: &v1alpha1.PRClosedParams{
+				Actor: user("bob"),
+				PR:    pr(v1alpha1.IssueStateClosed),
+			},
+		},
+		{
+			eventKey: "pr:reviewer:updated",
+			fixture:  "server-pr-reviewer-updated.json",
+			want: &v1alpha1.PRReviewRequestedParams{
+				Actor:     user("alice"),
+				PR:        pr(v1alpha1.IssueStateOpen, "dave"),
+				Reviewers: []v1alpha1.ForgeUser{user("dave")},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.fixture, func(t *testing.T) {
+			e := hookFixture(t, tc.eventKey, tc.fixture)
+			hooktest.AssertEvent(t, e, tc.want)
+		})
+	}
+}
+
+func TestHookRequestMetadata(t *testing.T) {
+	e := hookFixture(t, "pr:reviewer:approved", "server-pr-approved.json")
+
+	if got := e.DeliveryID(); got != "3b9f2c1d-7e6a-4f5b-8c0d-1e2f3a4b5c6d" {
+		t.Errorf("delivery ID: got %q", got)
+	}
+	if e.ForgeEvent() != "pr:reviewer" || e.ForgeAction() != "approved" {
+		t.Errorf("event: got %q, %q", e.ForgeEvent(), e.ForgeAction())
+	}
+}
@@ -0,0 +1,56 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package forge
+
+import (
+	"sync"
+	"time"
+)
+
+// StatusClaimTTL is how long the final combined statuses reported are
+// remembered by StatusClaims. Duplicates come from statuses finishing close
+// together, so this need not be long, and should not be, for re-runs reaching
+// the same state again to be reported.
+const StatusClaimTTL = 10 * time.Minute
+
+// StatusClaims remembers which event reported the final combined commit
+// status of commits, for forges sending one hook per status context, so that
+// the combined status is only reported once. Other changes sent in several
+// hooks can be claimed likewise.
+type StatusClaims struct {
+	mu     sync.Mutex
+	claims map[string]statusClaim
+}
+
+type statusClaim struct {
+	eventID string
+	at      time.Time
+}
+
+// NewStatusClaims returns an empty StatusClaims.
+func NewStatusClaims() *StatusClaims {
+	return &StatusClaims{
+		claims: make(map[string]statusClaim),
+	}
+}
+
+// Claim returns whether the event is the one to report the status of the
+// key, which is true for the first event claiming it, and for retries of
+// that event.
+func (c *StatusClaims) Claim(key string, eventID string, now time.Time) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for k, x := range c.claims {
+		if now.Sub(x.at) > StatusClaimTTL {
+			delete(c.claims, k)
+		}
+	}
+
+	if x, ok := c.claims[key]; ok {
+		return x.eventID == eventID
+	}
+
+	c.claims[key] = statusClaim{eventID: eventID, at: now}
+	return true
+}
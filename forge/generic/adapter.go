@@ -0,0 +1,362 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package generic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// Supported events.
+const (
+	EventPROpened                = "pr_opened"
+	EventPRClosed                = "pr_closed"
+	EventPRMerged                = "pr_merged"
+	EventCIFinished              = "ci_finished"
+	EventReleasePublished        = "release_published"
+	EventDeploymentStatusChanged = "deployment_status_changed"
+)
+
+// Supported fields.
+const (
+	FieldActor     = "actor"
+	FieldRepoOwner = "repo_owner"
+	FieldRepoName  = "repo_name"
+
+	// FieldDeliveryID is the ID of the delivery, for dropping duplicate
+	// deliveries. Options.DeliveryIDHeader is used if this is empty.
+	FieldDeliveryID = "delivery_id"
+	// FieldEventName and FieldEventAction are the system's own name and
+	// action of the event, for the information of bot plugins.
+	// Options.EventHeader is used if FieldEventName is empty.
+	FieldEventName   = "event_name"
+	FieldEventAction = "event_action"
+
+	FieldPRNumber     = "pr_number"
+	FieldPRTitle      = "pr_title"
+	FieldPRAuthor     = "pr_author"
+	FieldPRURL        = "pr_url"
+	FieldPRBody       = "pr_body"
+	FieldPRBaseBranch = "pr_base_branch"
+	FieldPRHeadBranch = "pr_head_branch"
+	FieldPRHeadSHA    = "pr_head_sha"
+
+	// FieldCIState is matched case-insensitively against common names of
+	// final CI states, e.g. "success", "failure" or Jenkins' "ABORTED". No
+	// event is emitted for other states, e.g. "RUNNING".
+	FieldCIState = "ci_state"
+	FieldCISHA   = "ci_sha"
+	FieldCIURL   = "ci_url"
+
+	FieldReleaseTag        = "release_tag"
+	FieldReleaseName       = "release_name"
+	FieldReleaseNotes      = "release_notes"
+	FieldReleaseURL        = "release_url"
+	FieldReleasePrerelease = "release_prerelease"
+
+	FieldDeploymentID          = "deployment_id"
+	FieldDeploymentEnvironment = "deployment_environment"
+	FieldDeploymentRef         = "deployment_ref"
+	FieldDeploymentSHA         = "deployment_sha"
+	FieldDeploymentURL         = "deployment_url"
+	// FieldDeploymentState is matched case-insensitively like FieldCIState.
+	FieldDeploymentState       = "deployment_state"
+	FieldDeploymentDescription = "deployment_description"
+)
+
+var commonFields = []string{
+	FieldActor,
+	FieldRepoOwner,
+	FieldRepoName,
+	FieldDeliveryID,
+	FieldEventName,
+	FieldEventAction,
+}
+
+var prFields = []string{
+	FieldPRNumber,
+	FieldPRTitle,
+	FieldPRAuthor,
+	FieldPRURL,
+	FieldPRBody,
+	FieldPRBaseBranch,
+	FieldPRHeadBranch,
+	FieldPRHeadSHA,
+}
+
+// fieldsByEvent is the fields applicable to each supported event.
+var fieldsByEvent = map[string][]string{
+	EventPROpened: prFields,
+	EventPRClosed: prFields,
+	EventPRMerged: prFields,
+	EventCIFinished: append([]string{
+		FieldCIState,
+		FieldCISHA,
+		FieldCIURL,
+	}, prFields...),
+	EventReleasePublished: {
+		FieldReleaseTag,
+		FieldReleaseName,
+		FieldReleaseNotes,
+		FieldReleaseURL,
+		FieldReleasePrerelease,
+	},
+	EventDeploymentStatusChanged: {
+		FieldDeploymentID,
+		FieldDeploymentEnvironment,
+		FieldDeploymentRef,
+		FieldDeploymentSHA,
+		FieldDeploymentURL,
+		FieldDeploymentState,
+		FieldDeploymentDescription,
+	},
+}
+
+func validateRule(r *Rule) error {
+	if r.Event == "" {
+		return ErrMissingEventIdentifier
+	}
+
+	fields, ok := fieldsByEvent[r.Event]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownEvent, r.Event)
+	}
+
+	check := func(name string) error {
+		for _, f := range commonFields {
+			if name == f {
+				return nil
+			}
+		}
+		for _, f := range fields {
+			if name == f {
+				return nil
+			}
+		}
+		for _, fs := range fieldsByEvent {
+			for _, f := range fs {
+				if name == f {
+					return fmt.Errorf("%w: %s for %s", ErrInvalidFieldForEvent, name, r.Event)
+				}
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrUnknownField, name)
+	}
+
+	for name := range r.Fields {
+		if err := check(name); err != nil {
+			return err
+		}
+	}
+	for name := range r.Values {
+		if err := check(name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fieldExtractor evaluates a rule's field mapping against a payload.
+type fieldExtractor struct {
+	rule *Rule
+	doc  interface{}
+}
+
+func (x *fieldExtractor) get(name string) string {
+	if path, ok := x.rule.Fields[name]; ok {
+		return lookupPathString(x.doc, path)
+	}
+	return x.rule.Values[name]
+}
+
+func (x *fieldExtractor) getInt(name string) int64 {
+	// tolerate things like "#123" or "!123"
+	s := strings.TrimLeft(x.get(name), "#!")
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func (x *fieldExtractor) getBool(name string) bool {
+	b, err := strconv.ParseBool(x.get(name))
+	if err != nil {
+		return false
+	}
+	return b
+}
+
+// intoEvent returns the event of the rule for the document, or nil if there is
+// none, e.g. for CI runs still in progress.
+func (f *genericForge) intoEvent(r *Rule, doc interface{}) *v1alpha1.Event {
+	x := &fieldExtractor{rule: r, doc: doc}
+
+	switch r.Event {
+	case EventPROpened:
+		params := v1alpha1.PROpenedParams{
+			Actor: f.botModelFromUserName(x.get(FieldActor)),
+			PR:    f.botModelFromPRFields(x, v1alpha1.IssueStateOpen),
+		}
+		return params.IntoEvent()
+
+	case EventPRClosed:
+		params := v1alpha1.PRClosedParams{
+			Actor: f.botModelFromUserName(x.get(FieldActor)),
+			PR:    f.botModelFromPRFields(x, v1alpha1.IssueStateClosed),
+		}
+		return params.IntoEvent()
+
+	case EventPRMerged:
+		params := v1alpha1.PRMergedParams{
+			Actor: f.botModelFromUserName(x.get(FieldActor)),
+			PR:    f.botModelFromPRFields(x, v1alpha1.IssueStateMerged),
+		}
+		return params.IntoEvent()
+
+	case EventCIFinished:
+		state := ciStateFromString(x.get(FieldCIState))
+		if !isFinishedCIState(state) {
+			// CI run still in progress, or in a state we do not know
+			return nil
+		}
+
+		var pr v1alpha1.PR
+		var prs []v1alpha1.PR
+		if x.getInt(FieldPRNumber) != 0 {
+			pr = f.botModelFromPRFields(x, v1alpha1.IssueStateUnknown)
+			prs = []v1alpha1.PR{pr}
+		}
+
+		params := v1alpha1.CIFinishedParams{
+			Run: v1alpha1.CIRun{
+				Repo:  f.botModelFromRepoFields(x),
+				PR:    pr,
+				PRs:   prs,
+				State: state,
+				SHA:   x.get(FieldCISHA),
+				URL:   x.get(FieldCIURL),
+			},
+		}
+		return params.IntoEvent()
+
+	case EventReleasePublished:
+		params := v1alpha1.ReleasePublishedParams{
+			Actor: f.botModelFromUserName(x.get(FieldActor)),
+			Release: v1alpha1.Release{
+				Repo:         f.botModelFromRepoFields(x),
+				Tag:          x.get(FieldReleaseTag),
+				Name:         x.get(FieldReleaseName),
+				Notes:        x.get(FieldReleaseNotes),
+				IsPrerelease: x.getBool(FieldReleasePrerelease),
+				Author:       f.botModelFromUserName(x.get(FieldActor)),
+				URL:          x.get(FieldReleaseURL),
+			},
+		}
+		return params.IntoEvent()
+
+	case EventDeploymentStatusChanged:
+		params := v1alpha1.DeploymentStatusChangedParams{
+			Actor: f.botModelFromUserName(x.get(FieldActor)),
+			Deployment: v1alpha1.Deployment{
+				Repo:        f.botModelFromRepoFields(x),
+				ID:          x.getInt(FieldDeploymentID),
+				Environment: x.get(FieldDeploymentEnvironment),
+				Ref:         x.get(FieldDeploymentRef),
+				SHA:         x.get(FieldDeploymentSHA),
+				Creator:     f.botModelFromUserName(x.get(FieldActor)),
+				URL:         x.get(FieldDeploymentURL),
+			},
+			State:       deploymentStateFromString(x.get(FieldDeploymentState)),
+			Description: x.get(FieldDeploymentDescription),
+			URL:         x.get(FieldDeploymentURL),
+		}
+		return params.IntoEvent()
+
+	default:
+		// rules are validated on construction
+		panic("should never happen")
+	}
+}
+
+// Adapters for component fields
+
+func (f *genericForge) botModelFromUserName(userName string) v1alpha1.ForgeUser {
+	if userName == "" {
+		return v1alpha1.ForgeUser{}
+	}
+
+	return v1alpha1.ForgeUser{
+		Forge:    f.name,
+		UserName: userName,
+	}
+}
+
+func (f *genericForge) botModelFromRepoFields(x *fieldExtractor) v1alpha1.Repo {
+	return v1alpha1.Repo{
+		User:     f.botModelFromUserName(x.get(FieldRepoOwner)),
+		RepoName: x.get(FieldRepoName),
+	}
+}
+
+func (f *genericForge) botModelFromPRFields(x *fieldExtractor, state v1alpha1.IssueState) v1alpha1.PR {
+	return v1alpha1.PR{
+		Repo:       f.botModelFromRepoFields(x),
+		Number:     int(x.getInt(FieldPRNumber)),
+		Title:      x.get(FieldPRTitle),
+		Author:     f.botModelFromUserName(x.get(FieldPRAuthor)),
+		State:      state,
+		URL:        x.get(FieldPRURL),
+		Body:       x.get(FieldPRBody),
+		BaseBranch: x.get(FieldPRBaseBranch),
+		HeadBranch: x.get(FieldPRHeadBranch),
+		HeadSHA:    x.get(FieldPRHeadSHA),
+	}
+}
+
+// isFinishedCIState returns whether the state from ciStateFromString is final.
+func isFinishedCIState(state v1alpha1.CIState) bool {
+	return state != v1alpha1.CIStateUnknown
+}
+
+func ciStateFromString(s string) v1alpha1.CIState {
+	switch strings.ToLower(s) {
+	case "success", "succeeded", "passed", "ok":
+		return v1alpha1.CIStatePassed
+	case "failure", "failed", "unstable":
+		return v1alpha1.CIStateFailed
+	case "error", "errored":
+		return v1alpha1.CIStateErrored
+	case "canceled", "cancelled", "aborted":
+		return v1alpha1.CIStateCanceled
+	case "skipped", "not_built":
+		return v1alpha1.CIStateSkipped
+	default:
+		return v1alpha1.CIStateUnknown
+	}
+}
+
+func deploymentStateFromString(s string) v1alpha1.DeploymentState {
+	switch strings.ToLower(s) {
+	case "pending", "queued", "created":
+		return v1alpha1.DeploymentStatePending
+	case "in_progress", "running", "started":
+		return v1alpha1.DeploymentStateInProgress
+	case "success", "succeeded", "finished":
+		return v1alpha1.DeploymentStateSuccess
+	case "failure", "failed":
+		return v1alpha1.DeploymentStateFailure
+	case "error", "errored":
+		return v1alpha1.DeploymentStateError
+	case "canceled", "cancelled", "aborted":
+		return v1alpha1.DeploymentStateCanceled
+	case "inactive":
+		return v1alpha1.DeploymentStateInactive
+	default:
+		return v1alpha1.DeploymentStateUnknown
+	}
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package generic
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+func TestValidateRule(t *testing.T) {
+	testcases := []struct {
+		name string
+		rule Rule
+		want error
+	}{
+		{
+			name: "valid",
+			rule: Rule{
+				Event:  EventCIFinished,
+				Fields: map[string]string{FieldCIState: "status", FieldPRNumber: "pr", FieldDeliveryID: "id"},
+				Values: map[string]string{FieldRepoOwner: "infra"},
+			},
+		},
+		{
+			name: "missing event",
+			rule: Rule{},
+			want: ErrMissingEventIdentifier,
+		},
+		{
+			name: "unknown event",
+			rule: Rule{Event: "pr_exploded"},
+			want: ErrUnknownEvent,
+		},
+		{
+			name: "unknown field",
+			rule: Rule{Event: EventPROpened, Fields: map[string]string{"pr_color": "color"}},
+			want: ErrUnknownField,
+		},
+		{
+			name: "unknown value",
+			rule: Rule{Event: EventPROpened, Values: map[string]string{"pr_color": "red"}},
+			want: ErrUnknownField,
+		},
+		{
+			name: "field of another event",
+			rule: Rule{Event: EventPROpened, Fields: map[string]string{FieldReleaseTag: "tag"}},
+			want: ErrInvalidFieldForEvent,
+		},
+	}
+
+	for _, tc := range testcases {
+		err := validateRule(&tc.rule)
+		if tc.want == nil {
+			if err != nil {
+				t.Errorf("%s: want no error, got %v", tc.name, err)
+			}
+			continue
+		}
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	doc := decodeTestDoc(t, `{"build": {"phase": "FINALIZED", "number": 42, "cause": null}}`)
+
+	testcases := []struct {
+		match map[string]string
+		want  bool
+	}{
+		{nil, true},
+		{map[string]string{"build.phase": "FINALIZED"}, true},
+		{map[string]string{"build.phase": "FINALIZED", "build.number": "42"}, true},
+		{map[string]string{"build.phase": "STARTED"}, false},
+		{map[string]string{"build.phase": "FINALIZED", "build.number": "43"}, false},
+		// nulls are present, unlike missing values
+		{map[string]string{"build.cause": ""}, true},
+		{map[string]string{"build.missing": ""}, false},
+	}
+
+	for _, tc := range testcases {
+		r := Rule{Match: tc.match}
+		if got := r.matches(doc); got != tc.want {
+			t.Errorf("match %v: got %v, want %v", tc.match, got, tc.want)
+		}
+	}
+}
+
+func TestCIStateFromString(t *testing.T) {
+	testcases := []struct {
+		in       string
+		want     v1alpha1.CIState
+		finished bool
+	}{
+		{"success", v1alpha1.CIStatePassed, true},
+		{"SUCCESS", v1alpha1.CIStatePassed, true},
+		{"passed", v1alpha1.CIStatePassed, true},
+		{"failure", v1alpha1.CIStateFailed, true},
+		{"UNSTABLE", v1alpha1.CIStateFailed, true},
+		{"error", v1alpha1.CIStateErrored, true},
+		{"ABORTED", v1alpha1.CIStateCanceled, true},
+		{"cancelled", v1alpha1.CIStateCanceled, true},
+		{"NOT_BUILT", v1alpha1.CIStateSkipped, true},
+		{"RUNNING", v1alpha1.CIStateUnknown, false},
+		{"", v1alpha1.CIStateUnknown, false},
+	}
+
+	for _, tc := range testcases {
+		got := ciStateFromString(tc.in)
+		if got != tc.want {
+			t.Errorf("ciStateFromString(%q): got %d, want %d", tc.in, got, tc.want)
+		}
+		if isFinishedCIState(got) != tc.finished {
+			t.Errorf("isFinishedCIState of %q: got %v, want %v", tc.in, !tc.finished, tc.finished)
+		}
+	}
+}
+
+func TestDeploymentStateFromString(t *testing.T) {
+	testcases := []struct {
+		in   string
+		want v1alpha1.DeploymentState
+	}{
+		{"queued", v1alpha1.DeploymentStatePending},
+		{"CREATED", v1alpha1.DeploymentStatePending},
+		{"in_progress", v1alpha1.DeploymentStateInProgress},
+		{"Running", v1alpha1.DeploymentStateInProgress},
+		{"success", v1alpha1.DeploymentStateSuccess},
+		{"finished", v1alpha1.DeploymentStateSuccess},
+		{"failed", v1alpha1.DeploymentStateFailure},
+		{"error", v1alpha1.DeploymentStateError},
+		{"aborted", v1alpha1.DeploymentStateCanceled},
+		{"inactive", v1alpha1.DeploymentStateInactive},
+		{"rolled_back", v1alpha1.DeploymentStateUnknown},
+	}
+
+	for _, tc := range testcases {
+		if got := deploymentStateFromString(tc.in); got != tc.want {
+			t.Errorf("deploymentStateFromString(%q): got %d, want %d", tc.in, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package generic implements a forge hook for arbitrary systems that are able
+// to POST JSON, with the JSON-to-event mapping declared in configuration.
+package generic
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+// Supported authentication methods.
+const (
+	// AuthHMAC is for verifying a hex-encoded HMAC-SHA256 signature of the
+	// request body, optionally prefixed with "sha256=" like GitHub does.
+	AuthHMAC = "hmac"
+	// AuthBearer is for checking the "Authorization: Bearer <secret>" header.
+	AuthBearer = "bearer"
+)
+
+const defaultSignatureHeader = "X-Hub-Signature-256"
+
+var (
+	ErrMissingSecret          = errors.New("generic: secret must not be empty")
+	ErrInvalidHTTPMethod      = errors.New("generic: invalid HTTP method")
+	ErrVerificationFailed     = errors.New("generic: request authentication failed")
+	ErrInvalidPayload         = errors.New("generic: payload is not a JSON object")
+	ErrUnknownAuthMethod      = errors.New("generic: unknown authentication method")
+	ErrUnknownEvent           = errors.New("generic: unknown event")
+	ErrUnknownField           = errors.New("generic: unknown field")
+	ErrInvalidFieldForEvent   = errors.New("generic: field not applicable to event")
+	ErrMissingEventIdentifier = errors.New("generic: rule must specify an event")
+)
+
+// Options is the configuration of a generic forge hook instance.
+type Options struct {
+	// Name identifies this instance, and is used as the forge name of all
+	// users and repos in events produced.
+	Name string
+	// Auth is the authentication method, one of AuthHMAC (the default) and
+	// AuthBearer.
+	Auth   string
+	Secret string
+	// SignatureHeader is the request header carrying the HMAC signature,
+	// defaults to "X-Hub-Signature-256".
+	SignatureHeader string
+	// DeliveryIDHeader is the request header carrying the ID of the
+	// delivery, if any, for dropping duplicate deliveries. The delivery_id
+	// field of rules takes precedence.
+	DeliveryIDHeader string
+	// EventHeader is the request header carrying the system's own name of
+	// the event, if any. The event_name field of rules takes precedence.
+	EventHeader string
+	// Rules are tried in order, and the first matching rule decides the
+	// event produced. Requests matching no rule are ignored.
+	Rules []Rule
+}
+
+// Rule maps matching JSON payloads to a bot event.
+type Rule struct {
+	// Match is the conditions for this rule to apply, from JSON paths to the
+	// expected values in string form. All conditions have to be satisfied.
+	Match map[string]string
+	// Event is the kind of bot event to produce, see the Event* constants.
+	Event string
+	// Fields maps event fields to the JSON paths to take values from, see
+	// the Field* constants.
+	Fields map[string]string
+	// Values maps event fields to constant values, for information that is
+	// not present in the payloads. Fields take precedence.
+	Values map[string]string
+}
+
+type genericForge struct {
+	name             string
+	auth             string
+	secret           []byte
+	signatureHeader  string
+	deliveryIDHeader string
+	eventHeader      string
+	rules            []Rule
+}
+
+var _ forge.IForgeHook = (*genericForge)(nil)
+
+// New returns a new generic forge hook instance.
+func New(opts Options) (forge.IForgeHook, error) {
+	if opts.Secret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	auth := opts.Auth
+	switch auth {
+	case "":
+		auth = AuthHMAC
+	case AuthHMAC, AuthBearer:
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownAuthMethod, auth)
+	}
+
+	signatureHeader := opts.SignatureHeader
+	if signatureHeader == "" {
+		signatureHeader = defaultSignatureHeader
+	}
+
+	for i := range opts.Rules {
+		err := validateRule(&opts.Rules[i])
+		if err != nil {
+			return nil, fmt.Errorf("rule #%d: %w", i, err)
+		}
+	}
+
+	return &genericForge{
+		name:             opts.Name,
+		auth:             auth,
+		secret:           []byte(opts.Secret),
+		signatureHeader:  signatureHeader,
+		deliveryIDHeader: opts.DeliveryIDHeader,
+		eventHeader:      opts.EventHeader,
+		rules:            opts.Rules,
+	}, nil
+}
+
+// HookRequest hooks an incoming webhook request to trigger actions.
+func (f *genericForge) HookRequest(req *http.Request) (*v1alpha1.Event, error) {
+	if req.Method != http.MethodPost {
+		return nil, ErrInvalidHTTPMethod
+	}
+
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if !f.verify(req, body) {
+		return nil, ErrVerificationFailed
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var doc interface{}
+	err = dec.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, ErrInvalidPayload
+	}
+
+	for i := range f.rules {
+		r := &f.rules[i]
+		if !r.matches(doc) {
+			continue
+		}
+
+		e := f.intoEvent(r, doc)
+		if e == nil {
+			return nil, nil
+		}
+		f.setMetadata(e, req, r, doc)
+		e.SetRawPayload(body)
+		return e, nil
+	}
+
+	// No rule matched
+	return nil, nil
+}
+
+// setMetadata records the delivery ID and the event name and action, from the
+// rule's fields if present, or from the configured headers.
+func (f *genericForge) setMetadata(e *v1alpha1.Event, req *http.Request, r *Rule, doc interface{}) {
+	x := &fieldExtractor{rule: r, doc: doc}
+
+	deliveryID := x.get(FieldDeliveryID)
+	if deliveryID == "" && f.deliveryIDHeader != "" {
+		deliveryID = req.Header.Get(f.deliveryIDHeader)
+	}
+	e.SetDeliveryID(deliveryID)
+
+	event := x.get(FieldEventName)
+	if event == "" && f.eventHeader != "" {
+		event = req.Header.Get(f.eventHeader)
+	}
+	e.SetForgeEvent(event, x.get(FieldEventAction))
+}
+
+func (f *genericForge) verify(req *http.Request, body []byte) bool {
+	switch f.auth {
+	case AuthHMAC:
+		sig := strings.TrimPrefix(req.Header.Get(f.signatureHeader), "sha256=")
+		got, err := hex.DecodeString(sig)
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha256.New, f.secret)
+		_, _ = mac.Write(body)
+		return hmac.Equal(got, mac.Sum(nil))
+
+	case AuthBearer:
+		token, ok := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+		if !ok {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(token), f.secret) == 1
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func (r *Rule) matches(doc interface{}) bool {
+	for path, expected := range r.Match {
+		if _, ok := lookupPath(doc, path); !ok {
+			return false
+		}
+		if lookupPathString(doc, path) != expected {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,289 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package generic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+const testSecret = "s3cr3t"
+
+const testPayload = `{
+	"delivery": "d-1",
+	"build": {"phase": "FINALIZED", "status": "FAILURE", "commit": "abc123", "url": "https://ci/1"},
+	"repo": {"owner": "infra", "name": "site"}
+}`
+
+var testRules = []Rule{
+	{
+		Match: map[string]string{"build.phase": "FINALIZED"},
+		Event: EventCIFinished,
+		Fields: map[string]string{
+			FieldCIState:   "build.status",
+			FieldCISHA:     "build.commit",
+			FieldCIURL:     "build.url",
+			FieldRepoOwner: "repo.owner",
+			FieldRepoName:  "repo.name",
+		},
+	},
+}
+
+func newTestHook(t *testing.T, opts Options) forge.IForgeHook {
+	t.Helper()
+
+	opts.Name = "jenkins"
+	opts.Secret = testSecret
+	if opts.Rules == nil {
+		opts.Rules = testRules
+	}
+
+	f, err := New(opts)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return f
+}
+
+func newTestRequest(body string, header map[string]string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/jenkins", strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func testSignature(body string) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	_, _ = mac.Write([]byte(body))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestNew(t *testing.T) {
+	testcases := []struct {
+		name string
+		opts Options
+		want error
+	}{
+		{"no secret", Options{Name: "x"}, ErrMissingSecret},
+		{"unknown auth", Options{Name: "x", Secret: testSecret, Auth: "basic"}, ErrUnknownAuthMethod},
+		{"invalid rule", Options{Name: "x", Secret: testSecret, Rules: []Rule{{}}}, ErrMissingEventIdentifier},
+	}
+
+	for _, tc := range testcases {
+		_, err := New(tc.opts)
+		if !errors.Is(err, tc.want) {
+			t.Errorf("%s: want %v, got %v", tc.name, tc.want, err)
+		}
+	}
+}
+
+func TestHookRequestAuth(t *testing.T) {
+	sig := testSignature(testPayload)
+
+	testcases := []struct {
+		name   string
+		auth   string
+		header map[string]string
+		ok     bool
+	}{
+		{"hmac", AuthHMAC, map[string]string{"X-Hub-Signature-256": sig}, true},
+		{"hmac with prefix", AuthHMAC, map[string]string{"X-Hub-Signature-256": "sha256=" + sig}, true},
+		{"hmac wrong", AuthHMAC, map[string]string{"X-Hub-Signature-256": testSignature("{}")}, false},
+		{"hmac not hex", AuthHMAC, map[string]string{"X-Hub-Signature-256": "sha256=zz"}, false},
+		{"hmac missing", AuthHMAC, nil, false},
+		{"hmac as bearer", AuthHMAC, map[string]string{"Authorization": "Bearer " + testSecret}, false},
+		{"bearer", AuthBearer, map[string]string{"Authorization": "Bearer " + testSecret}, true},
+		{"bearer wrong", AuthBearer, map[string]string{"Authorization": "Bearer nope"}, false},
+		{"bearer no scheme", AuthBearer, map[string]string{"Authorization": testSecret}, false},
+		{"bearer missing", AuthBearer, nil, false},
+	}
+
+	for _, tc := range testcases {
+		f := newTestHook(t, Options{Auth: tc.auth})
+		e, err := f.HookRequest(newTestRequest(testPayload, tc.header))
+		if tc.ok {
+			if err != nil || e == nil {
+				t.Errorf("%s: want an event, got %v, %v", tc.name, e, err)
+			}
+			continue
+		}
+		if !errors.Is(err, ErrVerificationFailed) {
+			t.Errorf("%s: want %v, got %v", tc.name, ErrVerificationFailed, err)
+		}
+	}
+}
+
+func TestHookRequestCustomSignatureHeader(t *testing.T) {
+	f := newTestHook(t, Options{SignatureHeader: "X-Jenkins-Signature"})
+
+	_, err := f.HookRequest(newTestRequest(testPayload, map[string]string{
+		"X-Hub-Signature-256": testSignature(testPayload),
+	}))
+	if !errors.Is(err, ErrVerificationFailed) {
+		t.Errorf("default header: want %v, got %v", ErrVerificationFailed, err)
+	}
+
+	e, err := f.HookRequest(newTestRequest(testPayload, map[string]string{
+		"X-Jenkins-Signature": testSignature(testPayload),
+	}))
+	if err != nil || e == nil {
+		t.Errorf("custom header: want an event, got %v, %v", e, err)
+	}
+}
+
+func TestHookRequestInvalid(t *testing.T) {
+	f := newTestHook(t, Options{Auth: AuthBearer})
+	header := map[string]string{"Authorization": "Bearer " + testSecret}
+
+	req := newTestRequest(testPayload, header)
+	req.Method = http.MethodGet
+	_, err := f.HookRequest(req)
+	if !errors.Is(err, ErrInvalidHTTPMethod) {
+		t.Errorf("GET: want %v, got %v", ErrInvalidHTTPMethod, err)
+	}
+
+	_, err = f.HookRequest(newTestRequest(`[1, 2]`, header))
+	if !errors.Is(err, ErrInvalidPayload) {
+		t.Errorf("array: want %v, got %v", ErrInvalidPayload, err)
+	}
+
+	_, err = f.HookRequest(newTestRequest(`{`, header))
+	if err == nil {
+		t.Errorf("malformed JSON: want an error")
+	}
+}
+
+func TestHookRequestCIFinished(t *testing.T) {
+	f := newTestHook(t, Options{Auth: AuthBearer})
+	header := map[string]string{"Authorization": "Bearer " + testSecret}
+
+	e, err := f.HookRequest(newTestRequest(testPayload, header))
+	if err != nil {
+		t.Fatalf("HookRequest: %v", err)
+	}
+	params, ok := e.CIFinished()
+	if !ok {
+		t.Fatalf("want CIFinished, got %v", e.Type())
+	}
+
+	run := params.Run
+	if run.State != v1alpha1.CIStateFailed {
+		t.Errorf("state: got %d, want %d", run.State, v1alpha1.CIStateFailed)
+	}
+	if run.SHA != "abc123" || run.URL != "https://ci/1" {
+		t.Errorf("SHA and URL: got %q and %q", run.SHA, run.URL)
+	}
+	want := v1alpha1.Repo{
+		User:     v1alpha1.ForgeUser{Forge: "jenkins", UserName: "infra"},
+		RepoName: "site",
+	}
+	if !reflect.DeepEqual(run.Repo, want) {
+		t.Errorf("repo: got %+v, want %+v", run.Repo, want)
+	}
+	if len(run.PRs) != 0 {
+		t.Errorf("PRs: want none, got %+v", run.PRs)
+	}
+	if string(e.RawPayload()) != testPayload {
+		t.Errorf("raw payload not kept")
+	}
+}
+
+func TestHookRequestNoEvent(t *testing.T) {
+	f := newTestHook(t, Options{Auth: AuthBearer})
+	header := map[string]string{"Authorization": "Bearer " + testSecret}
+
+	testcases := []struct {
+		name string
+		body string
+	}{
+		{"no rule matched", `{"build": {"phase": "STARTED", "status": "FAILURE"}}`},
+		{"CI not finished", `{"build": {"phase": "FINALIZED", "status": "RUNNING"}}`},
+	}
+
+	for _, tc := range testcases {
+		e, err := f.HookRequest(newTestRequest(tc.body, header))
+		if err != nil || e != nil {
+			t.Errorf("%s: want no event, got %v, %v", tc.name, e, err)
+		}
+	}
+}
+
+func TestHookRequestMetadata(t *testing.T) {
+	header := map[string]string{
+		"Authorization":   "Bearer " + testSecret,
+		"X-Delivery":      "from-header",
+		"X-Jenkins-Event": "build",
+	}
+
+	fieldRules := []Rule{
+		{
+			Event: EventCIFinished,
+			Fields: map[string]string{
+				FieldCIState:     "build.status",
+				FieldDeliveryID:  "delivery",
+				FieldEventName:   "build.phase",
+				FieldEventAction: "build.status",
+			},
+		},
+	}
+
+	testcases := []struct {
+		name         string
+		opts         Options
+		wantDelivery string
+		wantEvent    string
+		wantAction   string
+	}{
+		{
+			name: "none",
+			opts: Options{Auth: AuthBearer},
+		},
+		{
+			name:         "headers",
+			opts:         Options{Auth: AuthBearer, DeliveryIDHeader: "X-Delivery", EventHeader: "X-Jenkins-Event"},
+			wantDelivery: "from-header",
+			wantEvent:    "build",
+		},
+		{
+			name: "fields",
+			opts: Options{
+				Auth:             AuthBearer,
+				DeliveryIDHeader: "X-Delivery",
+				EventHeader:      "X-Jenkins-Event",
+				Rules:            fieldRules,
+			},
+			wantDelivery: "d-1",
+			wantEvent:    "FINALIZED",
+			wantAction:   "FAILURE",
+		},
+	}
+
+	for _, tc := range testcases {
+		f := newTestHook(t, tc.opts)
+		e, err := f.HookRequest(newTestRequest(testPayload, header))
+		if err != nil {
+			t.Errorf("%s: HookRequest: %v", tc.name, err)
+			continue
+		}
+		if got := e.DeliveryID(); got != tc.wantDelivery {
+			t.Errorf("%s: delivery ID: got %q, want %q", tc.name, got, tc.wantDelivery)
+		}
+		if got := e.ForgeEvent(); got != tc.wantEvent {
+			t.Errorf("%s: event: got %q, want %q", tc.name, got, tc.wantEvent)
+		}
+		if got := e.ForgeAction(); got != tc.wantAction {
+			t.Errorf("%s: action: got %q, want %q", tc.name, got, tc.wantAction)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package generic
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// lookupPath evaluates a dot-separated path against a decoded JSON document,
+// returning the value found and whether the path exists.
+//
+// Each path component is either an object key or, for arrays, a zero-based
+// index. An empty path refers to the whole document.
+func lookupPath(doc interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return doc, true
+	}
+
+	cur := doc
+	for _, component := range strings.Split(path, ".") {
+		switch x := cur.(type) {
+		case map[string]interface{}:
+			v, ok := x[component]
+			if !ok {
+				return nil, false
+			}
+			cur = v
+
+		case []interface{}:
+			idx, err := strconv.Atoi(component)
+			if err != nil || idx < 0 || idx >= len(x) {
+				return nil, false
+			}
+			cur = x[idx]
+
+		default:
+			// scalars have no children
+			return nil, false
+		}
+	}
+
+	return cur, true
+}
+
+// lookupPathString is like lookupPath but converts the value found into a
+// string. Missing values and nulls become the empty string, objects and
+// arrays are re-encoded as JSON.
+func lookupPathString(doc interface{}, path string) string {
+	v, ok := lookupPath(doc, path)
+	if !ok {
+		return ""
+	}
+
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	default:
+		buf, err := json.Marshal(x)
+		if err != nil {
+			return ""
+		}
+		return string(buf)
+	}
+}
@@ -0,0 +1,76 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package generic
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func decodeTestDoc(t *testing.T, s string) interface{} {
+	t.Helper()
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+	var doc interface{}
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestLookupPathString(t *testing.T) {
+	doc := decodeTestDoc(t, `{
+		"name": "widget",
+		"build": {
+			"number": 42,
+			"ratio": 0.5,
+			"ok": true,
+			"cause": null,
+			"scm": {"commit": "5f1c0e7b"},
+			"artifacts": [{"path": "a.tar"}, {"path": "b.tar"}],
+			"tags": ["x", "y"]
+		},
+		"a.b": "dotted"
+	}`)
+
+	testcases := []struct {
+		path string
+		want string
+		ok   bool
+	}{
+		{"name", "widget", true},
+		{"build.number", "42", true},
+		{"build.ratio", "0.5", true},
+		{"build.ok", "true", true},
+		{"build.cause", "", true},
+		{"build.scm.commit", "5f1c0e7b", true},
+		{"build.artifacts.1.path", "b.tar", true},
+		{"build.tags", `["x","y"]`, true},
+		{"build.scm", `{"commit":"5f1c0e7b"}`, true},
+
+		{"missing", "", false},
+		{"build.missing", "", false},
+		{"name.length", "", false},
+		{"build.artifacts.2.path", "", false},
+		{"build.artifacts.-1.path", "", false},
+		{"build.artifacts.first", "", false},
+		// keys containing dots cannot be referred to
+		{"a.b", "", false},
+	}
+
+	for _, tc := range testcases {
+		_, ok := lookupPath(doc, tc.path)
+		if ok != tc.ok {
+			t.Errorf("lookupPath(%q): got ok %v, want %v", tc.path, ok, tc.ok)
+		}
+		if got := lookupPathString(doc, tc.path); got != tc.want {
+			t.Errorf("lookupPathString(%q): got %q, want %q", tc.path, got, tc.want)
+		}
+	}
+
+	if v, ok := lookupPath(doc, ""); !ok || v == nil {
+		t.Errorf("lookupPath of the empty path: want the whole document, got %v, %v", v, ok)
+	}
+}
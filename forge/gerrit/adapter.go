@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gerrit
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// Adapters for whole event param structs
+
+func intoPROpenedParams(x *eventPayload) v1alpha1.PROpenedParams {
+	return v1alpha1.PROpenedParams{
+		Actor:   botModelFromAccount(&x.Uploader),
+		PR:      botModelFromChange(x),
+		IsDraft: x.Change.WIP,
+	}
+}
+
+func intoPRUpdatedParams(x *eventPayload) v1alpha1.PRUpdatedParams {
+	return v1alpha1.PRUpdatedParams{
+		Actor: botModelFromAccount(&x.Uploader),
+		PR:    botModelFromChange(x),
+	}
+}
+
+func intoPRMergedParams(x *eventPayload) v1alpha1.PRMergedParams {
+	return v1alpha1.PRMergedParams{
+		Actor: botModelFromAccount(&x.Submitter),
+		PR:    botModelFromChange(x),
+	}
+}
+
+func intoPRClosedParams(x *eventPayload) v1alpha1.PRClosedParams {
+	return v1alpha1.PRClosedParams{
+		Actor: botModelFromAccount(&x.Abandoner),
+		PR:    botModelFromChange(x),
+	}
+}
+
+func intoPRReviewedParams(x *eventPayload, review v1alpha1.ReviewType) v1alpha1.PRReviewedParams {
+	return v1alpha1.PRReviewedParams{
+		Actor:  botModelFromAccount(&x.Author),
+		PR:     botModelFromChange(x),
+		Review: review,
+	}
+}
+
+func intoCIFinishedParams(x *eventPayload, state v1alpha1.CIState) v1alpha1.CIFinishedParams {
+	pr := botModelFromChange(x)
+	return v1alpha1.CIFinishedParams{
+		Run: v1alpha1.CIRun{
+			Repo:  pr.Repo,
+			PR:    pr,
+			PRs:   []v1alpha1.PR{pr},
+			State: state,
+			SHA:   x.PatchSet.Revision,
+			// CI systems usually link to the results in the comment, but
+			// there is no telling where exactly.
+			URL: pr.URL,
+		},
+	}
+}
+
+func intoCommentCreatedParams(x *eventPayload) v1alpha1.CommentCreatedParams {
+	return v1alpha1.CommentCreatedParams{
+		Actor:  botModelFromAccount(&x.Author),
+		Target: v1alpha1.CommentTargetTypePR,
+		PR:     botModelFromChange(x),
+		Comment: v1alpha1.Comment{
+			Author:    botModelFromAccount(&x.Author),
+			Body:      x.Comment,
+			URL:       x.Change.URL,
+			CreatedAt: timeFromTimestamp(x.EventCreatedOn),
+		},
+	}
+}
+
+// Adapters for component fields
+
+// botModelFromChange converts the change in the event into a PR. Gerrit
+// changes are the closest thing to PRs, with the current patch set being the
+// PR's head.
+func botModelFromChange(x *eventPayload) v1alpha1.PR {
+	return v1alpha1.PR{
+		Repo:   botModelFromProject(x.Change.Project),
+		Number: x.Change.Number,
+		Title:  x.Change.Subject,
+		Author: botModelFromAccount(&x.Change.Owner),
+		State:  issueStateFromChangeStatus(x.Change.Status),
+		URL:    x.Change.URL,
+		Body:   x.Change.CommitMessage,
+
+		BaseBranch: x.Change.Branch,
+		// changes do not have source branches, but patch sets have refs
+		HeadBranch: x.PatchSet.Ref,
+		HeadSHA:    x.PatchSet.Revision,
+		IsDraft:    x.Change.WIP,
+
+		CreatedAt: timeFromTimestamp(x.Change.CreatedOn),
+		UpdatedAt: timeFromTimestamp(x.Change.LastUpdated),
+
+		Additions: x.PatchSet.SizeInsertions,
+		// sizeDeletions is negative
+		Deletions: -x.PatchSet.SizeDeletions,
+	}
+}
+
+func botModelFromAccount(x *account) v1alpha1.ForgeUser {
+	return v1alpha1.ForgeUser{
+		Forge:    forgeType,
+		UserName: x.Username,
+	}
+}
+
+func botModelFromProject(project string) v1alpha1.Repo {
+	// Gerrit projects can be nested arbitrarily deep, treat the parent path
+	// as the owner like for GitLab.
+	parent, name := "", project
+	if idx := strings.LastIndexByte(project, '/'); idx >= 0 {
+		parent = project[:idx]
+		name = project[idx+1:]
+	}
+
+	return v1alpha1.Repo{
+		User: v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: parent,
+		},
+		RepoName: name,
+	}
+}
+
+func timeFromTimestamp(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
+
+func issueStateFromChangeStatus(status string) v1alpha1.IssueState {
+	switch status {
+	case "NEW":
+		return v1alpha1.IssueStateOpen
+	case "MERGED":
+		return v1alpha1.IssueStateMerged
+	case "ABANDONED":
+		return v1alpha1.IssueStateClosed
+	default:
+		return v1alpha1.IssueStateUnknown
+	}
+}
+
+// eventTypeFromPayload returns the type of the raw event payload.
+func eventTypeFromPayload(payload []byte) string {
+	var x struct {
+		Type string `json:"type"`
+	}
+	// best-effort
+	_ = json.Unmarshal(payload, &x)
+	return x.Type
+}
+
+// changedVote returns the vote on the given label changed by the event, or
+// nil if there is none.
+func changedVote(approvals []approval, label string) *approval {
+	for i := range approvals {
+		a := &approvals[i]
+		if a.Type == label && a.OldValue != nil && *a.OldValue != a.Value {
+			return a
+		}
+	}
+	return nil
+}
+
+func voteValue(x *approval) int {
+	// best-effort, values look like "-1", "0", "+2" or "2"
+	v, _ := strconv.Atoi(x.Value)
+	return v
+}
+
+func reviewTypeFromVote(x *approval) v1alpha1.ReviewType {
+	switch v := voteValue(x); {
+	case v > 0:
+		return v1alpha1.ReviewTypeApprove
+	case v < 0:
+		return v1alpha1.ReviewTypeRequestChanges
+	default:
+		// the vote is withdrawn
+		return v1alpha1.ReviewTypeDismiss
+	}
+}
+
+func ciStateFromVote(x *approval) v1alpha1.CIState {
+	switch v := voteValue(x); {
+	case v > 0:
+		return v1alpha1.CIStatePassed
+	case v < 0:
+		return v1alpha1.CIStateFailed
+	default:
+		return v1alpha1.CIStateUnknown
+	}
+}
@@ -0,0 +1,128 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package gerrit implements the forge hook for Gerrit, consuming the POSTs of
+// Gerrit's webhooks plugin.
+package gerrit
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+const forgeType = "gerrit"
+
+// Label names of the votes we are interested in.
+const (
+	labelCodeReview = "Code-Review"
+	labelVerified   = "Verified"
+)
+
+var (
+	ErrInvalidHTTPMethod  = errors.New("gerrit: invalid HTTP method")
+	ErrVerificationFailed = errors.New("gerrit: token verification failed")
+	ErrSecretRequired     = errors.New("gerrit: secret is required")
+)
+
+type gerritForge struct {
+	secret []byte
+}
+
+var _ forge.IForgeHook = (*gerritForge)(nil)
+
+// New returns a new Gerrit forge hook instance.
+//
+// The webhooks plugin has no way of signing requests, so the secret has to be
+// included in the webhook URL as the "token" query parameter.
+func New(secret string) (forge.IForgeHook, error) {
+	if secret == "" {
+		return nil, ErrSecretRequired
+	}
+
+	return &gerritForge{
+		secret: []byte(secret),
+	}, nil
+}
+
+// HookRequest hooks an incoming webhook request to trigger actions.
+func (f *gerritForge) HookRequest(req *http.Request) (*v1alpha1.Event, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := f.hookRequest(req, body)
+	if e != nil {
+		// Gerrit events have no separate actions.
+		e.SetForgeEvent(eventTypeFromPayload(body), "")
+		e.SetRawPayload(body)
+	}
+	return e, err
+}
+
+func (f *gerritForge) hookRequest(req *http.Request, body []byte) (*v1alpha1.Event, error) {
+	if req.Method != http.MethodPost {
+		return nil, ErrInvalidHTTPMethod
+	}
+
+	token := []byte(req.URL.Query().Get("token"))
+	if subtle.ConstantTimeCompare(token, f.secret) != 1 {
+		return nil, ErrVerificationFailed
+	}
+
+	var p eventPayload
+	err := json.Unmarshal(body, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.Type {
+	case "patchset-created":
+		if p.PatchSet.Number == 1 {
+			params := intoPROpenedParams(&p)
+			return params.IntoEvent(), nil
+		}
+
+		params := intoPRUpdatedParams(&p)
+		return params.IntoEvent(), nil
+
+	case "change-merged":
+		params := intoPRMergedParams(&p)
+		return params.IntoEvent(), nil
+
+	case "change-abandoned":
+		params := intoPRClosedParams(&p)
+		return params.IntoEvent(), nil
+
+	case "comment-added":
+		// Votes are more interesting than the accompanying comments, and
+		// code review votes more than CI ones, as a CI system rarely leaves
+		// code review votes but humans may well leave verification votes.
+		if a := changedVote(p.Approvals, labelCodeReview); a != nil {
+			params := intoPRReviewedParams(&p, reviewTypeFromVote(a))
+			return params.IntoEvent(), nil
+		}
+
+		if a := changedVote(p.Approvals, labelVerified); a != nil {
+			state := ciStateFromVote(a)
+			if state == v1alpha1.CIStateUnknown {
+				// The vote is reset, e.g. for a new CI run
+				return nil, nil
+			}
+
+			params := intoCIFinishedParams(&p, state)
+			return params.IntoEvent(), nil
+		}
+
+		params := intoCommentCreatedParams(&p)
+		return params.IntoEvent(), nil
+	}
+
+	// Currently not handled
+	return nil, nil
+}
@@ -0,0 +1,335 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gerrit
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge/internal/hooktest"
+)
+
+const testSecret = "Sup3rS3cr3tStr1ng"
+
+func newTestRequest(token string, body []byte) *http.Request {
+	target := "/gerrit?token=" + url.QueryEscape(token)
+	return httptest.NewRequest(http.MethodPost, target, bytes.NewReader(body))
+}
+
+func newTestHook(t *testing.T) *gerritForge {
+	t.Helper()
+
+	fh, err := New(testSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fh.(*gerritForge)
+}
+
+// hookPayload feeds the payload to a new forge hook instance.
+func hookPayload(t *testing.T, body []byte) *v1alpha1.Event {
+	t.Helper()
+
+	e, err := newTestHook(t).HookRequest(newTestRequest(testSecret, body))
+	if err != nil {
+		t.Fatalf("HookRequest: %v", err)
+	}
+	return e
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("")
+	if !errors.Is(err, ErrSecretRequired) {
+		t.Errorf("want %v, got %v", ErrSecretRequired, err)
+	}
+}
+
+func TestHookRequestRejected(t *testing.T) {
+	body := hooktest.Fixture(t, "patchset-created-1.json")
+
+	testcases := []struct {
+		name string
+		req  *http.Request
+		want error
+	}{
+		{
+			name: "GET",
+			req:  httptest.NewRequest(http.MethodGet, "/gerrit?token="+testSecret, nil),
+			want: ErrInvalidHTTPMethod,
+		},
+		{
+			name: "no token",
+			req:  httptest.NewRequest(http.MethodPost, "/gerrit", bytes.NewReader(body)),
+			want: ErrVerificationFailed,
+		},
+		{
+			name: "empty token",
+			req:  newTestRequest("", body),
+			want: ErrVerificationFailed,
+		},
+		{
+			name: "wrong token",
+			req:  newTestRequest(testSecret+"x", body),
+			want: ErrVerificationFailed,
+		},
+	}
+
+	f := newTestHook(t)
+	for _, tc := range testcases {
+		e, err := f.HookRequest(tc.req)
+		if e != nil || !errors.Is(err, tc.want) {
+			t.Errorf("%s: want %v, got %v, %v", tc.name, tc.want, e, err)
+		}
+	}
+}
+
+func testUser(name string) v1alpha1.ForgeUser {
+	return v1alpha1.ForgeUser{Forge: forgeType, UserName: name}
+}
+
+// testChange returns the change of the fixtures as a PR, at the given patch
+// set.
+func testChange(state v1alpha1.IssueState, patchSet int) v1alpha1.PR {
+	result := v1alpha1.PR{
+		Repo: v1alpha1.Repo{
+			User:     testUser("tools"),
+			RepoName: "widget",
+		},
+		Number: 4217,
+		Title:  "Retry uploads on transient errors",
+		Author: testUser("alice"),
+		State:  state,
+		URL:    "https://review.example.com/c/tools/widget/+/4217",
+		Body:   "Retry uploads on transient errors\n\nChange-Id: I8473b95934b5732ac55d26311a706c9c2bde9940\n",
+
+		BaseBranch: "main",
+		HeadBranch: "refs/changes/17/4217/2",
+		HeadSHA:    "5f1c0e7b6d3a8e4f2b9c1d0a7e6f5b4c3d2e1f0a",
+
+		CreatedAt: time.Unix(1694506503, 0),
+		UpdatedAt: time.Unix(1694509361, 0),
+
+		Additions: 42,
+		Deletions: 7,
+	}
+	if patchSet == 1 {
+		result.HeadBranch = "refs/changes/17/4217/1"
+		result.HeadSHA = "4e0b9d6a5c2f7d3e1a8b0c9f6e5d4a3b2c1d0e9f"
+		result.UpdatedAt = result.CreatedAt
+	}
+	return result
+}
+
+func TestHookRequest(t *testing.T) {
+	draft := testChange(v1alpha1.IssueStateOpen, 1)
+	draft.IsDraft = true
+
+	testcases := []struct {
+		fixture string
+		want    hooktest.Params
+	}{
+		{
+			fixture: "patchset-created-1.json",
+			want: &v1alpha1.PROpenedParams{
+				Actor:   testUser("alice"),
+				PR:      draft,
+				IsDraft: true,
+			},
+		},
+		{
+			fixture: "patchset-created-2.json",
+			want: &v1alpha1.PRUpdatedParams{
+				Actor: testUser("alice"),
+				PR:    testChange(v1alpha1.IssueStateOpen, 2),
+			},
+		},
+		{
+			fixture: "change-merged.json",
+			want: &v1alpha1.PRMergedParams{
+				Actor: testUser("bob"),
+				PR:    testChange(v1alpha1.IssueStateMerged, 2),
+			},
+		},
+		{
+			fixture: "change-abandoned.json",
+			want: &v1alpha1.PRClosedParams{
+				Actor: testUser("bob"),
+				PR:    testChange(v1alpha1.IssueStateClosed, 2),
+			},
+		},
+		{
+			// no vote is changed
+			fixture: "comment-added.json",
+			want: &v1alpha1.CommentCreatedParams{
+				Actor:  testUser("carol"),
+				Target: v1alpha1.CommentTargetTypePR,
+				PR:     testChange(v1alpha1.IssueStateOpen, 2),
+				Comment: v1alpha1.Comment{
+					Author:    testUser("carol"),
+					Body:      "Patch Set 2:\n\nLooks good, one nit inline.",
+					URL:       "https://review.example.com/c/tools/widget/+/4217",
+					CreatedAt: time.Unix(1694509361, 0),
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.fixture, func(t *testing.T) {
+			e := hookPayload(t, hooktest.Fixture(t, tc.fixture))
+			hooktest.AssertEvent(t, e, tc.want)
+		})
+	}
+}
+
+// withApprovals returns the comment-added fixture with its approvals replaced.
+func withApprovals(t *testing.T, approvals []approval) []byte {
+	t.Helper()
+
+	var x map[string]any
+	if err := json.Unmarshal(hooktest.Fixture(t, "comment-added.json"), &x); err != nil {
+		t.Fatal(err)
+	}
+	x["approvals"] = approvals
+
+	body, err := json.Marshal(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func vote(label string, oldValue string, value string) approval {
+	return approval{Type: label, Description: label, Value: value, OldValue: &oldValue}
+}
+
+func unchangedVote(label string, value string) approval {
+	return approval{Type: label, Description: label, Value: value}
+}
+
+func TestHookRequestVotes(t *testing.T) {
+	reviewed := func(review v1alpha1.ReviewType) hooktest.Params {
+		return &v1alpha1.PRReviewedParams{
+			Actor:  testUser("carol"),
+			PR:     testChange(v1alpha1.IssueStateOpen, 2),
+			Review: review,
+		}
+	}
+	ciFinished := func(state v1alpha1.CIState) hooktest.Params {
+		pr := testChange(v1alpha1.IssueStateOpen, 2)
+		return &v1alpha1.CIFinishedParams{
+			Run: v1alpha1.CIRun{
+				Repo:  pr.Repo,
+				PR:    pr,
+				PRs:   []v1alpha1.PR{pr},
+				State: state,
+				SHA:   "5f1c0e7b6d3a8e4f2b9c1d0a7e6f5b4c3d2e1f0a",
+				URL:   pr.URL,
+			},
+		}
+	}
+
+	testcases := []struct {
+		name      string
+		approvals []approval
+		want      hooktest.Params
+	}{
+		{
+			name:      "approved",
+			approvals: []approval{vote(labelCodeReview, "0", "+2")},
+			want:      reviewed(v1alpha1.ReviewTypeApprove),
+		},
+		{
+			name:      "approved without sign",
+			approvals: []approval{vote(labelCodeReview, "0", "1")},
+			want:      reviewed(v1alpha1.ReviewTypeApprove),
+		},
+		{
+			name:      "changes requested",
+			approvals: []approval{vote(labelCodeReview, "+1", "-1")},
+			want:      reviewed(v1alpha1.ReviewTypeRequestChanges),
+		},
+		{
+			name:      "vote withdrawn",
+			approvals: []approval{vote(labelCodeReview, "-2", "0")},
+			want:      reviewed(v1alpha1.ReviewTypeDismiss),
+		},
+		{
+			name: "code review over verification",
+			approvals: []approval{
+				vote(labelVerified, "0", "-1"),
+				vote(labelCodeReview, "0", "+1"),
+			},
+			want: reviewed(v1alpha1.ReviewTypeApprove),
+		},
+		{
+			name: "verified",
+			approvals: []approval{
+				unchangedVote(labelCodeReview, "+1"),
+				vote(labelVerified, "0", "+1"),
+			},
+			want: ciFinished(v1alpha1.CIStatePassed),
+		},
+		{
+			name:      "verification failed",
+			approvals: []approval{vote(labelVerified, "+1", "-1")},
+			want:      ciFinished(v1alpha1.CIStateFailed),
+		},
+		{
+			// e.g. for a new CI run
+			name:      "verification reset",
+			approvals: []approval{vote(labelVerified, "-1", "0")},
+			want:      nil,
+		},
+		{
+			// an old value equal to the new one is no change
+			name:      "same vote",
+			approvals: []approval{vote(labelCodeReview, "+1", "+1")},
+			want: &v1alpha1.CommentCreatedParams{
+				Actor:  testUser("carol"),
+				Target: v1alpha1.CommentTargetTypePR,
+				PR:     testChange(v1alpha1.IssueStateOpen, 2),
+				Comment: v1alpha1.Comment{
+					Author:    testUser("carol"),
+					Body:      "Patch Set 2:\n\nLooks good, one nit inline.",
+					URL:       "https://review.example.com/c/tools/widget/+/4217",
+					CreatedAt: time.Unix(1694509361, 0),
+				},
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			e := hookPayload(t, withApprovals(t, tc.approvals))
+			hooktest.AssertEvent(t, e, tc.want)
+		})
+	}
+}
+
+func TestHookRequestUnhandled(t *testing.T) {
+	e := hookPayload(t, []byte(`{"type":"ref-updated","eventCreatedOn":1694509361}`))
+	if e != nil {
+		t.Errorf("want no event, got %v", e)
+	}
+}
+
+func TestHookRequestMetadata(t *testing.T) {
+	e := hookPayload(t, hooktest.Fixture(t, "change-merged.json"))
+
+	if e.ForgeEvent() != "change-merged" || e.ForgeAction() != "" {
+		t.Errorf("event: got %q, %q", e.ForgeEvent(), e.ForgeAction())
+	}
+	if e.DeliveryID() != "" {
+		t.Errorf("delivery ID: got %q", e.DeliveryID())
+	}
+}
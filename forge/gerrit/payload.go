@@ -0,0 +1,71 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gerrit
+
+// Payload types of Gerrit events, in the same JSON format as Gerrit's
+// stream-events. Only the fields we use are included.
+//
+// See https://gerrit-review.googlesource.com/Documentation/cmd-stream-events.html
+
+type account struct {
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	Username string `json:"username"`
+}
+
+type change struct {
+	Project       string  `json:"project"`
+	Branch        string  `json:"branch"`
+	Topic         string  `json:"topic"`
+	ID            string  `json:"id"`
+	Number        int     `json:"number"`
+	Subject       string  `json:"subject"`
+	Owner         account `json:"owner"`
+	URL           string  `json:"url"`
+	CommitMessage string  `json:"commitMessage"`
+	CreatedOn     int64   `json:"createdOn"`
+	LastUpdated   int64   `json:"lastUpdated"`
+	Status        string  `json:"status"`
+	WIP           bool    `json:"wip"`
+}
+
+type patchSet struct {
+	Number         int     `json:"number"`
+	Revision       string  `json:"revision"`
+	Ref            string  `json:"ref"`
+	Uploader       account `json:"uploader"`
+	Author         account `json:"author"`
+	CreatedOn      int64   `json:"createdOn"`
+	Kind           string  `json:"kind"`
+	SizeInsertions int     `json:"sizeInsertions"`
+	SizeDeletions  int     `json:"sizeDeletions"`
+}
+
+type approval struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Value       string `json:"value"`
+	// OldValue is only present if the vote is changed by the event.
+	OldValue *string `json:"oldValue"`
+}
+
+// eventPayload is the union of all event types we handle.
+type eventPayload struct {
+	Type           string   `json:"type"`
+	Change         change   `json:"change"`
+	PatchSet       patchSet `json:"patchSet"`
+	EventCreatedOn int64    `json:"eventCreatedOn"`
+
+	// patchset-created
+	Uploader account `json:"uploader"`
+	// change-merged
+	Submitter account `json:"submitter"`
+	NewRev    string  `json:"newRev"`
+	// change-abandoned
+	Abandoner account `json:"abandoner"`
+	Reason    string  `json:"reason"`
+	// comment-added
+	Author    account    `json:"author"`
+	Approvals []approval `json:"approvals"`
+	Comment   string     `json:"comment"`
+}
@@ -0,0 +1,500 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitea
+
+import (
+	"strings"
+
+	"github.com/go-playground/webhooks/v6/gitea"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// Adapters for whole event param structs
+
+func intoPROpenedParams(x *pullRequestPayload) v1alpha1.PROpenedParams {
+	pr := botModelFromPRContainingPayload(x)
+	return v1alpha1.PROpenedParams{
+		Actor:   botModelFromSenderContainingPayload(x),
+		PR:      pr,
+		IsDraft: pr.IsDraft,
+	}
+}
+
+func intoPRClosedParams(x *pullRequestPayload) v1alpha1.PRClosedParams {
+	return v1alpha1.PRClosedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		PR:    botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoPRMergedParams(x *pullRequestPayload) v1alpha1.PRMergedParams {
+	return v1alpha1.PRMergedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		PR:    botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoPRRenamedParams(x *pullRequestPayload, oldTitle string) v1alpha1.PRRenamedParams {
+	return v1alpha1.PRRenamedParams{
+		Actor:    botModelFromSenderContainingPayload(x),
+		PR:       botModelFromPRContainingPayload(x),
+		OldTitle: oldTitle,
+	}
+}
+
+func intoPRReadyParams(x *pullRequestPayload) v1alpha1.PRReadyParams {
+	return v1alpha1.PRReadyParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		PR:    botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoPRWithdrawnParams(x *pullRequestPayload) v1alpha1.PRWithdrawnParams {
+	return v1alpha1.PRWithdrawnParams{
+		PR: botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoPRReviewRequestedParams(x *pullRequestPayload) v1alpha1.PRReviewRequestedParams {
+	return v1alpha1.PRReviewRequestedParams{
+		Actor:     botModelFromSenderContainingPayload(x),
+		PR:        botModelFromPRContainingPayload(x),
+		Reviewers: botModelFromUsers([]*gitea.User{x.RequestedReviewer}),
+	}
+}
+
+func intoPRReviewRequestRemovedParams(x *pullRequestPayload) v1alpha1.PRReviewRequestRemovedParams {
+	return v1alpha1.PRReviewRequestRemovedParams{
+		Actor:     botModelFromSenderContainingPayload(x),
+		PR:        botModelFromPRContainingPayload(x),
+		Reviewers: botModelFromUsers([]*gitea.User{x.RequestedReviewer}),
+	}
+}
+
+func intoPRReviewedParams(x *pullRequestPayload, review v1alpha1.ReviewType) v1alpha1.PRReviewedParams {
+	return v1alpha1.PRReviewedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		PR:     botModelFromPRContainingPayload(x),
+		Review: review,
+	}
+}
+
+func intoIssueOpenedParams(x *gitea.IssuePayload) v1alpha1.IssueOpenedParams {
+	return v1alpha1.IssueOpenedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueClosedParams(x *gitea.IssuePayload) v1alpha1.IssueClosedParams {
+	return v1alpha1.IssueClosedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueReopenedParams(x *gitea.IssuePayload) v1alpha1.IssueReopenedParams {
+	return v1alpha1.IssueReopenedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+// intoIssueAssignedParams leaves the assignees to ResolveEvent, as Gitea does
+// not tell which assignees are new.
+func intoIssueAssignedParams(x *gitea.IssuePayload) v1alpha1.IssueAssignedParams {
+	return v1alpha1.IssueAssignedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+// intoIssueLabeledParams leaves the labels to ResolveEvent, as Gitea does not
+// tell which labels are new, or even whether any label is added at all.
+func intoIssueLabeledParams(x *gitea.IssuePayload) v1alpha1.IssueLabeledParams {
+	return v1alpha1.IssueLabeledParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueRenamedParams(x *gitea.IssuePayload, oldTitle string) v1alpha1.IssueRenamedParams {
+	return v1alpha1.IssueRenamedParams{
+		Actor:    botModelFromSenderContainingPayload(x),
+		Issue:    botModelFromIssueContainingPayload(x),
+		OldTitle: oldTitle,
+	}
+}
+
+func intoCommentCreatedParams(x *gitea.IssueCommentPayload) v1alpha1.CommentCreatedParams {
+	result := v1alpha1.CommentCreatedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Comment: v1alpha1.Comment{
+			Author:    botModelFromUser(x.Comment.Poster),
+			Body:      x.Comment.Body,
+			URL:       x.Comment.HTMLURL,
+			CreatedAt: x.Comment.Created,
+		},
+	}
+
+	// PRs are also issues on Gitea
+	if x.IsPull {
+		result.Target = v1alpha1.CommentTargetTypePR
+		result.PR = botModelFromPRContainingPayload(x)
+	} else {
+		result.Target = v1alpha1.CommentTargetTypeIssue
+		result.Issue = botModelFromIssueContainingPayload(x)
+	}
+
+	return result
+}
+
+func intoPushParams(x *gitea.PushPayload) v1alpha1.PushParams {
+	commits := make([]v1alpha1.Commit, len(x.Commits))
+	for i, c := range x.Commits {
+		commit := v1alpha1.Commit{
+			SHA:       c.ID,
+			Message:   c.Message,
+			URL:       c.URL,
+			Timestamp: c.Timestamp,
+		}
+		if c.Author != nil {
+			if c.Author.UserName != "" {
+				commit.Author = v1alpha1.ForgeUser{
+					Forge:    forgeType,
+					UserName: c.Author.UserName,
+				}
+			}
+			commit.AuthorName = c.Author.Name
+			commit.AuthorEmail = c.Author.Email
+		}
+		commits[i] = commit
+	}
+
+	return v1alpha1.PushParams{
+		Actor:   botModelFromSenderContainingPayload(x),
+		Repo:    botModelFromRepoContainingPayload(x),
+		Ref:     x.Ref,
+		Before:  x.Before,
+		After:   x.After,
+		Commits: commits,
+		// not reported by Gitea
+		Forced: false,
+	}
+}
+
+func intoBranchCreatedParams(x *gitea.CreatePayload) v1alpha1.BranchCreatedParams {
+	return v1alpha1.BranchCreatedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		Repo:   botModelFromRepoContainingPayload(x),
+		Branch: x.Ref,
+		SHA:    x.Sha,
+	}
+}
+
+func intoBranchDeletedParams(x *gitea.DeletePayload) v1alpha1.BranchDeletedParams {
+	return v1alpha1.BranchDeletedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		Repo:   botModelFromRepoContainingPayload(x),
+		Branch: x.Ref,
+	}
+}
+
+func intoTagCreatedParams(x *gitea.CreatePayload) v1alpha1.TagCreatedParams {
+	return v1alpha1.TagCreatedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Repo:  botModelFromRepoContainingPayload(x),
+		Tag:   x.Ref,
+		SHA:   x.Sha,
+	}
+}
+
+func intoReleasePublishedParams(x *gitea.ReleasePayload) v1alpha1.ReleasePublishedParams {
+	return v1alpha1.ReleasePublishedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Release: v1alpha1.Release{
+			Repo:         botModelFromRepoContainingPayload(x),
+			Tag:          x.Release.TagName,
+			Name:         x.Release.Title,
+			Notes:        x.Release.Note,
+			IsPrerelease: x.Release.IsPrerelease,
+			Author:       botModelFromUser(x.Release.Publisher),
+			URL:          x.Release.HTMLURL,
+			CreatedAt:    x.Release.CreatedAt,
+		},
+	}
+}
+
+func intoCIFinishedParams(x *statusPayload, state v1alpha1.CIState) v1alpha1.CIFinishedParams {
+	return v1alpha1.CIFinishedParams{
+		Run: v1alpha1.CIRun{
+			Repo:  botModelFromRepoContainingPayload(x),
+			State: state,
+			SHA:   x.SHA,
+			URL:   x.TargetURL,
+		},
+	}
+}
+
+// Adapters for component fields
+
+func botModelFromSenderContainingPayload(x interface{}) v1alpha1.ForgeUser {
+	switch x := x.(type) {
+	case *pullRequestPayload:
+		return botModelFromUser(x.Sender)
+
+	case *gitea.IssuePayload:
+		return botModelFromUser(x.Sender)
+
+	case *gitea.IssueCommentPayload:
+		return botModelFromUser(x.Sender)
+
+	case *gitea.PushPayload:
+		return botModelFromUser(x.Sender)
+
+	case *gitea.CreatePayload:
+		return botModelFromUser(x.Sender)
+
+	case *gitea.DeletePayload:
+		return botModelFromUser(x.Sender)
+
+	case *gitea.ReleasePayload:
+		return botModelFromUser(x.Sender)
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromRepoContainingPayload(x interface{}) v1alpha1.Repo {
+	switch x := x.(type) {
+	case *pullRequestPayload:
+		return botModelFromRepo(x.Repository)
+
+	case *gitea.IssuePayload:
+		return botModelFromRepo(x.Repository)
+
+	case *gitea.IssueCommentPayload:
+		return botModelFromRepo(x.Repository)
+
+	case *gitea.PushPayload:
+		return botModelFromRepo(x.Repo)
+
+	case *gitea.CreatePayload:
+		return botModelFromRepo(x.Repo)
+
+	case *gitea.DeletePayload:
+		return botModelFromRepo(x.Repo)
+
+	case *gitea.ReleasePayload:
+		return botModelFromRepo(x.Repository)
+
+	case *statusPayload:
+		return botModelFromRepo(x.Repository)
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromPRContainingPayload(x interface{}) v1alpha1.PR {
+	switch x := x.(type) {
+	case *pullRequestPayload:
+		return botModelFromPullRequest(botModelFromRepoContainingPayload(x), x.PullRequest)
+
+	case *gitea.IssueCommentPayload:
+		// Only the issue side of the PR is available
+		issue := botModelFromIssueContainingPayload(x)
+		return v1alpha1.PR{
+			Repo:      issue.Repo,
+			Number:    issue.Number,
+			Title:     issue.Title,
+			Author:    issue.Author,
+			State:     issueStateFromIssue(x.Issue),
+			URL:       issue.URL,
+			Body:      issue.Body,
+			IsDraft:   isDraftTitle(issue.Title),
+			Labels:    issue.Labels,
+			Assignees: issue.Assignees,
+			CreatedAt: issue.CreatedAt,
+			UpdatedAt: issue.UpdatedAt,
+		}
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromIssueContainingPayload(x interface{}) v1alpha1.Issue {
+	var repo v1alpha1.Repo
+	var issue *gitea.Issue
+	switch x := x.(type) {
+	case *gitea.IssuePayload:
+		repo = botModelFromRepoContainingPayload(x)
+		issue = x.Issue
+
+	case *gitea.IssueCommentPayload:
+		repo = botModelFromRepoContainingPayload(x)
+		issue = x.Issue
+
+	default:
+		panic("should never happen")
+	}
+
+	return v1alpha1.Issue{
+		Repo:      repo,
+		Number:    int(issue.Index),
+		Title:     issue.Title,
+		Author:    botModelFromUser(issue.Poster),
+		State:     issueStateFromIssue(issue),
+		URL:       issue.HTMLURL,
+		Body:      issue.Body,
+		Labels:    botModelFromLabels(issue.Labels),
+		Assignees: botModelFromUsers(issue.Assignees),
+		CreatedAt: issue.Created,
+		UpdatedAt: issue.Updated,
+	}
+}
+
+// botModelFromOpenPRsForCommit returns the PRs whose head is the given commit.
+func botModelFromOpenPRsForCommit(repo v1alpha1.Repo, prs []pullRequest, sha string) []v1alpha1.PR {
+	var result []v1alpha1.PR
+	for i := range prs {
+		if prs[i].Head != nil && prs[i].Head.Sha == sha {
+			result = append(result, botModelFromPullRequest(repo, &prs[i]))
+		}
+	}
+	return result
+}
+
+func botModelFromPullRequest(repo v1alpha1.Repo, x *pullRequest) v1alpha1.PR {
+	result := v1alpha1.PR{
+		Repo:   repo,
+		Number: int(x.Index),
+		Title:  x.Title,
+		Author: botModelFromUser(x.Poster),
+		State:  issueStateFromPRState(x.State, x.HasMerged),
+		URL:    x.HTMLURL,
+		Body:   x.Body,
+		// older Gitea versions only have the title prefixes
+		IsDraft: x.Draft || isDraftTitle(x.Title),
+
+		Labels:             botModelFromLabels(x.Labels),
+		Assignees:          botModelFromUsers(x.Assignees),
+		RequestedReviewers: botModelFromUsers(x.RequestedReviewers),
+	}
+
+	if x.Base != nil {
+		result.BaseBranch = x.Base.Ref
+	}
+	if x.Head != nil {
+		result.HeadBranch = x.Head.Ref
+		result.HeadSHA = x.Head.Sha
+	}
+	if x.Created != nil {
+		result.CreatedAt = *x.Created
+	}
+	if x.Updated != nil {
+		result.UpdatedAt = *x.Updated
+	}
+
+	return result
+}
+
+func botModelFromUser(x *gitea.User) v1alpha1.ForgeUser {
+	if x == nil {
+		return v1alpha1.ForgeUser{}
+	}
+
+	return v1alpha1.ForgeUser{
+		Forge:    forgeType,
+		UserName: x.UserName,
+	}
+}
+
+func botModelFromUsers(x []*gitea.User) []v1alpha1.ForgeUser {
+	result := make([]v1alpha1.ForgeUser, 0, len(x))
+	for _, u := range x {
+		if u == nil {
+			continue
+		}
+		result = append(result, botModelFromUser(u))
+	}
+	return result
+}
+
+func botModelFromLabels(x []*gitea.Label) []string {
+	result := make([]string, 0, len(x))
+	for _, l := range x {
+		if l == nil {
+			continue
+		}
+		result = append(result, l.Name)
+	}
+	return result
+}
+
+func botModelFromRepo(x *gitea.Repository) v1alpha1.Repo {
+	if x == nil {
+		return v1alpha1.Repo{}
+	}
+
+	return v1alpha1.Repo{
+		User:     botModelFromUser(x.Owner),
+		RepoName: x.Name,
+	}
+}
+
+func issueStateFromIssue(x *gitea.Issue) v1alpha1.IssueState {
+	merged := x.PullRequest != nil && x.PullRequest.HasMerged
+	return issueStateFromPRState(x.State, merged)
+}
+
+func issueStateFromPRState(state gitea.StateType, merged bool) v1alpha1.IssueState {
+	switch state {
+	case "open":
+		return v1alpha1.IssueStateOpen
+	case "closed":
+		if merged {
+			return v1alpha1.IssueStateMerged
+		}
+		return v1alpha1.IssueStateClosed
+	default:
+		return v1alpha1.IssueStateUnknown
+	}
+}
+
+// draftTitlePrefixes is Gitea's default list of "work in progress" PR title
+// prefixes.
+var draftTitlePrefixes = []string{"wip:", "[wip]"}
+
+// isDraftTitle returns whether the PR title marks the PR as a draft.
+func isDraftTitle(title string) bool {
+	lower := strings.ToLower(strings.TrimSpace(title))
+	for _, prefix := range draftTitlePrefixes {
+		if strings.HasPrefix(lower, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNullSHA returns whether the SHA is all zeros, which Gitea uses for
+// denoting creation and deletion of refs in push hooks.
+func isNullSHA(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
+
+func ciStateFromCombinedStatusState(state string) v1alpha1.CIState {
+	switch state {
+	case "success", "warning":
+		return v1alpha1.CIStatePassed
+	case "failure":
+		return v1alpha1.CIStateFailed
+	case "error":
+		return v1alpha1.CIStateErrored
+	default:
+		return v1alpha1.CIStateUnknown
+	}
+}
@@ -0,0 +1,161 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitea
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-playground/webhooks/v6/gitea"
+)
+
+// apiClient is a minimal Gitea REST API client, for looking up information
+// not included in webhook payloads.
+type apiClient struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+}
+
+func newAPIClient(baseURL string, token string) *apiClient {
+	return &apiClient{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+	}
+}
+
+type apiCombinedStatus struct {
+	State string `json:"state"`
+	SHA   string `json:"sha"`
+}
+
+// getCombinedStatus returns the combined commit status of the given ref.
+func (c *apiClient) getCombinedStatus(
+	ctx context.Context,
+	owner string,
+	repo string,
+	ref string,
+) (*apiCombinedStatus, error) {
+	var result apiCombinedStatus
+	err := c.get(
+		ctx,
+		fmt.Sprintf(
+			"/repos/%s/%s/commits/%s/status",
+			url.PathEscape(owner),
+			url.PathEscape(repo),
+			url.PathEscape(ref),
+		),
+		&result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// openPRsPageSize is the page size of listing open PRs, which is also the
+// default maximum of Gitea.
+const openPRsPageSize = 50
+
+// listOpenPRs returns all open PRs of the given repository.
+func (c *apiClient) listOpenPRs(
+	ctx context.Context,
+	owner string,
+	repo string,
+) ([]pullRequest, error) {
+	var result []pullRequest
+	for page := 1; ; page++ {
+		var prs []pullRequest
+		err := c.get(
+			ctx,
+			fmt.Sprintf(
+				"/repos/%s/%s/pulls?state=open&limit=%d&page=%d",
+				url.PathEscape(owner),
+				url.PathEscape(repo),
+				openPRsPageSize,
+				page,
+			),
+			&prs,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		result = append(result, prs...)
+		if len(prs) < openPRsPageSize {
+			return result, nil
+		}
+	}
+}
+
+// apiTimelineComment is an entry in the timeline of an issue or PR.
+type apiTimelineComment struct {
+	ID      int64       `json:"id"`
+	Type    string      `json:"type"`
+	Poster  *gitea.User `json:"user"`
+	Created time.Time   `json:"created_at"`
+	// Body is "1" for labels added, and empty for labels removed.
+	Body            string       `json:"body"`
+	Label           *gitea.Label `json:"label"`
+	Assignee        *gitea.User  `json:"assignee"`
+	RemovedAssignee bool         `json:"removed_assignee"`
+}
+
+// listTimeline returns the whole timeline of the given issue or PR, which is
+// available since Gitea 1.15.
+func (c *apiClient) listTimeline(
+	ctx context.Context,
+	owner string,
+	repo string,
+	number int,
+) ([]apiTimelineComment, error) {
+	// not paginated unless asked to
+	var result []apiTimelineComment
+	err := c.get(
+		ctx,
+		fmt.Sprintf(
+			"/repos/%s/%s/issues/%d/timeline",
+			url.PathEscape(owner),
+			url.PathEscape(repo),
+			number,
+		),
+		&result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// get requests the API path relative to the configured base URL, never any
+// URL from payloads, so that the token is not sent elsewhere.
+func (c *apiClient) get(ctx context.Context, path string, out interface{}) error {
+	endpoint := c.baseURL + path
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "token "+c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("Gitea API returned status %d for %s", resp.StatusCode, endpoint)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,340 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitea
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/webhooks/v6/gitea"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+const forgeType = "gitea"
+
+var (
+	ErrInvalidHTTPMethod      = errors.New("gitea: invalid HTTP method")
+	ErrMissingEventHeader     = errors.New("gitea: missing X-Gitea-Event-Type header")
+	ErrMissingSignatureHeader = errors.New("gitea: missing X-Gitea-Signature header")
+	ErrHMACVerificationFailed = errors.New("gitea: HMAC verification failed")
+	ErrSecretRequired         = errors.New("gitea: secret is required")
+	ErrAPIBaseURLRequired     = errors.New("gitea: API base URL is required")
+)
+
+type giteaForge struct {
+	secret []byte
+	api    *apiClient
+
+	finishedStatuses *forge.StatusClaims
+	// reportedChanges is the timeline entries of issues already reported,
+	// as Gitea sends one hook for each assignee added.
+	reportedChanges *forge.StatusClaims
+}
+
+var _ forge.IForgeHook = (*giteaForge)(nil)
+
+// New returns a new Gitea forge hook instance. Forgejo is also supported.
+//
+// The API base URL is that of the REST API, like
+// "https://gitea.example.com/api/v1". The token is used for Gitea API calls,
+// and can be left empty if all repositories are public.
+func New(secret string, token string, apiBaseURL string) (forge.IForgeHook, error) {
+	if secret == "" {
+		return nil, ErrSecretRequired
+	}
+	if apiBaseURL == "" {
+		return nil, ErrAPIBaseURLRequired
+	}
+
+	return &giteaForge{
+		secret: []byte(secret),
+		api:    newAPIClient(apiBaseURL, token),
+
+		finishedStatuses: forge.NewStatusClaims(),
+		reportedChanges:  forge.NewStatusClaims(),
+	}, nil
+}
+
+// HookRequest hooks an incoming webhook request to trigger actions.
+func (f *giteaForge) HookRequest(req *http.Request) (*v1alpha1.Event, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	e, err := f.hookRequest(req, body)
+	if e != nil {
+		e.SetDeliveryID(req.Header.Get("X-Gitea-Delivery"))
+		e.SetForgeEvent(req.Header.Get("X-Gitea-Event-Type"), forge.ActionFromPayload(body))
+		e.SetRawPayload(body)
+	}
+	return e, err
+}
+
+// hookRequest does the work of HookRequest.
+//
+// The upstream webhook library only looks at the X-Gitea-Event header, which
+// lumps together hooks with different payload types (e.g. review approvals
+// and PR comments are both "pull_request_comment"), and does not know about
+// commit status hooks, so the dispatching is done here instead, only reusing
+// the payload types.
+func (f *giteaForge) hookRequest(req *http.Request, body []byte) (*v1alpha1.Event, error) {
+	if req.Method != http.MethodPost {
+		return nil, ErrInvalidHTTPMethod
+	}
+
+	err := f.verify(req, body)
+	if err != nil {
+		return nil, err
+	}
+
+	event := req.Header.Get("X-Gitea-Event-Type")
+	if event == "" {
+		return nil, ErrMissingEventHeader
+	}
+
+	switch event {
+	case "pull_request", "pull_request_review_request":
+		var p pullRequestPayload
+		err := json.Unmarshal(body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		switch p.Action {
+		case "opened", "reopened":
+			params := intoPROpenedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "closed":
+			if p.PullRequest.HasMerged {
+				params := intoPRMergedParams(&p)
+				return params.IntoEvent(), nil
+			}
+
+			params := intoPRClosedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "edited":
+			if p.Changes == nil || p.Changes.Title == nil {
+				// Only the title is interesting
+				return nil, nil
+			}
+
+			// Gitea marks PRs as drafts with title prefixes, so check for
+			// draft status changes first.
+			wasDraft := isDraftTitle(p.Changes.Title.From)
+			isDraft := isDraftTitle(p.PullRequest.Title)
+			switch {
+			case wasDraft && !isDraft:
+				params := intoPRReadyParams(&p)
+				return params.IntoEvent(), nil
+			case !wasDraft && isDraft:
+				params := intoPRWithdrawnParams(&p)
+				return params.IntoEvent(), nil
+			}
+
+			params := intoPRRenamedParams(&p, p.Changes.Title.From)
+			return params.IntoEvent(), nil
+
+		case "review_requested":
+			params := intoPRReviewRequestedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "review_request_removed":
+			params := intoPRReviewRequestRemovedParams(&p)
+			return params.IntoEvent(), nil
+
+		default:
+			// Currently no bot event for this action
+			return nil, nil
+		}
+
+	case "pull_request_review_approved":
+		return f.hookReview(body, v1alpha1.ReviewTypeApprove)
+
+	case "pull_request_review_rejected":
+		return f.hookReview(body, v1alpha1.ReviewTypeRequestChanges)
+
+	case "pull_request_review_comment":
+		return f.hookReview(body, v1alpha1.ReviewTypeComment)
+
+	case "issues", "issue_assign", "issue_label":
+		var p gitea.IssuePayload
+		err := json.Unmarshal(body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		switch p.Action {
+		case "opened":
+			params := intoIssueOpenedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "closed":
+			params := intoIssueClosedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "reopened":
+			params := intoIssueReopenedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "assigned":
+			params := intoIssueAssignedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "label_updated":
+			// also sent for labels removed, which ResolveEvent drops
+			params := intoIssueLabeledParams(&p)
+			return params.IntoEvent(), nil
+
+		case "edited":
+			if p.Changes == nil || p.Changes.Title == nil {
+				// Only the title is interesting
+				return nil, nil
+			}
+
+			params := intoIssueRenamedParams(&p, p.Changes.Title.From)
+			return params.IntoEvent(), nil
+
+		default:
+			// Currently no bot event for this action
+			return nil, nil
+		}
+
+	case "issue_comment", "pull_request_comment":
+		var p gitea.IssueCommentPayload
+		err := json.Unmarshal(body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Action != "created" {
+			return nil, nil
+		}
+
+		params := intoCommentCreatedParams(&p)
+		return params.IntoEvent(), nil
+
+	case "push":
+		var p gitea.PushPayload
+		err := json.Unmarshal(body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		if isNullSHA(p.Before) || isNullSHA(p.After) || !strings.HasPrefix(p.Ref, "refs/heads/") {
+			// Covered by the create and delete events
+			return nil, nil
+		}
+
+		params := intoPushParams(&p)
+		return params.IntoEvent(), nil
+
+	case "create":
+		var p gitea.CreatePayload
+		err := json.Unmarshal(body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		switch p.RefType {
+		case "branch":
+			params := intoBranchCreatedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "tag":
+			params := intoTagCreatedParams(&p)
+			return params.IntoEvent(), nil
+
+		default:
+			return nil, nil
+		}
+
+	case "delete":
+		var p gitea.DeletePayload
+		err := json.Unmarshal(body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.RefType != "branch" {
+			// Tag deletions are not interesting
+			return nil, nil
+		}
+
+		params := intoBranchDeletedParams(&p)
+		return params.IntoEvent(), nil
+
+	case "release":
+		var p gitea.ReleasePayload
+		err := json.Unmarshal(body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Action != "published" || p.Release.IsDraft {
+			return nil, nil
+		}
+
+		params := intoReleasePublishedParams(&p)
+		return params.IntoEvent(), nil
+
+	case "status":
+		var p statusPayload
+		err := json.Unmarshal(body, &p)
+		if err != nil {
+			return nil, err
+		}
+
+		// Only report on the combined status, once every context has
+		// finished, which is looked up later in ResolveEvent along with the
+		// PRs. The state of this one status is only a placeholder.
+		state := ciStateFromCombinedStatusState(p.State)
+		params := intoCIFinishedParams(&p, state)
+		return params.IntoEvent(), nil
+	}
+
+	// Currently not handled
+	return nil, nil
+}
+
+func (f *giteaForge) hookReview(body []byte, review v1alpha1.ReviewType) (*v1alpha1.Event, error) {
+	var p pullRequestPayload
+	err := json.Unmarshal(body, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	params := intoPRReviewedParams(&p, review)
+	return params.IntoEvent(), nil
+}
+
+// verify checks the request's HMAC-SHA256 signature.
+func (f *giteaForge) verify(req *http.Request, body []byte) error {
+	signature := req.Header.Get("X-Gitea-Signature")
+	if signature == "" {
+		return ErrMissingSignatureHeader
+	}
+
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return ErrHMACVerificationFailed
+	}
+
+	mac := hmac.New(sha256.New, f.secret)
+	_, _ = mac.Write(body)
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return ErrHMACVerificationFailed
+	}
+
+	return nil
+}
@@ -0,0 +1,385 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitea
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge/internal/hooktest"
+)
+
+const testSecret = "Sup3rS3cr3tStr1ng"
+
+func sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newTestRequest(event string, body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/gitea", bytes.NewReader(body))
+	req.Header.Set("X-Gitea-Event-Type", event)
+	req.Header.Set("X-Gitea-Signature", sign(body))
+	req.Header.Set("X-Gitea-Delivery", "6c2a1f0e-8d3b-4f5a-9e7c-1b0d2a3c4e5f")
+	return req
+}
+
+// hookFixture feeds the recorded hook payload in testdata to the forge hook.
+func hookFixture(t *testing.T, f *giteaForge, event string, name string) *v1alpha1.Event {
+	t.Helper()
+
+	e, err := f.HookRequest(newTestRequest(event, hooktest.Fixture(t, name)))
+	if err != nil {
+		t.Fatalf("HookRequest: %v", err)
+	}
+	return e
+}
+
+func newTestHook(t *testing.T) *giteaForge {
+	t.Helper()
+
+	fh, err := New(testSecret, "", "https://gitea.example.com/api/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fh.(*giteaForge)
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("", "", "https://gitea.example.com/api/v1")
+	if !errors.Is(err, ErrSecretRequired) {
+		t.Errorf("no secret: want %v, got %v", ErrSecretRequired, err)
+	}
+
+	_, err = New(testSecret, "", "")
+	if !errors.Is(err, ErrAPIBaseURLRequired) {
+		t.Errorf("no API base URL: want %v, got %v", ErrAPIBaseURLRequired, err)
+	}
+}
+
+func TestHookRequestRejected(t *testing.T) {
+	body := hooktest.Fixture(t, "pr-opened.json")
+
+	testcases := []struct {
+		name   string
+		modify func(req *http.Request)
+		want   error
+	}{
+		{
+			name:   "GET",
+			modify: func(req *http.Request) { req.Method = http.MethodGet },
+			want:   ErrInvalidHTTPMethod,
+		},
+		{
+			name:   "no signature",
+			modify: func(req *http.Request) { req.Header.Del("X-Gitea-Signature") },
+			want:   ErrMissingSignatureHeader,
+		},
+		{
+			name:   "wrong signature",
+			modify: func(req *http.Request) { req.Header.Set("X-Gitea-Signature", sign([]byte("{}"))) },
+			want:   ErrHMACVerificationFailed,
+		},
+		{
+			name:   "malformed signature",
+			modify: func(req *http.Request) { req.Header.Set("X-Gitea-Signature", "sha256=abc") },
+			want:   ErrHMACVerificationFailed,
+		},
+		{
+			name:   "no event",
+			modify: func(req *http.Request) { req.Header.Del("X-Gitea-Event-Type") },
+			want:   ErrMissingEventHeader,
+		},
+	}
+
+	f := newTestHook(t)
+	for _, tc := range testcases {
+		req := newTestRequest("pull_request", body)
+		tc.modify(req)
+
+		e, err := f.HookRequest(req)
+		if e != nil || !errors.Is(err, tc.want) {
+			t.Errorf("%s: want %v, got %v, %v", tc.name, tc.want, e, err)
+		}
+	}
+}
+
+var (
+	testRepo = v1alpha1.Repo{
+		User:     v1alpha1.ForgeUser{Forge: forgeType, UserName: "acme"},
+		RepoName: "widget",
+	}
+
+	alice = v1alpha1.ForgeUser{Forge: forgeType, UserName: "alice"}
+	bob   = v1alpha1.ForgeUser{Forge: forgeType, UserName: "bob"}
+	carol = v1alpha1.ForgeUser{Forge: forgeType, UserName: "carol"}
+
+	created = time.Date(2023, 9, 12, 8, 15, 3, 0, time.UTC)
+	updated = time.Date(2023, 9, 12, 9, 2, 41, 0, time.UTC)
+)
+
+const testHeadSHA = "5f1c0e7b6d3a8e4f2b9c1d0a7e6f5b4c3d2e1f0a"
+
+// testPR returns the PR in the fixtures, in the given state.
+func testPR(state v1alpha1.IssueState, updatedAt time.Time) v1alpha1.PR {
+	return v1alpha1.PR{
+		Repo:   testRepo,
+		Number: 17,
+		Title:  "Retry uploads on transient errors",
+		Author: alice,
+		State:  state,
+		URL:    "https://gitea.example.com/acme/widget/pulls/17",
+		Body:   "Retries the upload on transient errors.",
+
+		BaseBranch: "main",
+		HeadBranch: "fix-upload-retry",
+		HeadSHA:    testHeadSHA,
+
+		Labels:             []string{"bug"},
+		Assignees:          []v1alpha1.ForgeUser{bob},
+		RequestedReviewers: []v1alpha1.ForgeUser{carol},
+
+		CreatedAt: created,
+		UpdatedAt: updatedAt,
+	}
+}
+
+// testIssue returns the issue in the fixtures, in the given state.
+func testIssue(state v1alpha1.IssueState, labels []string, assignees []v1alpha1.ForgeUser) v1alpha1.Issue {
+	return v1alpha1.Issue{
+		Repo:      testRepo,
+		Number:    21,
+		Title:     "Uploads fail on flaky networks",
+		Author:    alice,
+		State:     state,
+		URL:       "https://gitea.example.com/acme/widget/issues/21",
+		Body:      "Uploads give up on the first connection reset.",
+		Labels:    labels,
+		Assignees: assignees,
+		CreatedAt: created,
+		UpdatedAt: updated,
+	}
+}
+
+func TestHookRequest(t *testing.T) {
+	issue := testIssue(v1alpha1.IssueStateOpen, []string{"bug"}, []v1alpha1.ForgeUser{bob})
+
+	openedIssue := testIssue(v1alpha1.IssueStateOpen, []string{}, []v1alpha1.ForgeUser{})
+	openedIssue.UpdatedAt = created
+
+	testcases := []struct {
+		event   string
+		fixture string
+		want    hooktest.Params
+	}{
+		{
+			event:   "pull_request",
+			fixture: "pr-opened.json",
+			want: &v1alpha1.PROpenedParams{
+				Actor: alice,
+				PR:    testPR(v1alpha1.IssueStateOpen, created),
+			},
+		},
+		{
+			event:   "pull_request",
+			fixture: "pr-merged.json",
+			want: &v1alpha1.PRMergedParams{
+				Actor: bob,
+				PR:    testPR(v1alpha1.IssueStateMerged, updated),
+			},
+		},
+		{
+			event:   "pull_request",
+			fixture: "pr-edited-title.json",
+			want: &v1alpha1.PRRenamedParams{
+				Actor:    alice,
+				PR:       testPR(v1alpha1.IssueStateOpen, updated),
+				OldTitle: "Retry uploads",
+			},
+		},
+		{
+			// the title change from the draft prefix is not a rename
+			event:   "pull_request",
+			fixture: "pr-edited-ready.json",
+			want: &v1alpha1.PRReadyParams{
+				Actor: alice,
+				PR:    testPR(v1alpha1.IssueStateOpen, updated),
+			},
+		},
+		{
+			event:   "pull_request_review_request",
+			fixture: "pr-review-requested.json",
+			want: &v1alpha1.PRReviewRequestedParams{
+				Actor:     alice,
+				PR:        testPR(v1alpha1.IssueStateOpen, updated),
+				Reviewers: []v1alpha1.ForgeUser{carol},
+			},
+		},
+		{
+			event:   "pull_request_review_approved",
+			fixture: "pr-review-approved.json",
+			want: &v1alpha1.PRReviewedParams{
+				Actor:  carol,
+				PR:     testPR(v1alpha1.IssueStateOpen, updated),
+				Review: v1alpha1.ReviewTypeApprove,
+			},
+		},
+		{
+			event:   "issues",
+			fixture: "issue-opened.json",
+			want: &v1alpha1.IssueOpenedParams{
+				Actor: alice,
+				Issue: openedIssue,
+			},
+		},
+		{
+			event:   "issues",
+			fixture: "issue-closed.json",
+			want: &v1alpha1.IssueClosedParams{
+				Actor: bob,
+				Issue: testIssue(v1alpha1.IssueStateClosed, []string{"bug"}, []v1alpha1.ForgeUser{bob}),
+			},
+		},
+		{
+			event:   "issues",
+			fixture: "issue-edited-title.json",
+			want: &v1alpha1.IssueRenamedParams{
+				Actor:    alice,
+				Issue:    issue,
+				OldTitle: "Uploads fail",
+			},
+		},
+		{
+			// the assignees added are left to ResolveEvent
+			event:   "issue_assign",
+			fixture: "issue-assigned.json",
+			want: &v1alpha1.IssueAssignedParams{
+				Actor: alice,
+				Issue: testIssue(v1alpha1.IssueStateOpen, []string{"bug"}, []v1alpha1.ForgeUser{bob, carol}),
+			},
+		},
+		{
+			// so are the labels added
+			event:   "issue_label",
+			fixture: "issue-label-updated.json",
+			want: &v1alpha1.IssueLabeledParams{
+				Actor: bob,
+				Issue: testIssue(v1alpha1.IssueStateOpen, []string{"bug", "help wanted"}, []v1alpha1.ForgeUser{bob}),
+			},
+		},
+		{
+			event:   "issue_comment",
+			fixture: "issue-comment.json",
+			want: &v1alpha1.CommentCreatedParams{
+				Actor:  carol,
+				Target: v1alpha1.CommentTargetTypeIssue,
+				Issue:  issue,
+				Comment: v1alpha1.Comment{
+					Author:    carol,
+					Body:      "Seeing this too.",
+					URL:       "https://gitea.example.com/acme/widget/issues/21#issuecomment-81",
+					CreatedAt: updated,
+				},
+			},
+		},
+		{
+			event:   "push",
+			fixture: "push.json",
+			want: &v1alpha1.PushParams{
+				Actor:  alice,
+				Repo:   testRepo,
+				Ref:    "refs/heads/main",
+				Before: "0a1b2c3d4e5f60718293a4b5c6d7e8f901234567",
+				After:  testHeadSHA,
+				Commits: []v1alpha1.Commit{
+					{
+						SHA:         testHeadSHA,
+						Message:     "Retry uploads on transient errors\n",
+						URL:         "https://gitea.example.com/acme/widget/commit/" + testHeadSHA,
+						Author:      alice,
+						AuthorName:  "Alice",
+						AuthorEmail: "alice@example.com",
+						Timestamp:   created,
+					},
+				},
+			},
+		},
+		{
+			event:   "create",
+			fixture: "create-branch.json",
+			want: &v1alpha1.BranchCreatedParams{
+				Actor:  alice,
+				Repo:   testRepo,
+				Branch: "fix-upload-retry",
+				SHA:    testHeadSHA,
+			},
+		},
+		{
+			// tag deletions are not interesting
+			event:   "delete",
+			fixture: "delete-tag.json",
+			want:    nil,
+		},
+		{
+			event:   "release",
+			fixture: "release-published.json",
+			want: &v1alpha1.ReleasePublishedParams{
+				Actor: bob,
+				Release: v1alpha1.Release{
+					Repo:      testRepo,
+					Tag:       "v1.0.0",
+					Name:      "1.0.0",
+					Notes:     "First stable release.",
+					Author:    bob,
+					URL:       "https://gitea.example.com/acme/widget/releases/tag/v1.0.0",
+					CreatedAt: updated,
+				},
+			},
+		},
+		{
+			// the combined status and the PRs are left to ResolveEvent
+			event:   "status",
+			fixture: "status-success.json",
+			want: &v1alpha1.CIFinishedParams{
+				Run: v1alpha1.CIRun{
+					Repo:  testRepo,
+					State: v1alpha1.CIStatePassed,
+					SHA:   testHeadSHA,
+					URL:   "https://ci.example.com/builds/7",
+				},
+			},
+		},
+	}
+
+	f := newTestHook(t)
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.fixture, func(t *testing.T) {
+			e := hookFixture(t, f, tc.event, tc.fixture)
+			hooktest.AssertEvent(t, e, tc.want)
+		})
+	}
+}
+
+func TestHookRequestMetadata(t *testing.T) {
+	e := hookFixture(t, newTestHook(t), "pull_request", "pr-opened.json")
+
+	if got := e.DeliveryID(); got != "6c2a1f0e-8d3b-4f5a-9e7c-1b0d2a3c4e5f" {
+		t.Errorf("delivery ID: got %q", got)
+	}
+	if e.ForgeEvent() != "pull_request" || e.ForgeAction() != "opened" {
+		t.Errorf("event: got %q, %q", e.ForgeEvent(), e.ForgeAction())
+	}
+	if len(e.RawPayload()) == 0 {
+		t.Errorf("raw payload not kept")
+	}
+}
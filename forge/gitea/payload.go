@@ -0,0 +1,39 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitea
+
+import (
+	"github.com/go-playground/webhooks/v6/gitea"
+)
+
+// Supplementary payload types for fields and events missing from the
+// upstream webhook library.
+
+// pullRequest is gitea.PullRequest with the missing fields added.
+type pullRequest struct {
+	gitea.PullRequest
+	Draft              bool          `json:"draft"`
+	RequestedReviewers []*gitea.User `json:"requested_reviewers"`
+}
+
+// pullRequestPayload is gitea.PullRequestPayload with the missing fields
+// added.
+type pullRequestPayload struct {
+	gitea.PullRequestPayload
+	// shadows the upstream field
+	PullRequest *pullRequest `json:"pull_request"`
+	// RequestedReviewer is the user added to or removed from the reviewers,
+	// for review request actions.
+	RequestedReviewer *gitea.User `json:"requested_reviewer"`
+}
+
+// statusPayload is the payload of commit status hooks.
+type statusPayload struct {
+	SHA         string            `json:"sha"`
+	State       string            `json:"state"`
+	Context     string            `json:"context"`
+	Description string            `json:"description"`
+	TargetURL   string            `json:"target_url"`
+	Repository  *gitea.Repository `json:"repository"`
+	Sender      *gitea.User       `json:"sender"`
+}
@@ -0,0 +1,194 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitea
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+var _ forge.IEventResolver = (*giteaForge)(nil)
+
+// NeedsResolving returns whether the event is to be completed with API
+// lookups, which is the case for CIFinished, IssueAssigned and IssueLabeled
+// events.
+func (f *giteaForge) NeedsResolving(e *v1alpha1.Event) bool {
+	switch e.Type() {
+	case v1alpha1.EventTypeCIFinished, v1alpha1.EventTypeIssueAssigned, v1alpha1.EventTypeIssueLabeled:
+		return true
+	default:
+		return false
+	}
+}
+
+// ResolveEvent completes the event with what Gitea hooks do not tell, and
+// drops events turning out to be nothing to report.
+func (f *giteaForge) ResolveEvent(ctx context.Context, e *v1alpha1.Event) (*v1alpha1.Event, error) {
+	if params, ok := e.CIFinished(); ok {
+		return f.resolveCIFinished(ctx, e, params)
+	}
+	if params, ok := e.IssueAssigned(); ok {
+		return f.resolveIssueAssigned(ctx, e, params)
+	}
+	if params, ok := e.IssueLabeled(); ok {
+		return f.resolveIssueLabeled(ctx, e, params)
+	}
+	return e, nil
+}
+
+// resolveCIFinished completes CIFinished events with the combined status and
+// the open PRs of the commit. Events are dropped while the combined status is
+// pending, or if another event has already reported the same final combined
+// status of the commit.
+func (f *giteaForge) resolveCIFinished(
+	ctx context.Context,
+	e *v1alpha1.Event,
+	params *v1alpha1.CIFinishedParams,
+) (*v1alpha1.Event, error) {
+	run := &params.Run
+	owner := run.Repo.User.UserName
+	repo := run.Repo.RepoName
+
+	combined, err := f.api.getCombinedStatus(ctx, owner, repo, run.SHA)
+	if err != nil {
+		return nil, err
+	}
+
+	if combined.State == "pending" || combined.State == "" {
+		return nil, nil
+	}
+
+	// Several statuses finishing close together all see the final combined
+	// status.
+	key := owner + "/" + repo + "@" + run.SHA + ":" + combined.State
+	if !f.finishedStatuses.Claim(key, e.ID(), time.Now()) {
+		return nil, nil
+	}
+
+	run.State = ciStateFromCombinedStatusState(combined.State)
+
+	// Gitea cannot list the PRs of a commit, so go through all open ones.
+	prs, err := f.api.listOpenPRs(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	run.PRs = botModelFromOpenPRsForCommit(run.Repo, prs, run.SHA)
+	if len(run.PRs) > 0 {
+		run.PR = run.PRs[0]
+	}
+	return e, nil
+}
+
+// resolveIssueAssigned fills in the assignees added by the change the event is
+// for, from the timeline of the issue. The event is dropped if there is none
+// still assigned, or all are already reported by other events, as Gitea sends
+// one hook for each assignee added.
+func (f *giteaForge) resolveIssueAssigned(
+	ctx context.Context,
+	e *v1alpha1.Event,
+	params *v1alpha1.IssueAssignedParams,
+) (*v1alpha1.Event, error) {
+	changes, err := f.latestChanges(ctx, &params.Issue, params.Actor, "assignees")
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := make(map[string]struct{}, len(params.Issue.Assignees))
+	for _, u := range params.Issue.Assignees {
+		assigned[u.UserName] = struct{}{}
+	}
+
+	now := time.Now()
+	for _, c := range changes {
+		if c.RemovedAssignee || c.Assignee == nil {
+			continue
+		}
+		if _, ok := assigned[c.Assignee.UserName]; !ok {
+			continue
+		}
+		if !f.reportedChanges.Claim(strconv.FormatInt(c.ID, 10), e.ID(), now) {
+			continue
+		}
+		params.Assignees = append(params.Assignees, botModelFromUser(c.Assignee))
+	}
+
+	if len(params.Assignees) == 0 {
+		return nil, nil
+	}
+	return e, nil
+}
+
+// resolveIssueLabeled fills in the labels added by the change the event is
+// for, from the timeline of the issue. The event is dropped if there is none
+// still present, for example if labels are only removed.
+func (f *giteaForge) resolveIssueLabeled(
+	ctx context.Context,
+	e *v1alpha1.Event,
+	params *v1alpha1.IssueLabeledParams,
+) (*v1alpha1.Event, error) {
+	changes, err := f.latestChanges(ctx, &params.Issue, params.Actor, "label")
+	if err != nil {
+		return nil, err
+	}
+
+	present := make(map[string]struct{}, len(params.Issue.Labels))
+	for _, l := range params.Issue.Labels {
+		present[l] = struct{}{}
+	}
+
+	now := time.Now()
+	for _, c := range changes {
+		if c.Body != "1" || c.Label == nil {
+			continue
+		}
+		if _, ok := present[c.Label.Name]; !ok {
+			continue
+		}
+		if !f.reportedChanges.Claim(strconv.FormatInt(c.ID, 10), e.ID(), now) {
+			continue
+		}
+		params.Labels = append(params.Labels, c.Label.Name)
+	}
+
+	if len(params.Labels) == 0 {
+		return nil, nil
+	}
+	return e, nil
+}
+
+// latestChanges returns the timeline entries of the given type of the latest
+// such change by the actor, which is taken as the change the hook is sent
+// for. Gitea makes the entries of one change at the same time, in seconds.
+func (f *giteaForge) latestChanges(
+	ctx context.Context,
+	issue *v1alpha1.Issue,
+	actor v1alpha1.ForgeUser,
+	typ string,
+) ([]apiTimelineComment, error) {
+	timeline, err := f.api.listTimeline(ctx, issue.Repo.User.UserName, issue.Repo.RepoName, issue.Number)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []apiTimelineComment
+	var latest time.Time
+	for _, c := range timeline {
+		if c.Type != typ || c.Poster == nil || c.Poster.UserName != actor.UserName {
+			continue
+		}
+
+		switch {
+		case c.Created.After(latest):
+			latest = c.Created
+			result = []apiTimelineComment{c}
+		case c.Created.Equal(latest):
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
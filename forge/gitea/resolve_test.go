@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitea
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge/internal/apitest"
+	"github.com/xen0n/brickbot/forge/internal/hooktest"
+)
+
+const testToken = "t0k3n"
+
+// newTestForge returns a forge hook talking to a stand-in server, which
+// expects exactly the given calls in order.
+func newTestForge(t *testing.T, calls ...apitest.Call) *giteaForge {
+	t.Helper()
+
+	fh, err := New(testSecret, testToken, apitest.NewServer(t, "Authorization", "token "+testToken, calls...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fh.(*giteaForge)
+}
+
+// resolveFixture feeds the recorded hook payload in testdata to the forge
+// hook as the event of the given ID, and resolves the event.
+func resolveFixture(t *testing.T, f *giteaForge, event string, name string, id string) *v1alpha1.Event {
+	t.Helper()
+
+	e := hookFixture(t, f, event, name)
+	if e == nil {
+		t.Fatal("want event, got none")
+	}
+	if !f.NeedsResolving(e) {
+		t.Fatalf("want event of type %d to need resolving", e.Type())
+	}
+	e.SetID(id)
+
+	e, err := f.ResolveEvent(context.Background(), e)
+	if err != nil {
+		t.Fatalf("ResolveEvent: %v", err)
+	}
+	return e
+}
+
+const statusPath = "/repos/acme/widget/commits/" + testHeadSHA + "/status"
+
+func TestResolveCIFinished(t *testing.T) {
+	getStatus := func(state string) apitest.Call {
+		return apitest.Call{
+			Method:   http.MethodGet,
+			Path:     statusPath,
+			Response: `{"state": "` + state + `", "sha": "` + testHeadSHA + `"}`,
+		}
+	}
+
+	f := newTestForge(
+		t,
+		getStatus("pending"),
+		getStatus("failure"),
+		apitest.Call{
+			Method: http.MethodGet,
+			Path:   "/repos/acme/widget/pulls?state=open&limit=50&page=1",
+			Response: `[
+				{"number": 16, "title": "Other", "head": {"ref": "other", "sha": "1111111111111111111111111111111111111111"}},
+				{"number": 17, "title": "Retry uploads", "head": {"ref": "fix-upload-retry", "sha": "` + testHeadSHA + `"}}
+			]`,
+		},
+		// reported already
+		getStatus("failure"),
+	)
+
+	// other statuses still running
+	e := resolveFixture(t, f, "status", "status-success.json", "1")
+	if e != nil {
+		t.Fatalf("pending: want no event, got %v", e.Type())
+	}
+
+	e = resolveFixture(t, f, "status", "status-success.json", "2")
+	if e == nil {
+		t.Fatal("failure: want event, got none")
+	}
+	params, _ := e.CIFinished()
+	run := params.Run
+	if run.State != v1alpha1.CIStateFailed {
+		t.Errorf("state: got %d, want %d", run.State, v1alpha1.CIStateFailed)
+	}
+	if len(run.PRs) != 1 || run.PRs[0].Number != 17 || !reflect.DeepEqual(run.PR, run.PRs[0]) {
+		t.Errorf("PRs: got %+v, want PR 17 only", run.PRs)
+	}
+
+	e = resolveFixture(t, f, "status", "status-success.json", "3")
+	if e != nil {
+		t.Errorf("failure again: want no event, got %v", e.Type())
+	}
+}
+
+const timelinePath = "/repos/acme/widget/issues/21/timeline"
+
+func TestResolveIssueLabeled(t *testing.T) {
+	issue := testIssue(v1alpha1.IssueStateOpen, []string{"bug", "help wanted"}, []v1alpha1.ForgeUser{bob})
+
+	testcases := []struct {
+		name     string
+		timeline string
+		want     hooktest.Params
+	}{
+		{
+			name: "added",
+			timeline: `[
+				{"id": 90, "type": "label", "user": {"login": "alice"}, "created_at": "2023-09-12T08:15:03Z",
+					"body": "1", "label": {"name": "bug"}},
+				{"id": 91, "type": "comment", "user": {"login": "bob"}, "created_at": "2023-09-12T09:00:00Z",
+					"body": "Confirmed."},
+				{"id": 92, "type": "label", "user": {"login": "bob"}, "created_at": "2023-09-12T09:02:41Z",
+					"body": "1", "label": {"name": "help wanted"}},
+				{"id": 93, "type": "label", "user": {"login": "bob"}, "created_at": "2023-09-12T09:02:41Z",
+					"body": "", "label": {"name": "wontfix"}}
+			]`,
+			want: &v1alpha1.IssueLabeledParams{
+				Actor:  bob,
+				Issue:  issue,
+				Labels: []string{"help wanted"},
+			},
+		},
+		{
+			name: "removed only",
+			timeline: `[
+				{"id": 90, "type": "label", "user": {"login": "bob"}, "created_at": "2023-09-12T08:15:03Z",
+					"body": "1", "label": {"name": "bug"}},
+				{"id": 93, "type": "label", "user": {"login": "bob"}, "created_at": "2023-09-12T09:02:41Z",
+					"body": "", "label": {"name": "wontfix"}}
+			]`,
+			want: nil,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			f := newTestForge(t, apitest.Call{
+				Method:   http.MethodGet,
+				Path:     timelinePath,
+				Response: tc.timeline,
+			})
+
+			e := resolveFixture(t, f, "issue_label", "issue-label-updated.json", "1")
+			hooktest.AssertEvent(t, e, tc.want)
+		})
+	}
+}
+
+// TestResolveIssueAssigned checks the assignees added at once are reported
+// only once, although Gitea sends one hook for each of them.
+func TestResolveIssueAssigned(t *testing.T) {
+	getTimeline := apitest.Call{
+		Method: http.MethodGet,
+		Path:   timelinePath,
+		Response: `[
+			{"id": 70, "type": "assignees", "user": {"login": "alice"}, "created_at": "2023-09-12T08:15:03Z",
+				"assignee": {"login": "bob"}},
+			{"id": 71, "type": "assignees", "user": {"login": "alice"}, "created_at": "2023-09-12T09:02:41Z",
+				"assignee": {"login": "carol"}},
+			{"id": 72, "type": "assignees", "user": {"login": "alice"}, "created_at": "2023-09-12T09:02:41Z",
+				"assignee": {"login": "dave"}, "removed_assignee": true}
+		]`,
+	}
+	f := newTestForge(t, getTimeline, getTimeline)
+
+	e := resolveFixture(t, f, "issue_assign", "issue-assigned.json", "1")
+	hooktest.AssertEvent(t, e, &v1alpha1.IssueAssignedParams{
+		Actor:     alice,
+		Issue:     testIssue(v1alpha1.IssueStateOpen, []string{"bug"}, []v1alpha1.ForgeUser{bob, carol}),
+		Assignees: []v1alpha1.ForgeUser{carol},
+	})
+
+	e = resolveFixture(t, f, "issue_assign", "issue-assigned.json", "2")
+	hooktest.AssertEvent(t, e, nil)
+}
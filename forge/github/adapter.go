@@ -3,6 +3,9 @@
 package github
 
 import (
+	"strings"
+	"time"
+
 	"github.com/go-playground/webhooks/v6/github"
 
 	"github.com/xen0n/brickbot/bot/v1alpha1"
@@ -18,8 +21,9 @@ func intoWebhookInstalledParams(x *github.PingPayload) v1alpha1.WebhookInstalled
 
 func intoPROpenedParams(x *github.PullRequestPayload) v1alpha1.PROpenedParams {
 	return v1alpha1.PROpenedParams{
-		Actor: botModelFromSenderContainingPayload(x),
-		PR:    botModelFromPRContainingPayload(x),
+		Actor:   botModelFromSenderContainingPayload(x),
+		PR:      botModelFromPRContainingPayload(x),
+		IsDraft: isDraftPRFromPRContainingPayload(x),
 	}
 }
 
@@ -37,6 +41,14 @@ func intoPRMergedParams(x *github.PullRequestPayload) v1alpha1.PRMergedParams {
 	}
 }
 
+func intoPRRenamedParams(x *github.PullRequestPayload, oldTitle string) v1alpha1.PRRenamedParams {
+	return v1alpha1.PRRenamedParams{
+		Actor:    botModelFromSenderContainingPayload(x),
+		PR:       botModelFromPRContainingPayload(x),
+		OldTitle: oldTitle,
+	}
+}
+
 func intoPRReadyParams(x *github.PullRequestPayload) v1alpha1.PRReadyParams {
 	return v1alpha1.PRReadyParams{
 		Actor: botModelFromSenderContainingPayload(x),
@@ -44,6 +56,284 @@ func intoPRReadyParams(x *github.PullRequestPayload) v1alpha1.PRReadyParams {
 	}
 }
 
+func intoPRWithdrawnParams(x *github.PullRequestPayload) v1alpha1.PRWithdrawnParams {
+	return v1alpha1.PRWithdrawnParams{
+		PR: botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoPRReviewRequestedParams(x *github.PullRequestPayload) v1alpha1.PRReviewRequestedParams {
+	reviewers, teams := requestedReviewerFromPRPayload(x)
+	return v1alpha1.PRReviewRequestedParams{
+		Actor:     botModelFromSenderContainingPayload(x),
+		PR:        botModelFromPRContainingPayload(x),
+		Reviewers: reviewers,
+		Teams:     teams,
+	}
+}
+
+func intoPRReviewRequestRemovedParams(x *github.PullRequestPayload) v1alpha1.PRReviewRequestRemovedParams {
+	reviewers, teams := requestedReviewerFromPRPayload(x)
+	return v1alpha1.PRReviewRequestRemovedParams{
+		Actor:     botModelFromSenderContainingPayload(x),
+		PR:        botModelFromPRContainingPayload(x),
+		Reviewers: reviewers,
+		Teams:     teams,
+	}
+}
+
+func intoPRReviewedParams(x *github.PullRequestReviewPayload) v1alpha1.PRReviewedParams {
+	return v1alpha1.PRReviewedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		PR:     botModelFromPRContainingPayload(x),
+		Review: reviewTypeFromReviewState(x.Review.State),
+	}
+}
+
+func intoIssueOpenedParams(x *github.IssuesPayload) v1alpha1.IssueOpenedParams {
+	return v1alpha1.IssueOpenedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueClosedParams(x *github.IssuesPayload) v1alpha1.IssueClosedParams {
+	return v1alpha1.IssueClosedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueReopenedParams(x *github.IssuesPayload) v1alpha1.IssueReopenedParams {
+	return v1alpha1.IssueReopenedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueAssignedParams(x *github.IssuesPayload) v1alpha1.IssueAssignedParams {
+	return v1alpha1.IssueAssignedParams{
+		Actor:     botModelFromSenderContainingPayload(x),
+		Issue:     botModelFromIssueContainingPayload(x),
+		Assignees: botModelFromAssignees([]*github.Assignee{x.Assignee}),
+	}
+}
+
+func intoIssueLabeledParams(x *github.IssuesPayload) v1alpha1.IssueLabeledParams {
+	var labels []string
+	if x.Label != nil {
+		labels = []string{x.Label.Name}
+	}
+
+	return v1alpha1.IssueLabeledParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		Issue:  botModelFromIssueContainingPayload(x),
+		Labels: labels,
+	}
+}
+
+func intoIssueRenamedParams(x *github.IssuesPayload, oldTitle string) v1alpha1.IssueRenamedParams {
+	return v1alpha1.IssueRenamedParams{
+		Actor:    botModelFromSenderContainingPayload(x),
+		Issue:    botModelFromIssueContainingPayload(x),
+		OldTitle: oldTitle,
+	}
+}
+
+func intoCommentCreatedParams(x interface{}, extras *reviewCommentExtras) v1alpha1.CommentCreatedParams {
+	result := v1alpha1.CommentCreatedParams{
+		Actor:   botModelFromSenderContainingPayload(x),
+		Comment: botModelFromCommentContainingPayload(x, extras),
+	}
+
+	switch x := x.(type) {
+	case *github.IssueCommentPayload:
+		// PRs are also issues on GitHub
+		if x.Issue.PullRequest != nil {
+			result.Target = v1alpha1.CommentTargetTypePR
+			result.PR = botModelFromPRContainingPayload(x)
+		} else {
+			result.Target = v1alpha1.CommentTargetTypeIssue
+			result.Issue = botModelFromIssueContainingPayload(x)
+		}
+
+	case *github.PullRequestReviewCommentPayload:
+		result.Target = v1alpha1.CommentTargetTypePR
+		result.PR = botModelFromPRContainingPayload(x)
+
+	default:
+		panic("should never happen")
+	}
+
+	return result
+}
+
+func intoPushParams(x *github.PushPayload) v1alpha1.PushParams {
+	commits := make([]v1alpha1.Commit, len(x.Commits))
+	for i, c := range x.Commits {
+		// best-effort
+		ts, _ := time.Parse(time.RFC3339, c.Timestamp)
+
+		commits[i] = v1alpha1.Commit{
+			SHA:     c.ID,
+			Message: c.Message,
+			URL:     c.URL,
+			Author: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: c.Author.Username,
+			},
+			AuthorName:  c.Author.Name,
+			AuthorEmail: c.Author.Email,
+			Timestamp:   ts,
+		}
+	}
+
+	return v1alpha1.PushParams{
+		Actor:   botModelFromSenderContainingPayload(x),
+		Repo:    botModelFromRepoContainingPayload(x),
+		Ref:     x.Ref,
+		Before:  x.Before,
+		After:   x.After,
+		Commits: commits,
+		Forced:  x.Forced,
+	}
+}
+
+func intoBranchCreatedParams(x *github.CreatePayload) v1alpha1.BranchCreatedParams {
+	return v1alpha1.BranchCreatedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		Repo:   botModelFromRepoContainingPayload(x),
+		Branch: x.Ref,
+	}
+}
+
+func intoBranchDeletedParams(x *github.DeletePayload) v1alpha1.BranchDeletedParams {
+	return v1alpha1.BranchDeletedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		Repo:   botModelFromRepoContainingPayload(x),
+		Branch: x.Ref,
+	}
+}
+
+func intoTagCreatedParams(x *github.CreatePayload) v1alpha1.TagCreatedParams {
+	return v1alpha1.TagCreatedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Repo:  botModelFromRepoContainingPayload(x),
+		Tag:   x.Ref,
+	}
+}
+
+func intoReleasePublishedParams(x *github.ReleasePayload) v1alpha1.ReleasePublishedParams {
+	var name, notes string
+	if x.Release.Name != nil {
+		name = *x.Release.Name
+	}
+	if x.Release.Body != nil {
+		notes = *x.Release.Body
+	}
+
+	return v1alpha1.ReleasePublishedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Release: v1alpha1.Release{
+			Repo:         botModelFromRepoContainingPayload(x),
+			Tag:          x.Release.TagName,
+			Name:         name,
+			Notes:        notes,
+			IsPrerelease: x.Release.Prerelease,
+			Author: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Release.Author.Login,
+			},
+			URL:       x.Release.HTMLURL,
+			CreatedAt: x.Release.CreatedAt,
+		},
+	}
+}
+
+func intoDeploymentCreatedParams(x *github.DeploymentPayload) v1alpha1.DeploymentCreatedParams {
+	return v1alpha1.DeploymentCreatedParams{
+		Actor:      botModelFromSenderContainingPayload(x),
+		Deployment: botModelFromDeploymentContainingPayload(x),
+	}
+}
+
+func intoDeploymentStatusChangedParams(
+	x *github.DeploymentStatusPayload,
+) v1alpha1.DeploymentStatusChangedParams {
+	var desc, url string
+	if x.DeploymentStatus.Description != nil {
+		desc = *x.DeploymentStatus.Description
+	}
+	if x.DeploymentStatus.TargetURL != nil {
+		url = *x.DeploymentStatus.TargetURL
+	}
+
+	return v1alpha1.DeploymentStatusChangedParams{
+		Actor:       botModelFromSenderContainingPayload(x),
+		Deployment:  botModelFromDeploymentContainingPayload(x),
+		State:       deploymentStateFromDeploymentStatusState(x.DeploymentStatus.State),
+		Description: desc,
+		URL:         url,
+	}
+}
+
+func intoCIFinishedParams(x interface{}, state v1alpha1.CIState) v1alpha1.CIFinishedParams {
+	return v1alpha1.CIFinishedParams{
+		Run: v1alpha1.CIRun{
+			Repo:  botModelFromRepoContainingPayload(x),
+			State: state,
+			SHA:   headSHAFromCIPayload(x),
+			URL:   ciURLFromCIPayload(x),
+		},
+	}
+}
+
+func intoAppInstalledParams(x *github.InstallationPayload) v1alpha1.AppInstalledParams {
+	repos := make([]v1alpha1.Repo, len(x.Repositories))
+	for i := range x.Repositories {
+		repos[i] = botModelFromRepoFullName(x.Repositories[i].FullName)
+	}
+
+	return v1alpha1.AppInstalledParams{
+		Actor:        botModelFromSenderContainingPayload(x),
+		Installation: botModelFromInstallationContainingPayload(x),
+		Repos:        repos,
+	}
+}
+
+func intoAppUninstalledParams(x *github.InstallationPayload) v1alpha1.AppUninstalledParams {
+	return v1alpha1.AppUninstalledParams{
+		Actor:        botModelFromSenderContainingPayload(x),
+		Installation: botModelFromInstallationContainingPayload(x),
+	}
+}
+
+func intoAppReposAddedParams(x *github.InstallationRepositoriesPayload) v1alpha1.AppReposAddedParams {
+	repos := make([]v1alpha1.Repo, len(x.RepositoriesAdded))
+	for i := range x.RepositoriesAdded {
+		repos[i] = botModelFromRepoFullName(x.RepositoriesAdded[i].FullName)
+	}
+
+	return v1alpha1.AppReposAddedParams{
+		Actor:        botModelFromSenderContainingPayload(x),
+		Installation: botModelFromInstallationContainingPayload(x),
+		Repos:        repos,
+	}
+}
+
+func intoAppReposRemovedParams(x *github.InstallationRepositoriesPayload) v1alpha1.AppReposRemovedParams {
+	repos := make([]v1alpha1.Repo, len(x.RepositoriesRemoved))
+	for i := range x.RepositoriesRemoved {
+		repos[i] = botModelFromRepoFullName(x.RepositoriesRemoved[i].FullName)
+	}
+
+	return v1alpha1.AppReposRemovedParams{
+		Actor:        botModelFromSenderContainingPayload(x),
+		Installation: botModelFromInstallationContainingPayload(x),
+		Repos:        repos,
+	}
+}
+
 // Adapters for component fields
 
 func botModelFromSenderContainingPayload(x interface{}) v1alpha1.ForgeUser {
@@ -54,11 +344,121 @@ func botModelFromSenderContainingPayload(x interface{}) v1alpha1.ForgeUser {
 			UserName: x.Sender.Login,
 		}
 
+	case *github.PullRequestReviewPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.IssuesPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.IssueCommentPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.PullRequestReviewCommentPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.PushPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.CreatePayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.DeletePayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.ReleasePayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.DeploymentPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.DeploymentStatusPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.InstallationPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
+	case *github.InstallationRepositoriesPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.Sender.Login,
+		}
+
 	default:
 		panic("should never happen")
 	}
 }
 
+func botModelFromInstallationContainingPayload(x interface{}) v1alpha1.AppInstallation {
+	switch x := x.(type) {
+	case *github.InstallationPayload:
+		return v1alpha1.AppInstallation{
+			ID: x.Installation.ID,
+			Account: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Installation.Account.Login,
+			},
+		}
+
+	case *github.InstallationRepositoriesPayload:
+		return v1alpha1.AppInstallation{
+			ID: x.Installation.ID,
+			Account: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Installation.Account.Login,
+			},
+		}
+
+	default:
+		panic("should never happen")
+	}
+}
+
+// botModelFromRepoFullName converts repo full names like "owner/name", for
+// payloads only listing repos by name.
+func botModelFromRepoFullName(fullName string) v1alpha1.Repo {
+	owner, name, _ := strings.Cut(fullName, "/")
+	return v1alpha1.Repo{
+		User: v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: owner,
+		},
+		RepoName: name,
+	}
+}
+
 func botModelFromRepoContainingPayload(x interface{}) v1alpha1.Repo {
 	switch x := x.(type) {
 	case *github.PingPayload:
@@ -79,6 +479,114 @@ func botModelFromRepoContainingPayload(x interface{}) v1alpha1.Repo {
 			RepoName: x.Repository.Name,
 		}
 
+	case *github.PullRequestReviewPayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.IssuesPayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.IssueCommentPayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.PullRequestReviewCommentPayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.PushPayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.CreatePayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.DeletePayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.ReleasePayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.DeploymentPayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.DeploymentStatusPayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.CheckSuitePayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
+	case *github.StatusPayload:
+		return v1alpha1.Repo{
+			User: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Repository.Owner.Login,
+			},
+			RepoName: x.Repository.Name,
+		}
+
 	default:
 		panic("should never happen")
 	}
@@ -87,6 +595,19 @@ func botModelFromRepoContainingPayload(x interface{}) v1alpha1.Repo {
 func botModelFromPRContainingPayload(x interface{}) v1alpha1.PR {
 	switch x := x.(type) {
 	case *github.PullRequestPayload:
+		labels := make([]string, len(x.PullRequest.Labels))
+		for i, l := range x.PullRequest.Labels {
+			labels[i] = l.Name
+		}
+
+		reviewers := make([]v1alpha1.ForgeUser, len(x.PullRequest.RequestedReviewers))
+		for i, u := range x.PullRequest.RequestedReviewers {
+			reviewers[i] = v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: u.Login,
+			}
+		}
+
 		return v1alpha1.PR{
 			Repo:   botModelFromRepoContainingPayload(x),
 			Number: int(x.PullRequest.Number),
@@ -97,6 +618,22 @@ func botModelFromPRContainingPayload(x interface{}) v1alpha1.PR {
 			},
 			State: issueStateFromPRState(x.PullRequest.State, x.PullRequest.Merged),
 			URL:   x.PullRequest.HTMLURL,
+			Body:  x.PullRequest.Body,
+
+			BaseBranch: x.PullRequest.Base.Ref,
+			HeadBranch: x.PullRequest.Head.Ref,
+			HeadSHA:    x.PullRequest.Head.Sha,
+			IsDraft:    x.PullRequest.Draft,
+
+			Labels:             labels,
+			Assignees:          botModelFromAssignees(x.PullRequest.Assignees),
+			RequestedReviewers: reviewers,
+
+			CreatedAt: x.PullRequest.CreatedAt,
+			UpdatedAt: x.PullRequest.UpdatedAt,
+
+			Additions: int(x.PullRequest.Additions),
+			Deletions: int(x.PullRequest.Deletions),
 		}
 
 	case *github.PullRequestReviewPayload:
@@ -108,8 +645,113 @@ func botModelFromPRContainingPayload(x interface{}) v1alpha1.PR {
 				Forge:    forgeType,
 				UserName: x.PullRequest.User.Login,
 			},
-			State: issueStateFromPRState(x.PullRequest.State, false), // no "merged" field
+			State: issueStateFromPRState(x.PullRequest.State, x.PullRequest.MergedAt != nil),
 			URL:   x.PullRequest.HTMLURL,
+			Body:  x.PullRequest.Body,
+
+			// the review payload carries no draft status, labels, reviewers
+			// or line counts
+			BaseBranch: x.PullRequest.Base.Ref,
+			HeadBranch: x.PullRequest.Head.Ref,
+			HeadSHA:    x.PullRequest.Head.Sha,
+
+			Assignees: botModelFromAssigneeValues(x.PullRequest.Assignees),
+
+			CreatedAt: x.PullRequest.CreatedAt,
+			UpdatedAt: x.PullRequest.UpdatedAt,
+		}
+
+	case *github.IssueCommentPayload:
+		// Only the issue side of the PR is available.
+		issue := botModelFromIssueContainingPayload(x)
+		return v1alpha1.PR{
+			Repo:      issue.Repo,
+			Number:    issue.Number,
+			Title:     issue.Title,
+			Author:    issue.Author,
+			State:     issue.State,
+			URL:       issue.URL,
+			Body:      issue.Body,
+			Labels:    issue.Labels,
+			Assignees: issue.Assignees,
+			CreatedAt: issue.CreatedAt,
+			UpdatedAt: issue.UpdatedAt,
+		}
+
+	case *github.PullRequestReviewCommentPayload:
+		return v1alpha1.PR{
+			Repo:   botModelFromRepoContainingPayload(x),
+			Number: int(x.PullRequest.Number),
+			Title:  x.PullRequest.Title,
+			Author: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.PullRequest.User.Login,
+			},
+			State: issueStateFromPRState(x.PullRequest.State, x.PullRequest.MergedAt != nil),
+			URL:   x.PullRequest.HTMLURL,
+			Body:  x.PullRequest.Body,
+
+			BaseBranch: x.PullRequest.Base.Ref,
+			HeadBranch: x.PullRequest.Head.Ref,
+			HeadSHA:    x.PullRequest.Head.Sha,
+
+			Assignees: botModelFromAssignees(x.PullRequest.Assignees),
+
+			CreatedAt: x.PullRequest.CreatedAt,
+			UpdatedAt: x.PullRequest.UpdatedAt,
+		}
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromIssueContainingPayload(x interface{}) v1alpha1.Issue {
+	switch x := x.(type) {
+	case *github.IssuesPayload:
+		labels := make([]string, len(x.Issue.Labels))
+		for i, l := range x.Issue.Labels {
+			labels[i] = l.Name
+		}
+
+		return v1alpha1.Issue{
+			Repo:   botModelFromRepoContainingPayload(x),
+			Number: int(x.Issue.Number),
+			Title:  x.Issue.Title,
+			Author: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Issue.User.Login,
+			},
+			State:     issueStateFromPRState(x.Issue.State, false),
+			URL:       x.Issue.HTMLURL,
+			Body:      x.Issue.Body,
+			Labels:    labels,
+			Assignees: botModelFromAssignees(x.Issue.Assignees),
+			CreatedAt: x.Issue.CreatedAt,
+			UpdatedAt: x.Issue.UpdatedAt,
+		}
+
+	case *github.IssueCommentPayload:
+		labels := make([]string, len(x.Issue.Labels))
+		for i, l := range x.Issue.Labels {
+			labels[i] = l.Name
+		}
+
+		return v1alpha1.Issue{
+			Repo:   botModelFromRepoContainingPayload(x),
+			Number: int(x.Issue.Number),
+			Title:  x.Issue.Title,
+			Author: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Issue.User.Login,
+			},
+			State:     issueStateFromPRState(x.Issue.State, false),
+			URL:       x.Issue.HTMLURL,
+			Body:      x.Issue.Body,
+			Labels:    labels,
+			Assignees: botModelFromAssignees(x.Issue.Assignees),
+			CreatedAt: x.Issue.CreatedAt,
+			UpdatedAt: x.Issue.UpdatedAt,
 		}
 
 	default:
@@ -117,6 +759,237 @@ func botModelFromPRContainingPayload(x interface{}) v1alpha1.PR {
 	}
 }
 
+func botModelFromCommentContainingPayload(x interface{}, extras *reviewCommentExtras) v1alpha1.Comment {
+	switch x := x.(type) {
+	case *github.IssueCommentPayload:
+		return v1alpha1.Comment{
+			Author: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Comment.User.Login,
+			},
+			Body:      x.Comment.Body,
+			URL:       x.Comment.HTMLURL,
+			CreatedAt: x.Comment.CreatedAt,
+		}
+
+	case *github.PullRequestReviewCommentPayload:
+		return v1alpha1.Comment{
+			Author: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Comment.User.Login,
+			},
+			Body:      x.Comment.Body,
+			URL:       x.Comment.HTMLURL,
+			CreatedAt: x.Comment.CreatedAt,
+			Path:      x.Comment.Path,
+			Line:      extras.line(),
+		}
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromDeploymentContainingPayload(x interface{}) v1alpha1.Deployment {
+	switch x := x.(type) {
+	case *github.DeploymentPayload:
+		return v1alpha1.Deployment{
+			Repo:        botModelFromRepoContainingPayload(x),
+			ID:          x.Deployment.ID,
+			Environment: x.Deployment.Environment,
+			Ref:         x.Deployment.Ref,
+			SHA:         x.Deployment.Sha,
+			Creator: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Deployment.Creator.Login,
+			},
+			// deployments have no page of their own
+			URL: x.Repository.HTMLURL + "/deployments",
+		}
+
+	case *github.DeploymentStatusPayload:
+		return v1alpha1.Deployment{
+			Repo:        botModelFromRepoContainingPayload(x),
+			ID:          x.Deployment.ID,
+			Environment: x.Deployment.Environment,
+			Ref:         x.Deployment.Ref,
+			SHA:         x.Deployment.Sha,
+			Creator: v1alpha1.ForgeUser{
+				Forge:    forgeType,
+				UserName: x.Deployment.Creator.Login,
+			},
+			URL: x.Repository.HTMLURL + "/deployments",
+		}
+
+	default:
+		panic("should never happen")
+	}
+}
+
+// botModelFromOpenAPIPRs returns the open PRs among the given ones.
+func botModelFromOpenAPIPRs(repo v1alpha1.Repo, prs []apiPullRequest) []v1alpha1.PR {
+	var result []v1alpha1.PR
+	for i := range prs {
+		if prs[i].State == "open" {
+			result = append(result, botModelFromAPIPR(repo, &prs[i]))
+		}
+	}
+	return result
+}
+
+func botModelFromAPIPR(repo v1alpha1.Repo, pr *apiPullRequest) v1alpha1.PR {
+	labels := make([]string, len(pr.Labels))
+	for i, l := range pr.Labels {
+		labels[i] = l.Name
+	}
+
+	return v1alpha1.PR{
+		Repo:   repo,
+		Number: pr.Number,
+		Title:  pr.Title,
+		Author: v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: pr.User.Login,
+		},
+		State: issueStateFromPRState(pr.State, pr.MergedAt != nil),
+		URL:   pr.HTMLURL,
+		Body:  pr.Body,
+
+		BaseBranch: pr.Base.Ref,
+		HeadBranch: pr.Head.Ref,
+		HeadSHA:    pr.Head.SHA,
+		IsDraft:    pr.Draft,
+
+		Labels:             labels,
+		Assignees:          botModelFromAPIUsers(pr.Assignees),
+		RequestedReviewers: botModelFromAPIUsers(pr.RequestedReviewers),
+
+		CreatedAt: pr.CreatedAt,
+		UpdatedAt: pr.UpdatedAt,
+
+		// only present when fetching single PRs
+		Additions: pr.Additions,
+		Deletions: pr.Deletions,
+	}
+}
+
+func botModelFromAPIReview(x *apiReview) v1alpha1.Review {
+	return v1alpha1.Review{
+		Author: v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.User.Login,
+		},
+		Type:        reviewTypeFromReviewState(x.State),
+		Body:        x.Body,
+		URL:         x.HTMLURL,
+		SubmittedAt: x.SubmittedAt,
+	}
+}
+
+func botModelFromAPICheckRun(x *apiCheckRun) v1alpha1.Check {
+	state := v1alpha1.CIStatePending
+	if x.Status == "completed" {
+		state = ciStateFromCheckSuiteConclusion(x.Conclusion)
+	}
+
+	return v1alpha1.Check{
+		Name:  x.Name,
+		State: state,
+		URL:   x.HTMLURL,
+	}
+}
+
+func botModelFromAPIStatus(x *apiStatus) v1alpha1.Check {
+	return v1alpha1.Check{
+		Name:  x.Context,
+		State: ciStateFromCombinedStatusState(x.State),
+		URL:   x.TargetURL,
+	}
+}
+
+// requestedReviewerFromPRPayload returns the user or team a review request
+// action is about. GitHub sends one action per reviewer, so at most one of the
+// results is non-empty.
+func requestedReviewerFromPRPayload(x *github.PullRequestPayload) ([]v1alpha1.ForgeUser, []string) {
+	if x.RequestedReviewer != nil {
+		return []v1alpha1.ForgeUser{
+			{
+				Forge:    forgeType,
+				UserName: x.RequestedReviewer.Login,
+			},
+		}, nil
+	}
+
+	if x.RequestedTeam.Name != "" {
+		return nil, []string{x.RequestedTeam.Name}
+	}
+
+	return nil, nil
+}
+
+func botModelFromAssignees(x []*github.Assignee) []v1alpha1.ForgeUser {
+	result := make([]v1alpha1.ForgeUser, 0, len(x))
+	for _, u := range x {
+		if u == nil {
+			continue
+		}
+		result = append(result, v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: u.Login,
+		})
+	}
+	return result
+}
+
+func botModelFromAssigneeValues(x []github.Assignee) []v1alpha1.ForgeUser {
+	result := make([]v1alpha1.ForgeUser, len(x))
+	for i, u := range x {
+		result[i] = v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: u.Login,
+		}
+	}
+	return result
+}
+
+func botModelFromAPIUsers(x []apiUser) []v1alpha1.ForgeUser {
+	result := make([]v1alpha1.ForgeUser, len(x))
+	for i, u := range x {
+		result[i] = v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: u.Login,
+		}
+	}
+	return result
+}
+
+func headSHAFromCIPayload(x interface{}) string {
+	switch x := x.(type) {
+	case *github.CheckSuitePayload:
+		return x.CheckSuite.HeadSHA
+
+	case *github.StatusPayload:
+		return x.Sha
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func ciURLFromCIPayload(x interface{}) string {
+	switch x := x.(type) {
+	case *github.CheckSuitePayload:
+		// The check suite only carries an API URL, point to the web UI instead.
+		return x.Repository.HTMLURL + "/commit/" + x.CheckSuite.HeadSHA + "/checks"
+
+	case *github.StatusPayload:
+		return x.Commit.HTMLURL
+
+	default:
+		panic("should never happen")
+	}
+}
+
 func issueStateFromPRState(state string, merged bool) v1alpha1.IssueState {
 	switch state {
 	case "open":
@@ -131,7 +1004,6 @@ func issueStateFromPRState(state string, merged bool) v1alpha1.IssueState {
 	}
 }
 
-//nolint:deadcode,unused // used by upcoming logic
 func isDraftPRFromPRContainingPayload(x interface{}) bool {
 	switch x := x.(type) {
 	case *github.PullRequestPayload:
@@ -141,3 +1013,88 @@ func isDraftPRFromPRContainingPayload(x interface{}) bool {
 		panic("should never happen")
 	}
 }
+
+func reviewTypeFromReviewState(state string) v1alpha1.ReviewType {
+	// The REST API uses upper case for review states, while webhook payloads
+	// use lower case.
+	switch strings.ToLower(state) {
+	case "commented":
+		return v1alpha1.ReviewTypeComment
+	case "approved":
+		return v1alpha1.ReviewTypeApprove
+	case "changes_requested":
+		return v1alpha1.ReviewTypeRequestChanges
+	case "dismissed":
+		return v1alpha1.ReviewTypeDismiss
+	default:
+		return v1alpha1.ReviewTypeUnknown
+	}
+}
+
+func deploymentStateFromDeploymentStatusState(state string) v1alpha1.DeploymentState {
+	switch state {
+	case "pending", "queued":
+		return v1alpha1.DeploymentStatePending
+	case "in_progress":
+		return v1alpha1.DeploymentStateInProgress
+	case "success":
+		return v1alpha1.DeploymentStateSuccess
+	case "failure":
+		return v1alpha1.DeploymentStateFailure
+	case "error":
+		return v1alpha1.DeploymentStateError
+	case "inactive":
+		return v1alpha1.DeploymentStateInactive
+	default:
+		return v1alpha1.DeploymentStateUnknown
+	}
+}
+
+func ciStateFromCheckSuiteConclusion(conclusion string) v1alpha1.CIState {
+	switch conclusion {
+	case "success", "neutral":
+		return v1alpha1.CIStatePassed
+	case "failure":
+		return v1alpha1.CIStateFailed
+	case "timed_out":
+		return v1alpha1.CIStateErrored
+	case "cancelled":
+		return v1alpha1.CIStateCanceled
+	case "skipped":
+		return v1alpha1.CIStateSkipped
+	case "action_required":
+		return v1alpha1.CIStateActionRequired
+	default:
+		return v1alpha1.CIStateUnknown
+	}
+}
+
+func ciStateFromCombinedStatusState(state string) v1alpha1.CIState {
+	switch state {
+	case "success":
+		return v1alpha1.CIStatePassed
+	case "failure":
+		return v1alpha1.CIStateFailed
+	case "error":
+		return v1alpha1.CIStateErrored
+	case "pending":
+		return v1alpha1.CIStatePending
+	default:
+		return v1alpha1.CIStateUnknown
+	}
+}
+
+// commitStatusStateFromCIState maps CI states to the ones accepted by the
+// commit status API.
+func commitStatusStateFromCIState(state v1alpha1.CIState) string {
+	switch state {
+	case v1alpha1.CIStatePassed, v1alpha1.CIStateSkipped:
+		return "success"
+	case v1alpha1.CIStateFailed:
+		return "failure"
+	case v1alpha1.CIStateErrored, v1alpha1.CIStateCanceled:
+		return "error"
+	default:
+		return "pending"
+	}
+}
@@ -0,0 +1,319 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const defaultAPIBaseURL = "https://api.github.com"
+
+// apiClient is a minimal GitHub REST API client, for looking up information
+// not included in webhook payloads.
+type apiClient struct {
+	httpClient *http.Client
+	baseURL    string
+	tokens     tokenSource
+}
+
+func newAPIClient(tokens tokenSource) *apiClient {
+	return &apiClient{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    defaultAPIBaseURL,
+		tokens:     tokens,
+	}
+}
+
+type apiUser struct {
+	Login string `json:"login"`
+}
+
+type apiLabel struct {
+	Name string `json:"name"`
+}
+
+type apiBranch struct {
+	Ref string `json:"ref"`
+	SHA string `json:"sha"`
+}
+
+type apiPullRequest struct {
+	Number             int        `json:"number"`
+	State              string     `json:"state"`
+	Title              string     `json:"title"`
+	Body               string     `json:"body"`
+	User               apiUser    `json:"user"`
+	HTMLURL            string     `json:"html_url"`
+	Draft              bool       `json:"draft"`
+	Base               apiBranch  `json:"base"`
+	Head               apiBranch  `json:"head"`
+	Labels             []apiLabel `json:"labels"`
+	Assignees          []apiUser  `json:"assignees"`
+	RequestedReviewers []apiUser  `json:"requested_reviewers"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	MergedAt           *time.Time `json:"merged_at"`
+	Additions          int        `json:"additions"`
+	Deletions          int        `json:"deletions"`
+}
+
+type apiCombinedStatus struct {
+	State    string      `json:"state"`
+	SHA      string      `json:"sha"`
+	Statuses []apiStatus `json:"statuses"`
+}
+
+type apiStatus struct {
+	State       string `json:"state"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+	Context     string `json:"context"`
+}
+
+type apiReview struct {
+	User        apiUser   `json:"user"`
+	State       string    `json:"state"`
+	Body        string    `json:"body"`
+	HTMLURL     string    `json:"html_url"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+type apiCheckRun struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Conclusion string `json:"conclusion"`
+	HTMLURL    string `json:"html_url"`
+}
+
+type apiCheckRunList struct {
+	CheckRuns []apiCheckRun `json:"check_runs"`
+}
+
+// listPRsForCommit returns the PRs associated with the given commit.
+func (c *apiClient) listPRsForCommit(
+	ctx context.Context,
+	owner string,
+	repo string,
+	sha string,
+) ([]apiPullRequest, error) {
+	var result []apiPullRequest
+	err := c.get(
+		ctx,
+		owner,
+		repo,
+		fmt.Sprintf(
+			"/commits/%s/pulls",
+			url.PathEscape(sha),
+		),
+		&result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getCombinedStatus returns the combined commit status of the given ref.
+func (c *apiClient) getCombinedStatus(
+	ctx context.Context,
+	owner string,
+	repo string,
+	ref string,
+) (*apiCombinedStatus, error) {
+	var result apiCombinedStatus
+	err := c.get(
+		ctx,
+		owner,
+		repo,
+		fmt.Sprintf(
+			"/commits/%s/status",
+			url.PathEscape(ref),
+		),
+		&result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// getPR returns the PR of the given number.
+func (c *apiClient) getPR(ctx context.Context, owner string, repo string, number int) (*apiPullRequest, error) {
+	var result apiPullRequest
+	err := c.get(ctx, owner, repo, fmt.Sprintf("/pulls/%d", number), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// listReviews returns the submitted reviews of the given PR.
+func (c *apiClient) listReviews(ctx context.Context, owner string, repo string, number int) ([]apiReview, error) {
+	var result []apiReview
+	err := c.get(ctx, owner, repo, fmt.Sprintf("/pulls/%d/reviews?per_page=100", number), &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// listCheckRuns returns the check runs of the given ref.
+func (c *apiClient) listCheckRuns(ctx context.Context, owner string, repo string, ref string) ([]apiCheckRun, error) {
+	var result apiCheckRunList
+	err := c.get(
+		ctx,
+		owner,
+		repo,
+		fmt.Sprintf("/commits/%s/check-runs?per_page=100", url.PathEscape(ref)),
+		&result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result.CheckRuns, nil
+}
+
+// createComment comments on the given issue or PR, which share the same
+// numbering.
+func (c *apiClient) createComment(ctx context.Context, owner string, repo string, number int, body string) error {
+	in := struct {
+		Body string `json:"body"`
+	}{body}
+	return c.post(ctx, owner, repo, fmt.Sprintf("/issues/%d/comments", number), &in, nil)
+}
+
+func (c *apiClient) addLabels(ctx context.Context, owner string, repo string, number int, labels []string) error {
+	in := struct {
+		Labels []string `json:"labels"`
+	}{labels}
+	return c.post(ctx, owner, repo, fmt.Sprintf("/issues/%d/labels", number), &in, nil)
+}
+
+func (c *apiClient) removeLabel(ctx context.Context, owner string, repo string, number int, label string) error {
+	return c.delete(ctx, owner, repo, fmt.Sprintf("/issues/%d/labels/%s", number, url.PathEscape(label)))
+}
+
+func (c *apiClient) requestReviewers(
+	ctx context.Context,
+	owner string,
+	repo string,
+	number int,
+	reviewers []string,
+) error {
+	in := struct {
+		Reviewers []string `json:"reviewers"`
+	}{reviewers}
+	return c.post(ctx, owner, repo, fmt.Sprintf("/pulls/%d/requested_reviewers", number), &in, nil)
+}
+
+func (c *apiClient) createStatus(ctx context.Context, owner string, repo string, sha string, status *apiStatus) error {
+	return c.post(ctx, owner, repo, fmt.Sprintf("/statuses/%s", url.PathEscape(sha)), status, nil)
+}
+
+func (c *apiClient) get(ctx context.Context, owner string, repo string, path string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, owner, repo, path, nil, out)
+}
+
+func (c *apiClient) post(
+	ctx context.Context,
+	owner string,
+	repo string,
+	path string,
+	in interface{},
+	out interface{},
+) error {
+	return c.do(ctx, http.MethodPost, owner, repo, path, in, out)
+}
+
+func (c *apiClient) delete(ctx context.Context, owner string, repo string, path string) error {
+	return c.do(ctx, http.MethodDelete, owner, repo, path, nil, nil)
+}
+
+// do sends an API request for the path under the given repo, with in as the
+// JSON body if non-nil, decoding the response into out if non-nil.
+func (c *apiClient) do(
+	ctx context.Context,
+	method string,
+	owner string,
+	repo string,
+	path string,
+	in interface{},
+	out interface{},
+) error {
+	token, err := c.tokens.token(ctx, owner, repo)
+	if err != nil {
+		return err
+	}
+
+	path = "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo) + path
+	return doAPIRequest(ctx, c.httpClient, method, c.baseURL+path, token, in, out)
+}
+
+// doAPIRequest sends an API request authenticated with the bearer token if
+// non-empty, with in as the JSON body if non-nil, decoding the response into
+// out if non-nil.
+func doAPIRequest(
+	ctx context.Context,
+	httpClient *http.Client,
+	method string,
+	endpoint string,
+	token string,
+	in interface{},
+	out interface{},
+) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &apiError{StatusCode: resp.StatusCode, Method: method, Path: req.URL.Path}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// apiError is returned for unsuccessful API responses.
+type apiError struct {
+	StatusCode int
+	Method     string
+	Path       string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("GitHub API returned status %d for %s %s", e.StatusCode, e.Method, e.Path)
+}
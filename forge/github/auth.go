@@ -0,0 +1,404 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidPrivateKey = errors.New("github: invalid private key, expecting a PEM-encoded RSA key")
+	ErrAuthConflict      = errors.New("github: token and app credentials are mutually exclusive")
+)
+
+// Auth is the credentials for GitHub API calls.
+//
+// Either a token, or the App ID and private key of a GitHub App may be given.
+// The zero value means anonymous access, which only works for public repos.
+type Auth struct {
+	Token string
+
+	AppID int64
+	// PrivateKey is the PEM-encoded private key of the app, as downloaded
+	// from GitHub.
+	PrivateKey []byte
+}
+
+func (a *Auth) isEmpty() bool {
+	return a.Token == "" && a.AppID == 0
+}
+
+// Credentials is the source of tokens for GitHub API calls, to be shared by
+// the forge hook and the client, so that cached installation tokens are
+// dropped for both when an app is uninstalled.
+type Credentials struct {
+	auth   Auth
+	tokens tokenSource
+}
+
+// NewCredentials returns the credentials for GitHub API calls.
+func NewCredentials(auth Auth) (*Credentials, error) {
+	tokens, err := newTokenSource(auth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Credentials{
+		auth:   auth,
+		tokens: tokens,
+	}, nil
+}
+
+// appTokens returns the token source if running as a GitHub App, or nil
+// otherwise.
+func (c *Credentials) appTokens() *appTokenSource {
+	app, _ := c.tokens.(*appTokenSource)
+	return app
+}
+
+// tokenSource provides tokens for API calls on repos.
+type tokenSource interface {
+	// token returns the token for API calls on the given repo, or the empty
+	// string for anonymous access.
+	token(ctx context.Context, owner string, repo string) (string, error)
+}
+
+// newTokenSource returns the token source according to the credentials given.
+func newTokenSource(auth Auth) (tokenSource, error) {
+	if auth.AppID == 0 {
+		return staticToken(auth.Token), nil
+	}
+
+	if auth.Token != "" {
+		return nil, ErrAuthConflict
+	}
+
+	key, err := parsePrivateKey(auth.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAppTokenSource(auth.AppID, key), nil
+}
+
+// staticToken is a personal access token or the like, used for all repos.
+type staticToken string
+
+func (t staticToken) token(context.Context, string, string) (string, error) {
+	return string(t), nil
+}
+
+// Installation tokens are valid for an hour, refresh them a while before they
+// expire so a token is never used near its expiry.
+const installationTokenRefreshMargin = 5 * time.Minute
+
+// lookupTimeout bounds each API lookup of installations and tokens.
+const lookupTimeout = 30 * time.Second
+
+type installationToken struct {
+	token     string
+	expiresAt time.Time
+}
+
+// appTokenSource provides installation tokens of a GitHub App, caching both
+// the installation of each repo and the token of each installation.
+//
+// The lock is never held during API calls; concurrent lookups of the same
+// thing are coalesced instead.
+type appTokenSource struct {
+	httpClient *http.Client
+	baseURL    string
+	appID      int64
+	key        *rsa.PrivateKey
+
+	mu sync.Mutex
+	// installations maps "owner/repo" to installation IDs
+	installations map[string]int64
+	tokens        map[int64]installationToken
+	// generation is bumped on every forget, so lookups started before are
+	// not cached.
+	generation uint64
+	// inflight maps keys of ongoing lookups to them
+	inflight map[string]*lookup
+}
+
+var _ tokenSource = (*appTokenSource)(nil)
+
+// lookup is an ongoing API lookup shared by concurrent callers.
+type lookup struct {
+	done chan struct{}
+	// set before done is closed
+	installationID int64
+	token          installationToken
+	err            error
+}
+
+func newAppTokenSource(appID int64, key *rsa.PrivateKey) *appTokenSource {
+	return &appTokenSource{
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		baseURL:       defaultAPIBaseURL,
+		appID:         appID,
+		key:           key,
+		installations: make(map[string]int64),
+		tokens:        make(map[int64]installationToken),
+		inflight:      make(map[string]*lookup),
+	}
+}
+
+func (s *appTokenSource) token(ctx context.Context, owner string, repo string) (string, error) {
+	id, err := s.installationOf(ctx, owner, repo)
+	if err != nil {
+		return "", err
+	}
+
+	tok, err := s.installationToken(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	return tok.token, nil
+}
+
+// installationOf returns the ID of the installation covering the repo.
+func (s *appTokenSource) installationOf(ctx context.Context, owner string, repo string) (int64, error) {
+	key := owner + "/" + repo
+
+	s.mu.Lock()
+	if id, ok := s.installations[key]; ok {
+		s.mu.Unlock()
+		return id, nil
+	}
+
+	l, err := s.startLookupLocked(ctx, "repo:"+key, func(ctx context.Context, l *lookup) {
+		l.installationID, l.err = s.findInstallation(ctx, owner, repo)
+	}, func(l *lookup) {
+		s.installations[key] = l.installationID
+	})
+	if err != nil {
+		return 0, err
+	}
+	return l.installationID, l.err
+}
+
+// installationToken returns a token of the installation not about to expire.
+func (s *appTokenSource) installationToken(ctx context.Context, id int64) (installationToken, error) {
+	s.mu.Lock()
+	if tok, ok := s.tokens[id]; ok && time.Now().Add(installationTokenRefreshMargin).Before(tok.expiresAt) {
+		s.mu.Unlock()
+		return tok, nil
+	}
+
+	l, err := s.startLookupLocked(ctx, "installation:"+strconv.FormatInt(id, 10), func(ctx context.Context, l *lookup) {
+		l.token, l.err = s.createInstallationToken(ctx, id)
+	}, func(l *lookup) {
+		s.tokens[id] = l.token
+	})
+	if err != nil {
+		return installationToken{}, err
+	}
+	return l.token, l.err
+}
+
+// startLookupLocked joins the ongoing lookup of the key, or starts one with
+// fetch, and waits for it, unlocking s.mu in either case.
+//
+// The error returned is that of ctx if it is done before the lookup; errors
+// of the lookup itself are in the lookup.
+func (s *appTokenSource) startLookupLocked(
+	ctx context.Context,
+	key string,
+	fetch func(ctx context.Context, l *lookup),
+	store func(l *lookup),
+) (*lookup, error) {
+	l, ok := s.inflight[key]
+	if !ok {
+		l = &lookup{done: make(chan struct{})}
+		s.inflight[key] = l
+		go s.runLookup(key, l, s.generation, fetch, store)
+	}
+	s.mu.Unlock()
+
+	select {
+	case <-l.done:
+		return l, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// runLookup runs the lookup with fetch, storing successful results with store
+// under the lock, unless something is forgotten since the given generation.
+//
+// The lookup is shared by all callers, so it is not canceled along with any
+// of them, the one starting it included, but has a timeout of its own.
+func (s *appTokenSource) runLookup(
+	key string,
+	l *lookup,
+	generation uint64,
+	fetch func(ctx context.Context, l *lookup),
+	store func(l *lookup),
+) {
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+
+	fetch(ctx, l)
+
+	s.mu.Lock()
+	delete(s.inflight, key)
+	if l.err == nil && s.generation == generation {
+		store(l)
+	}
+	s.mu.Unlock()
+	close(l.done)
+}
+
+// forgetInstallation drops everything cached for the installation, e.g. when
+// it is removed.
+func (s *appTokenSource) forgetInstallation(id int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	delete(s.tokens, id)
+	for k, v := range s.installations {
+		if v == id {
+			delete(s.installations, k)
+		}
+	}
+}
+
+// forgetRepo drops the cached installation of the repo, e.g. when it is
+// removed from the installation.
+func (s *appTokenSource) forgetRepo(owner string, repo string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	delete(s.installations, owner+"/"+repo)
+}
+
+func (s *appTokenSource) findInstallation(ctx context.Context, owner string, repo string) (int64, error) {
+	jwt, err := s.makeJWT(time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		ID int64 `json:"id"`
+	}
+	err = doAPIRequest(
+		ctx,
+		s.httpClient,
+		http.MethodGet,
+		s.baseURL+"/repos/"+url.PathEscape(owner)+"/"+url.PathEscape(repo)+"/installation",
+		jwt,
+		nil,
+		&result,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.ID, nil
+}
+
+func (s *appTokenSource) createInstallationToken(ctx context.Context, id int64) (installationToken, error) {
+	jwt, err := s.makeJWT(time.Now())
+	if err != nil {
+		return installationToken{}, err
+	}
+
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	err = doAPIRequest(
+		ctx,
+		s.httpClient,
+		http.MethodPost,
+		fmt.Sprintf("%s/app/installations/%d/access_tokens", s.baseURL, id),
+		jwt,
+		nil,
+		&result,
+	)
+	if err != nil {
+		return installationToken{}, err
+	}
+
+	return installationToken{
+		token:     result.Token,
+		expiresAt: result.ExpiresAt,
+	}, nil
+}
+
+// makeJWT returns the RS256-signed JWT for authenticating as the app itself.
+func (s *appTokenSource) makeJWT(now time.Time) (string, error) {
+	header := map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	}
+	claims := map[string]interface{}{
+		// backdated to allow for clock drift, as recommended by GitHub
+		"iat": now.Add(-1 * time.Minute).Unix(),
+		// at most 10 minutes
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": strconv.FormatInt(s.appID, 10),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(headerJSON) +
+		"." + base64.RawURLEncoding.EncodeToString(claimsJSON)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig), nil
+}
+
+// parsePrivateKey parses the PEM-encoded RSA private key, in either PKCS #1
+// (what GitHub gives out) or PKCS #8 form.
+func parsePrivateKey(pemBytes []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemBytes)
+	if block == nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrInvalidPrivateKey
+	}
+	return rsaKey, nil
+}
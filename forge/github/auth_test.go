@@ -0,0 +1,345 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testAppID = 1234
+
+var (
+	testKeyOnce sync.Once
+	testKey     *rsa.PrivateKey
+)
+
+func getTestKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	testKeyOnce.Do(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testKey = key
+	})
+	return testKey
+}
+
+func encodePEM(typ string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+func TestParsePrivateKey(t *testing.T) {
+	key := getTestKey(t)
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPKCS8, err := x509.MarshalPKCS8PrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testcases := []struct {
+		name  string
+		input []byte
+		ok    bool
+	}{
+		{"PKCS #1", encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key)), true},
+		{"PKCS #8", encodePEM("PRIVATE KEY", pkcs8), true},
+		{"not PEM", []byte("not a key"), false},
+		{"garbage", encodePEM("PRIVATE KEY", []byte("garbage")), false},
+		{"not RSA", encodePEM("PRIVATE KEY", ecPKCS8), false},
+	}
+
+	for _, tc := range testcases {
+		got, err := parsePrivateKey(tc.input)
+		if !tc.ok {
+			if !errors.Is(err, ErrInvalidPrivateKey) {
+				t.Errorf("%s: want %v, got %v", tc.name, ErrInvalidPrivateKey, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if !got.Equal(key) {
+			t.Errorf("%s: got a different key", tc.name)
+		}
+	}
+}
+
+func TestNewCredentialsConflict(t *testing.T) {
+	_, err := NewCredentials(Auth{
+		Token:      testToken,
+		AppID:      testAppID,
+		PrivateKey: encodePEM("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(getTestKey(t))),
+	})
+	if !errors.Is(err, ErrAuthConflict) {
+		t.Errorf("want %v, got %v", ErrAuthConflict, err)
+	}
+}
+
+// verifyJWT checks that the JWT is signed by the key, returning its claims.
+func verifyJWT(key *rsa.PublicKey, jwt string) (map[string]interface{}, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed JWT %q", jwt)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, err
+	}
+
+	var header map[string]string
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, err
+	}
+	if header["alg"] != "RS256" || header["typ"] != "JWT" {
+		return nil, fmt.Errorf("unexpected JWT header %v", header)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, out interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, out)
+}
+
+func TestMakeJWT(t *testing.T) {
+	s := newAppTokenSource(testAppID, getTestKey(t))
+
+	now := time.Unix(1694509361, 0)
+	jwt, err := s.makeJWT(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := verifyJWT(&getTestKey(t).PublicKey, jwt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]interface{}{
+		"iat": float64(now.Unix() - 60),
+		"exp": float64(now.Unix() + 9*60),
+		"iss": "1234",
+	}
+	for k, v := range want {
+		if claims[k] != v {
+			t.Errorf("claim %s: got %v, want %v", k, claims[k], v)
+		}
+	}
+}
+
+// testAppServer is a stand-in API server for the app endpoints, counting the
+// installation lookups and tokens created, which are numbered from 1.
+type testAppServer struct {
+	t   *testing.T
+	key *rsa.PublicKey
+	// tokenLifetime is how long the tokens created are valid for.
+	tokenLifetime time.Duration
+	// started, if not nil, is closed when the installation is first looked
+	// up, which is then held until release is closed.
+	started chan struct{}
+	release chan struct{}
+
+	mu            sync.Mutex
+	lookups       int
+	tokensCreated int
+}
+
+func (a *testAppServer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	jwt := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if _, err := verifyJWT(a.key, jwt); err != nil {
+		a.t.Errorf("%s %s: invalid JWT: %v", r.Method, r.URL.Path, err)
+		rw.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widget/installation":
+		a.mu.Lock()
+		a.lookups++
+		first := a.lookups == 1
+		a.mu.Unlock()
+
+		if first && a.started != nil {
+			close(a.started)
+			<-a.release
+		}
+
+		_, _ = fmt.Fprint(rw, `{"id":42}`)
+
+	case r.Method == http.MethodPost && r.URL.Path == "/app/installations/42/access_tokens":
+		a.mu.Lock()
+		a.tokensCreated++
+		n := a.tokensCreated
+		a.mu.Unlock()
+
+		rw.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(rw).Encode(map[string]interface{}{
+			"token":      fmt.Sprintf("ghs_%d", n),
+			"expires_at": time.Now().Add(a.tokenLifetime).UTC().Format(time.RFC3339),
+		})
+
+	default:
+		a.t.Errorf("unexpected request %s %s", r.Method, r.URL.RequestURI())
+		rw.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (a *testAppServer) counts() (lookups int, tokensCreated int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.lookups, a.tokensCreated
+}
+
+// newTestAppTokenSource returns an app token source talking to a stand-in
+// server.
+func newTestAppTokenSource(t *testing.T) (*appTokenSource, *testAppServer) {
+	t.Helper()
+
+	key := getTestKey(t)
+	a := &testAppServer{
+		t:             t,
+		key:           &key.PublicKey,
+		tokenLifetime: time.Hour,
+	}
+	srv := httptest.NewServer(a)
+	t.Cleanup(srv.Close)
+
+	s := newAppTokenSource(testAppID, key)
+	s.baseURL = srv.URL
+	return s, a
+}
+
+func checkToken(t *testing.T, s *appTokenSource, want string) {
+	t.Helper()
+
+	got, err := s.token(context.Background(), "acme", "widget")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("got token %q, want %q", got, want)
+	}
+}
+
+func checkCounts(t *testing.T, a *testAppServer, wantLookups int, wantTokensCreated int) {
+	t.Helper()
+
+	lookups, tokensCreated := a.counts()
+	if lookups != wantLookups || tokensCreated != wantTokensCreated {
+		t.Errorf(
+			"got %d lookups and %d tokens created, want %d and %d",
+			lookups,
+			tokensCreated,
+			wantLookups,
+			wantTokensCreated,
+		)
+	}
+}
+
+func TestAppTokenCached(t *testing.T) {
+	s, a := newTestAppTokenSource(t)
+
+	checkToken(t, s, "ghs_1")
+	checkToken(t, s, "ghs_1")
+	checkCounts(t, a, 1, 1)
+}
+
+func TestAppTokenRefresh(t *testing.T) {
+	s, a := newTestAppTokenSource(t)
+	// within the refresh margin
+	a.tokenLifetime = installationTokenRefreshMargin - time.Minute
+
+	checkToken(t, s, "ghs_1")
+	checkToken(t, s, "ghs_2")
+	// the installation is still cached
+	checkCounts(t, a, 1, 2)
+}
+
+func TestForgetInstallation(t *testing.T) {
+	s, a := newTestAppTokenSource(t)
+
+	checkToken(t, s, "ghs_1")
+	s.forgetInstallation(42)
+	checkToken(t, s, "ghs_2")
+	checkCounts(t, a, 2, 2)
+}
+
+func TestForgetRepo(t *testing.T) {
+	s, a := newTestAppTokenSource(t)
+
+	checkToken(t, s, "ghs_1")
+	s.forgetRepo("acme", "widget")
+	checkToken(t, s, "ghs_1")
+	// the token of the installation is still cached
+	checkCounts(t, a, 2, 1)
+}
+
+func TestAppTokenStarterCanceled(t *testing.T) {
+	s, a := newTestAppTokenSource(t)
+	a.started = make(chan struct{})
+	a.release = make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error)
+	go func() {
+		_, err := s.token(ctx, "acme", "widget")
+		errCh <- err
+	}()
+
+	<-a.started
+	cancel()
+	if err := <-errCh; !errors.Is(err, context.Canceled) {
+		t.Errorf("want %v, got %v", context.Canceled, err)
+	}
+
+	// The lookup goes on for others, who either join it or find its result
+	// cached, instead of looking up again.
+	close(a.release)
+	checkToken(t, s, "ghs_1")
+	checkCounts(t, a, 1, 1)
+}
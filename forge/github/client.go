@@ -0,0 +1,152 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+var ErrAuthRequired = errors.New("github: token or app credentials are required for API access")
+
+type githubClient struct {
+	api *apiClient
+}
+
+var _ forge.IClient = (*githubClient)(nil)
+
+// NewClient returns a new GitHub API client for plugins to act on GitHub.
+//
+// The credentials should be shared with the forge hook from New.
+func NewClient(creds *Credentials) (forge.IClient, error) {
+	if creds.auth.isEmpty() {
+		return nil, ErrAuthRequired
+	}
+
+	return &githubClient{
+		api: newAPIClient(creds.tokens),
+	}, nil
+}
+
+// PostComment comments on the given issue or PR.
+func (c *githubClient) PostComment(
+	ctx context.Context,
+	repo v1alpha1.Repo,
+	_ v1alpha1.CommentTargetType,
+	number int,
+	body string,
+) error {
+	// Issues and PRs share the same numbering and comment API.
+	return c.api.createComment(ctx, repo.User.UserName, repo.RepoName, number, body)
+}
+
+// AddLabels adds labels to the given issue or PR.
+func (c *githubClient) AddLabels(
+	ctx context.Context,
+	repo v1alpha1.Repo,
+	_ v1alpha1.CommentTargetType,
+	number int,
+	labels []string,
+) error {
+	return c.api.addLabels(ctx, repo.User.UserName, repo.RepoName, number, labels)
+}
+
+// RemoveLabels removes labels from the given issue or PR, ignoring those not
+// present.
+func (c *githubClient) RemoveLabels(
+	ctx context.Context,
+	repo v1alpha1.Repo,
+	_ v1alpha1.CommentTargetType,
+	number int,
+	labels []string,
+) error {
+	for _, l := range labels {
+		err := c.api.removeLabel(ctx, repo.User.UserName, repo.RepoName, number, l)
+		if err != nil {
+			var apiErr *apiError
+			if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// RequestReviewers requests reviews of the given PR from the given users.
+func (c *githubClient) RequestReviewers(ctx context.Context, repo v1alpha1.Repo, number int, reviewers []string) error {
+	return c.api.requestReviewers(ctx, repo.User.UserName, repo.RepoName, number, reviewers)
+}
+
+// SetCommitStatus sets a commit status on the given commit.
+func (c *githubClient) SetCommitStatus(
+	ctx context.Context,
+	repo v1alpha1.Repo,
+	sha string,
+	status v1alpha1.CommitStatus,
+) error {
+	return c.api.createStatus(
+		ctx,
+		repo.User.UserName,
+		repo.RepoName,
+		sha,
+		&apiStatus{
+			State:       commitStatusStateFromCIState(status.State),
+			TargetURL:   status.URL,
+			Description: status.Description,
+			Context:     status.Context,
+		},
+	)
+}
+
+// GetPR returns details of the given PR.
+func (c *githubClient) GetPR(ctx context.Context, repo v1alpha1.Repo, number int) (*v1alpha1.PR, error) {
+	pr, err := c.api.getPR(ctx, repo.User.UserName, repo.RepoName, number)
+	if err != nil {
+		return nil, err
+	}
+
+	result := botModelFromAPIPR(repo, pr)
+	return &result, nil
+}
+
+// ListReviews returns the submitted reviews of the given PR.
+func (c *githubClient) ListReviews(ctx context.Context, repo v1alpha1.Repo, number int) ([]v1alpha1.Review, error) {
+	reviews, err := c.api.listReviews(ctx, repo.User.UserName, repo.RepoName, number)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]v1alpha1.Review, len(reviews))
+	for i := range reviews {
+		result[i] = botModelFromAPIReview(&reviews[i])
+	}
+	return result, nil
+}
+
+// ListChecks returns both the check runs and the commit statuses of the given
+// commit.
+func (c *githubClient) ListChecks(ctx context.Context, repo v1alpha1.Repo, sha string) ([]v1alpha1.Check, error) {
+	runs, err := c.api.listCheckRuns(ctx, repo.User.UserName, repo.RepoName, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	combined, err := c.api.getCombinedStatus(ctx, repo.User.UserName, repo.RepoName, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]v1alpha1.Check, 0, len(runs)+len(combined.Statuses))
+	for i := range runs {
+		result = append(result, botModelFromAPICheckRun(&runs[i]))
+	}
+	for i := range combined.Statuses {
+		result = append(result, botModelFromAPIStatus(&combined.Statuses[i]))
+	}
+	return result, nil
+}
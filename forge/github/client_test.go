@@ -0,0 +1,312 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package github
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge/internal/apitest"
+)
+
+const testToken = "ghp_0123456789abcdef"
+
+var testRepo = v1alpha1.Repo{
+	User: v1alpha1.ForgeUser{
+		Forge:    forgeType,
+		UserName: "acme",
+	},
+	RepoName: "widget",
+}
+
+// newTestClient returns a client talking to a stand-in server, which expects
+// exactly the given calls in order.
+func newTestClient(t *testing.T, calls ...apitest.Call) *githubClient {
+	t.Helper()
+
+	creds, err := NewCredentials(Auth{Token: testToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClient(creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := client.(*githubClient)
+	c.api.baseURL = newTestServer(t, calls...)
+	return c
+}
+
+// newTestServer starts a stand-in API server expecting exactly the given calls
+// in order, returning its URL.
+func newTestServer(t *testing.T, calls ...apitest.Call) string {
+	t.Helper()
+	return apitest.NewServer(t, "Authorization", "Bearer "+testToken, calls...)
+}
+
+func TestClientPostComment(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodPost,
+		Path:   "/repos/acme/widget/issues/17/comments",
+		Body:   `{"body":"LGTM"}`,
+		Status: http.StatusCreated,
+	})
+
+	err := c.PostComment(context.Background(), testRepo, v1alpha1.CommentTargetTypePR, 17, "LGTM")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientAddLabels(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodPost,
+		Path:   "/repos/acme/widget/issues/17/labels",
+		Body:   `{"labels":["bug","needs review"]}`,
+	})
+
+	labels := []string{"bug", "needs review"}
+	err := c.AddLabels(context.Background(), testRepo, v1alpha1.CommentTargetTypeIssue, 17, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientRemoveLabels(t *testing.T) {
+	c := newTestClient(
+		t,
+		apitest.Call{
+			Method: http.MethodDelete,
+			Path:   "/repos/acme/widget/issues/17/labels/needs%20review",
+			Status: http.StatusNotFound,
+		},
+		apitest.Call{
+			Method: http.MethodDelete,
+			Path:   "/repos/acme/widget/issues/17/labels/bug",
+		},
+	)
+
+	labels := []string{"needs review", "bug"}
+	err := c.RemoveLabels(context.Background(), testRepo, v1alpha1.CommentTargetTypePR, 17, labels)
+	if err != nil {
+		t.Fatalf("want labels not present ignored, got %v", err)
+	}
+}
+
+func TestClientRemoveLabelsError(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodDelete,
+		Path:   "/repos/acme/widget/issues/17/labels/bug",
+		Status: http.StatusForbidden,
+	})
+
+	err := c.RemoveLabels(context.Background(), testRepo, v1alpha1.CommentTargetTypePR, 17, []string{"bug", "wontfix"})
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+}
+
+func TestClientRequestReviewers(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodPost,
+		Path:   "/repos/acme/widget/pulls/17/requested_reviewers",
+		Body:   `{"reviewers":["bob","carol"]}`,
+		Status: http.StatusCreated,
+	})
+
+	err := c.RequestReviewers(context.Background(), testRepo, 17, []string{"bob", "carol"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientSetCommitStatus(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodPost,
+		Path:   "/repos/acme/widget/statuses/5f1c0e7b",
+		Body: `{
+			"state": "failure",
+			"target_url": "https://ci.example.com/builds/42",
+			"description": "2 tests failed",
+			"context": "ci/test"
+		}`,
+		Status: http.StatusCreated,
+	})
+
+	err := c.SetCommitStatus(context.Background(), testRepo, "5f1c0e7b", v1alpha1.CommitStatus{
+		State:       v1alpha1.CIStateFailed,
+		Context:     "ci/test",
+		Description: "2 tests failed",
+		URL:         "https://ci.example.com/builds/42",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientGetPR(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/repos/acme/widget/pulls/17",
+		Response: `{
+			"number": 17,
+			"state": "closed",
+			"title": "Retry uploads on transient errors",
+			"body": "Retries the upload on transient errors.",
+			"user": {"login": "alice"},
+			"html_url": "https://github.com/acme/widget/pull/17",
+			"draft": false,
+			"base": {"ref": "main", "sha": "0a1b2c3d"},
+			"head": {"ref": "fix-upload-retry", "sha": "5f1c0e7b"},
+			"labels": [{"name": "bug"}],
+			"assignees": [{"login": "bob"}],
+			"requested_reviewers": [{"login": "carol"}],
+			"created_at": "2023-09-12T08:15:03Z",
+			"updated_at": "2023-09-12T09:02:41Z",
+			"merged_at": "2023-09-12T09:02:41Z",
+			"additions": 42,
+			"deletions": 7
+		}`,
+	})
+
+	got, err := c.GetPR(context.Background(), testRepo, 17)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &v1alpha1.PR{
+		Repo:   testRepo,
+		Number: 17,
+		Title:  "Retry uploads on transient errors",
+		Author: v1alpha1.ForgeUser{Forge: forgeType, UserName: "alice"},
+		State:  v1alpha1.IssueStateMerged,
+		URL:    "https://github.com/acme/widget/pull/17",
+		Body:   "Retries the upload on transient errors.",
+
+		BaseBranch: "main",
+		HeadBranch: "fix-upload-retry",
+		HeadSHA:    "5f1c0e7b",
+
+		Labels:             []string{"bug"},
+		Assignees:          []v1alpha1.ForgeUser{{Forge: forgeType, UserName: "bob"}},
+		RequestedReviewers: []v1alpha1.ForgeUser{{Forge: forgeType, UserName: "carol"}},
+
+		CreatedAt: time.Date(2023, 9, 12, 8, 15, 3, 0, time.UTC),
+		UpdatedAt: time.Date(2023, 9, 12, 9, 2, 41, 0, time.UTC),
+
+		Additions: 42,
+		Deletions: 7,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestClientListReviews(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/repos/acme/widget/pulls/17/reviews?per_page=100",
+		Response: `[
+			{
+				"user": {"login": "carol"},
+				"state": "CHANGES_REQUESTED",
+				"body": "Please add a test.",
+				"html_url": "https://github.com/acme/widget/pull/17#pullrequestreview-1",
+				"submitted_at": "2023-09-12T08:30:00Z"
+			},
+			{
+				"user": {"login": "carol"},
+				"state": "APPROVED",
+				"body": "",
+				"html_url": "https://github.com/acme/widget/pull/17#pullrequestreview-2",
+				"submitted_at": "2023-09-12T09:00:00Z"
+			}
+		]`,
+	})
+
+	got, err := c.ListReviews(context.Background(), testRepo, 17)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	carol := v1alpha1.ForgeUser{Forge: forgeType, UserName: "carol"}
+	want := []v1alpha1.Review{
+		{
+			Author:      carol,
+			Type:        v1alpha1.ReviewTypeRequestChanges,
+			Body:        "Please add a test.",
+			URL:         "https://github.com/acme/widget/pull/17#pullrequestreview-1",
+			SubmittedAt: time.Date(2023, 9, 12, 8, 30, 0, 0, time.UTC),
+		},
+		{
+			Author:      carol,
+			Type:        v1alpha1.ReviewTypeApprove,
+			URL:         "https://github.com/acme/widget/pull/17#pullrequestreview-2",
+			SubmittedAt: time.Date(2023, 9, 12, 9, 0, 0, 0, time.UTC),
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestClientListChecks(t *testing.T) {
+	c := newTestClient(
+		t,
+		apitest.Call{
+			Method: http.MethodGet,
+			Path:   "/repos/acme/widget/commits/5f1c0e7b/check-runs?per_page=100",
+			Response: `{
+				"check_runs": [
+					{
+						"name": "build",
+						"status": "completed",
+						"conclusion": "success",
+						"html_url": "https://github.com/acme/widget/runs/1"
+					},
+					{
+						"name": "lint",
+						"status": "in_progress",
+						"conclusion": null,
+						"html_url": "https://github.com/acme/widget/runs/2"
+					}
+				]
+			}`,
+		},
+		apitest.Call{
+			Method: http.MethodGet,
+			Path:   "/repos/acme/widget/commits/5f1c0e7b/status",
+			Response: `{
+				"state": "failure",
+				"sha": "5f1c0e7b",
+				"statuses": [
+					{
+						"state": "failure",
+						"target_url": "https://ci.example.com/builds/42",
+						"description": "2 tests failed",
+						"context": "ci/test"
+					}
+				]
+			}`,
+		},
+	)
+
+	got, err := c.ListChecks(context.Background(), testRepo, "5f1c0e7b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []v1alpha1.Check{
+		{Name: "build", State: v1alpha1.CIStatePassed, URL: "https://github.com/acme/widget/runs/1"},
+		{Name: "lint", State: v1alpha1.CIStatePending, URL: "https://github.com/acme/widget/runs/2"},
+		{Name: "ci/test", State: v1alpha1.CIStateFailed, URL: "https://ci.example.com/builds/42"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
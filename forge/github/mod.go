@@ -3,7 +3,11 @@
 package github
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/go-playground/webhooks/v6/github"
 
@@ -15,12 +19,21 @@ const forgeType = "github"
 
 type githubForge struct {
 	hook *github.Webhook
+	api  *apiClient
+	// app is non-nil if running as a GitHub App.
+	app *appTokenSource
+
+	finishedStatuses *forge.StatusClaims
 }
 
 var _ forge.IForgeHook = (*githubForge)(nil)
 
 // New returns a new GitHub forge hook instance.
-func New(secret string) (forge.IForgeHook, error) {
+//
+// The credentials are used for GitHub API calls, and can be empty if all
+// repositories are public. They should be shared with the client from
+// NewClient.
+func New(secret string, creds *Credentials) (forge.IForgeHook, error) {
 	hook, err := github.New(
 		github.Options.Secret(secret),
 	)
@@ -30,11 +43,31 @@ func New(secret string) (forge.IForgeHook, error) {
 
 	return &githubForge{
 		hook: hook,
+		api:  newAPIClient(creds.tokens),
+		app:  creds.appTokens(),
+
+		finishedStatuses: forge.NewStatusClaims(),
 	}, nil
 }
 
 // HookRequest hooks an incoming webhook request to trigger actions.
 func (f *githubForge) HookRequest(req *http.Request) (*v1alpha1.Event, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	e, err := f.hookRequest(req, body)
+	if e != nil {
+		e.SetDeliveryID(req.Header.Get("X-GitHub-Delivery"))
+		e.SetForgeEvent(req.Header.Get("X-GitHub-Event"), forge.ActionFromPayload(body))
+		e.SetRawPayload(body)
+	}
+	return e, err
+}
+
+func (f *githubForge) hookRequest(req *http.Request, body []byte) (*v1alpha1.Event, error) {
 	payload, err := f.hook.Parse(
 		req,
 		// XXX This is everything for now, I don't know exactly what GitHub is
@@ -104,15 +137,213 @@ func (f *githubForge) HookRequest(req *http.Request) (*v1alpha1.Event, error) {
 			params := intoPRClosedParams(&p)
 			return params.IntoEvent(), nil
 
+		case "edited":
+			if p.Changes == nil || p.Changes.Title == nil {
+				// Only the title is interesting
+				return nil, nil
+			}
+
+			params := intoPRRenamedParams(&p, p.Changes.Title.From)
+			return params.IntoEvent(), nil
+
 		case "ready_for_review":
 			params := intoPRReadyParams(&p)
 			return params.IntoEvent(), nil
 
+		case "converted_to_draft":
+			params := intoPRWithdrawnParams(&p)
+			return params.IntoEvent(), nil
+
+		case "review_requested":
+			params := intoPRReviewRequestedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "review_request_removed":
+			params := intoPRReviewRequestRemovedParams(&p)
+			return params.IntoEvent(), nil
+
+		default:
+			// Currently no bot event for this action
+			return nil, nil
+		}
+
+	case github.PullRequestReviewPayload:
+		switch p.Action {
+		case "submitted", "dismissed":
+			params := intoPRReviewedParams(&p)
+			return params.IntoEvent(), nil
+
+		default:
+			// Currently no bot event for this action
+			return nil, nil
+		}
+
+	case github.IssuesPayload:
+		switch p.Action {
+		case "opened":
+			params := intoIssueOpenedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "closed":
+			params := intoIssueClosedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "reopened":
+			params := intoIssueReopenedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "assigned":
+			params := intoIssueAssignedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "labeled":
+			params := intoIssueLabeledParams(&p)
+			return params.IntoEvent(), nil
+
+		case "edited":
+			if p.Changes == nil || p.Changes.Title == nil {
+				// Only the title is interesting
+				return nil, nil
+			}
+
+			params := intoIssueRenamedParams(&p, p.Changes.Title.From)
+			return params.IntoEvent(), nil
+
 		default:
 			// Currently no bot event for this action
 			return nil, nil
 		}
 
+	case github.IssueCommentPayload:
+		if p.Action != "created" {
+			return nil, nil
+		}
+
+		params := intoCommentCreatedParams(&p, nil)
+		return params.IntoEvent(), nil
+
+	case github.PullRequestReviewCommentPayload:
+		if p.Action != "created" {
+			return nil, nil
+		}
+
+		var extras reviewCommentExtras
+		err := json.Unmarshal(body, &extras)
+		if err != nil {
+			return nil, err
+		}
+
+		params := intoCommentCreatedParams(&p, &extras)
+		return params.IntoEvent(), nil
+
+	case github.PushPayload:
+		if p.Created || p.Deleted || !strings.HasPrefix(p.Ref, "refs/heads/") {
+			// Covered by the create and delete events
+			return nil, nil
+		}
+
+		params := intoPushParams(&p)
+		return params.IntoEvent(), nil
+
+	case github.CreatePayload:
+		switch p.RefType {
+		case "branch":
+			params := intoBranchCreatedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "tag":
+			params := intoTagCreatedParams(&p)
+			return params.IntoEvent(), nil
+
+		default:
+			return nil, nil
+		}
+
+	case github.DeletePayload:
+		if p.RefType != "branch" {
+			// Tag deletions are not interesting
+			return nil, nil
+		}
+
+		params := intoBranchDeletedParams(&p)
+		return params.IntoEvent(), nil
+
+	case github.ReleasePayload:
+		if p.Action != "published" {
+			return nil, nil
+		}
+
+		params := intoReleasePublishedParams(&p)
+		return params.IntoEvent(), nil
+
+	case github.DeploymentPayload:
+		params := intoDeploymentCreatedParams(&p)
+		return params.IntoEvent(), nil
+
+	case github.DeploymentStatusPayload:
+		params := intoDeploymentStatusChangedParams(&p)
+		return params.IntoEvent(), nil
+
+	case github.CheckSuitePayload:
+		if p.Action != "completed" {
+			return nil, nil
+		}
+
+		// The PRs are looked up later in ResolveEvent.
+		state := ciStateFromCheckSuiteConclusion(p.CheckSuite.Conclusion)
+		params := intoCIFinishedParams(&p, state)
+		return params.IntoEvent(), nil
+
+	case github.CheckRunPayload:
+		// Individual check runs are rolled up into check suites, so nothing
+		// to do here.
+		return nil, nil
+
+	case github.StatusPayload:
+		// Only report on the combined status, once every context has
+		// finished, which is looked up later in ResolveEvent along with the
+		// PRs. The state of this one status is only a placeholder.
+		state := ciStateFromCombinedStatusState(p.State)
+		params := intoCIFinishedParams(&p, state)
+		return params.IntoEvent(), nil
+
+	case github.InstallationPayload:
+		switch p.Action {
+		case "created":
+			params := intoAppInstalledParams(&p)
+			return params.IntoEvent(), nil
+
+		case "deleted":
+			if f.app != nil {
+				f.app.forgetInstallation(p.Installation.ID)
+			}
+
+			params := intoAppUninstalledParams(&p)
+			return params.IntoEvent(), nil
+		}
+
+		// Currently no bot event for this action
+		return nil, nil
+
+	case github.InstallationRepositoriesPayload:
+		switch p.Action {
+		case "added":
+			params := intoAppReposAddedParams(&p)
+			return params.IntoEvent(), nil
+
+		case "removed":
+			params := intoAppReposRemovedParams(&p)
+			if f.app != nil {
+				for _, r := range params.Repos {
+					f.app.forgetRepo(r.User.UserName, r.RepoName)
+				}
+			}
+
+			return params.IntoEvent(), nil
+		}
+
+		// Currently no bot event for this action
+		return nil, nil
 	}
 
 	// Currently not handled
@@ -0,0 +1,26 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package github
+
+// Supplementary payload types for fields missing from the upstream webhook
+// library's payload structs. These are decoded from the raw request body
+// after the upstream library has verified and parsed the request.
+
+type reviewCommentExtras struct {
+	Comment struct {
+		Line *int `json:"line"`
+		// set instead of "line" for comments on outdated diffs
+		OriginalLine *int `json:"original_line"`
+	} `json:"comment"`
+}
+
+// line returns the line number the review comment is on.
+func (x *reviewCommentExtras) line() int {
+	if x.Comment.Line != nil {
+		return *x.Comment.Line
+	}
+	if x.Comment.OriginalLine != nil {
+		return *x.Comment.OriginalLine
+	}
+	return 0
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package github
+
+import (
+	"context"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+var _ forge.IEventResolver = (*githubForge)(nil)
+
+// NeedsResolving returns whether the event is to be completed with API
+// lookups, which is the case for CIFinished events.
+func (f *githubForge) NeedsResolving(e *v1alpha1.Event) bool {
+	_, ok := e.CIFinished()
+	return ok
+}
+
+// ResolveEvent completes CIFinished events with the open PRs of the commit,
+// and for commit statuses, the combined status. Commit statuses are dropped
+// while the combined status is pending, or if another event has already
+// reported the same final combined status of the commit.
+func (f *githubForge) ResolveEvent(ctx context.Context, e *v1alpha1.Event) (*v1alpha1.Event, error) {
+	params, ok := e.CIFinished()
+	if !ok {
+		return e, nil
+	}
+
+	run := &params.Run
+	owner := run.Repo.User.UserName
+	repo := run.Repo.RepoName
+
+	if e.ForgeEvent() == "status" {
+		combined, err := f.api.getCombinedStatus(ctx, owner, repo, run.SHA)
+		if err != nil {
+			return nil, err
+		}
+
+		if combined.State == "pending" {
+			return nil, nil
+		}
+
+		// Several statuses finishing close together all see the final
+		// combined status.
+		key := owner + "/" + repo + "@" + run.SHA + ":" + combined.State
+		if !f.finishedStatuses.Claim(key, e.ID(), time.Now()) {
+			return nil, nil
+		}
+
+		run.State = ciStateFromCombinedStatusState(combined.State)
+	}
+
+	prs, err := f.api.listPRsForCommit(ctx, owner, repo, run.SHA)
+	if err != nil {
+		return nil, err
+	}
+
+	run.PRs = botModelFromOpenAPIPRs(run.Repo, prs)
+	if len(run.PRs) > 0 {
+		run.PR = run.PRs[0]
+	}
+	return e, nil
+}
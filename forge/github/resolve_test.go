@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package github
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge/internal/apitest"
+)
+
+const testSHA = "5f1c0e7b"
+
+// newTestForge returns a forge hook talking to a stand-in server, which
+// expects exactly the given calls in order.
+func newTestForge(t *testing.T, calls ...apitest.Call) *githubForge {
+	t.Helper()
+
+	creds, err := NewCredentials(Auth{Token: testToken})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fh, err := New("Sup3rS3cr3tStr1ng", creds)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := fh.(*githubForge)
+	f.api.baseURL = newTestServer(t, calls...)
+	return f
+}
+
+// newCIEvent returns an unresolved CIFinished event, as from HookRequest.
+func newCIEvent(id string, forgeEvent string, state v1alpha1.CIState) *v1alpha1.Event {
+	params := &v1alpha1.CIFinishedParams{
+		Run: v1alpha1.CIRun{
+			Repo:  testRepo,
+			State: state,
+			SHA:   testSHA,
+			URL:   "https://github.com/acme/widget/commit/" + testSHA,
+		},
+	}
+
+	e := params.IntoEvent()
+	e.SetID(id)
+	e.SetForgeEvent(forgeEvent, "")
+	return e
+}
+
+var (
+	combinedStatusCall = apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/repos/acme/widget/commits/" + testSHA + "/status",
+	}
+	prsForCommitCall = apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/repos/acme/widget/commits/" + testSHA + "/pulls",
+		Response: `[
+			{"number": 17, "state": "open", "user": {"login": "alice"}, "head": {"sha": "5f1c0e7b"}},
+			{"number": 12, "state": "closed", "user": {"login": "bob"}, "head": {"sha": "5f1c0e7b"}},
+			{"number": 18, "state": "open", "user": {"login": "carol"}, "head": {"sha": "5f1c0e7b"}}
+		]`,
+	}
+)
+
+func withResponse(c apitest.Call, response string) apitest.Call {
+	c.Response = response
+	return c
+}
+
+// assertResolvedRun checks that the event is resolved to the given state,
+// with the open PRs of prsForCommitCall.
+func assertResolvedRun(t *testing.T, e *v1alpha1.Event, state v1alpha1.CIState) {
+	t.Helper()
+
+	if e == nil {
+		t.Fatal("want resolved event, got none")
+	}
+	params, ok := e.CIFinished()
+	if !ok {
+		t.Fatalf("want CIFinished event, got event of type %d", e.Type())
+	}
+
+	run := &params.Run
+	if run.State != state {
+		t.Errorf("got state %d, want %d", run.State, state)
+	}
+
+	var numbers []int
+	for _, pr := range run.PRs {
+		numbers = append(numbers, pr.Number)
+	}
+	if !reflect.DeepEqual(numbers, []int{17, 18}) {
+		t.Errorf("got PRs %v, want [17 18]", numbers)
+	}
+	if run.PR.Number != 17 {
+		t.Errorf("got PR %d, want 17", run.PR.Number)
+	}
+}
+
+func TestResolveStatusPending(t *testing.T) {
+	f := newTestForge(t, withResponse(combinedStatusCall, `{"state": "pending"}`))
+
+	e, err := f.ResolveEvent(context.Background(), newCIEvent("1", "status", v1alpha1.CIStatePassed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != nil {
+		t.Fatalf("want no event while the combined status is pending, got %+v", e)
+	}
+}
+
+func TestResolveStatusDedup(t *testing.T) {
+	f := newTestForge(
+		t,
+		withResponse(combinedStatusCall, `{"state": "failure"}`),
+		prsForCommitCall,
+		// another status finishing at about the same time
+		withResponse(combinedStatusCall, `{"state": "failure"}`),
+		// the first event retried
+		withResponse(combinedStatusCall, `{"state": "failure"}`),
+		prsForCommitCall,
+		// the commit turning green after a re-run
+		withResponse(combinedStatusCall, `{"state": "success"}`),
+		prsForCommitCall,
+	)
+	ctx := context.Background()
+
+	e, err := f.ResolveEvent(ctx, newCIEvent("1", "status", v1alpha1.CIStatePassed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResolvedRun(t, e, v1alpha1.CIStateFailed)
+
+	e, err = f.ResolveEvent(ctx, newCIEvent("2", "status", v1alpha1.CIStateFailed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e != nil {
+		t.Fatalf("want duplicate final status dropped, got %+v", e)
+	}
+
+	e, err = f.ResolveEvent(ctx, newCIEvent("1", "status", v1alpha1.CIStatePassed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResolvedRun(t, e, v1alpha1.CIStateFailed)
+
+	e, err = f.ResolveEvent(ctx, newCIEvent("3", "status", v1alpha1.CIStatePassed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResolvedRun(t, e, v1alpha1.CIStatePassed)
+}
+
+func TestResolveCheckSuite(t *testing.T) {
+	f := newTestForge(t, prsForCommitCall)
+
+	e, err := f.ResolveEvent(context.Background(), newCIEvent("1", "check_suite", v1alpha1.CIStateCanceled))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResolvedRun(t, e, v1alpha1.CIStateCanceled)
+}
+
+func TestResolveError(t *testing.T) {
+	f := newTestForge(t, apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/repos/acme/widget/commits/" + testSHA + "/status",
+		Status: http.StatusBadGateway,
+	})
+
+	_, err := f.ResolveEvent(context.Background(), newCIEvent("1", "status", v1alpha1.CIStatePassed))
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+}
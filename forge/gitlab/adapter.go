@@ -0,0 +1,679 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitlab
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-playground/webhooks/v6/gitlab"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// Adapters for whole event param structs
+
+func intoPROpenedParams(x *mergeRequestEventPayload) v1alpha1.PROpenedParams {
+	return v1alpha1.PROpenedParams{
+		Actor:   botModelFromSenderContainingPayload(x),
+		PR:      botModelFromPRContainingPayload(x),
+		IsDraft: x.ObjectAttributes.WorkInProgress,
+	}
+}
+
+func intoPRClosedParams(x *mergeRequestEventPayload) v1alpha1.PRClosedParams {
+	return v1alpha1.PRClosedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		PR:    botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoPRMergedParams(x *mergeRequestEventPayload) v1alpha1.PRMergedParams {
+	return v1alpha1.PRMergedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		PR:    botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoPRRenamedParams(x *mergeRequestEventPayload, oldTitle string) v1alpha1.PRRenamedParams {
+	return v1alpha1.PRRenamedParams{
+		Actor:    botModelFromSenderContainingPayload(x),
+		PR:       botModelFromPRContainingPayload(x),
+		OldTitle: oldTitle,
+	}
+}
+
+func intoPRReviewRequestedParams(
+	x *mergeRequestEventPayload,
+	reviewers []gitlab.Assignee,
+) v1alpha1.PRReviewRequestedParams {
+	return v1alpha1.PRReviewRequestedParams{
+		Actor:     botModelFromSenderContainingPayload(x),
+		PR:        botModelFromPRContainingPayload(x),
+		Reviewers: botModelFromAssignees(reviewers),
+	}
+}
+
+func intoPRReviewRequestRemovedParams(
+	x *mergeRequestEventPayload,
+	reviewers []gitlab.Assignee,
+) v1alpha1.PRReviewRequestRemovedParams {
+	return v1alpha1.PRReviewRequestRemovedParams{
+		Actor:     botModelFromSenderContainingPayload(x),
+		PR:        botModelFromPRContainingPayload(x),
+		Reviewers: botModelFromAssignees(reviewers),
+	}
+}
+
+func intoPRReviewedParams(
+	x *mergeRequestEventPayload,
+	review v1alpha1.ReviewType,
+) v1alpha1.PRReviewedParams {
+	return v1alpha1.PRReviewedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		PR:     botModelFromPRContainingPayload(x),
+		Review: review,
+	}
+}
+
+func intoPRReadyParams(x *mergeRequestEventPayload) v1alpha1.PRReadyParams {
+	return v1alpha1.PRReadyParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		PR:    botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoPRWithdrawnParams(x *mergeRequestEventPayload) v1alpha1.PRWithdrawnParams {
+	return v1alpha1.PRWithdrawnParams{
+		PR: botModelFromPRContainingPayload(x),
+	}
+}
+
+func intoCIFinishedParams(x *gitlab.PipelineEventPayload) v1alpha1.CIFinishedParams {
+	return v1alpha1.CIFinishedParams{
+		Run: botModelFromCIRunContainingPayload(x),
+	}
+}
+
+func intoIssueOpenedParams(x *issueEventPayload) v1alpha1.IssueOpenedParams {
+	return v1alpha1.IssueOpenedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueClosedParams(x *issueEventPayload) v1alpha1.IssueClosedParams {
+	return v1alpha1.IssueClosedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueReopenedParams(x *issueEventPayload) v1alpha1.IssueReopenedParams {
+	return v1alpha1.IssueReopenedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Issue: botModelFromIssueContainingPayload(x),
+	}
+}
+
+func intoIssueAssignedParams(x *issueEventPayload, assignees []gitlab.Assignee) v1alpha1.IssueAssignedParams {
+	return v1alpha1.IssueAssignedParams{
+		Actor:     botModelFromSenderContainingPayload(x),
+		Issue:     botModelFromIssueContainingPayload(x),
+		Assignees: botModelFromAssignees(assignees),
+	}
+}
+
+func intoIssueLabeledParams(x *issueEventPayload, labels []gitlab.Label) v1alpha1.IssueLabeledParams {
+	return v1alpha1.IssueLabeledParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		Issue:  botModelFromIssueContainingPayload(x),
+		Labels: botModelFromLabels(labels),
+	}
+}
+
+func intoIssueRenamedParams(x *issueEventPayload, oldTitle string) v1alpha1.IssueRenamedParams {
+	return v1alpha1.IssueRenamedParams{
+		Actor:    botModelFromSenderContainingPayload(x),
+		Issue:    botModelFromIssueContainingPayload(x),
+		OldTitle: oldTitle,
+	}
+}
+
+func intoCommentCreatedParams(x *commentEventPayload) v1alpha1.CommentCreatedParams {
+	result := v1alpha1.CommentCreatedParams{
+		Actor:   botModelFromSenderContainingPayload(x),
+		Comment: botModelFromCommentContainingPayload(x),
+	}
+
+	switch x.ObjectAttributes.NotebookType {
+	case "Issue":
+		result.Target = v1alpha1.CommentTargetTypeIssue
+		result.Issue = botModelFromIssueContainingPayload(x)
+
+	case "MergeRequest":
+		result.Target = v1alpha1.CommentTargetTypePR
+		result.PR = botModelFromPRContainingPayload(x)
+	}
+
+	return result
+}
+
+func intoPushParams(x *gitlab.PushEventPayload) v1alpha1.PushParams {
+	return v1alpha1.PushParams{
+		Actor:   botModelFromSenderContainingPayload(x),
+		Repo:    botModelFromRepoContainingPayload(x),
+		Ref:     x.Ref,
+		Before:  x.Before,
+		After:   x.After,
+		Commits: botModelFromCommits(x.Commits),
+		// GitLab does not tell if the push is forced
+	}
+}
+
+func intoBranchCreatedParams(x *gitlab.PushEventPayload) v1alpha1.BranchCreatedParams {
+	return v1alpha1.BranchCreatedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		Repo:   botModelFromRepoContainingPayload(x),
+		Branch: strings.TrimPrefix(x.Ref, "refs/heads/"),
+		SHA:    x.After,
+	}
+}
+
+func intoBranchDeletedParams(x *gitlab.PushEventPayload) v1alpha1.BranchDeletedParams {
+	return v1alpha1.BranchDeletedParams{
+		Actor:  botModelFromSenderContainingPayload(x),
+		Repo:   botModelFromRepoContainingPayload(x),
+		Branch: strings.TrimPrefix(x.Ref, "refs/heads/"),
+	}
+}
+
+func intoTagCreatedParams(x *gitlab.TagEventPayload) v1alpha1.TagCreatedParams {
+	return v1alpha1.TagCreatedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Repo:  botModelFromRepoContainingPayload(x),
+		Tag:   strings.TrimPrefix(x.Ref, "refs/tags/"),
+		SHA:   x.After,
+	}
+}
+
+func intoReleasePublishedParams(x *releaseEventPayload) v1alpha1.ReleasePublishedParams {
+	return v1alpha1.ReleasePublishedParams{
+		// Release hooks carry no information about the user
+		Actor: v1alpha1.ForgeUser{},
+		Release: v1alpha1.Release{
+			Repo:  botModelFromRepoContainingPayload(x),
+			Tag:   x.Tag,
+			Name:  x.Name,
+			Notes: x.Description,
+			// GitLab has no concept of pre-releases
+			IsPrerelease: false,
+			URL:          x.URL,
+			CreatedAt:    x.CreatedAt,
+		},
+	}
+}
+
+func intoDeploymentStatusChangedParams(x *deploymentEventPayload) v1alpha1.DeploymentStatusChangedParams {
+	return v1alpha1.DeploymentStatusChangedParams{
+		Actor: botModelFromSenderContainingPayload(x),
+		Deployment: v1alpha1.Deployment{
+			Repo:        botModelFromRepoContainingPayload(x),
+			ID:          x.DeploymentId,
+			Environment: x.Environment,
+			Ref:         x.Ref,
+			// only the short SHA is available
+			SHA:     x.ShortSha,
+			Creator: botModelFromSenderContainingPayload(x),
+			URL:     fmt.Sprintf("%s/-/environments", x.Project.WebURL),
+		},
+		State: deploymentStateFromDeploymentStatus(x.Status),
+		URL:   x.DeployableUrl,
+	}
+}
+
+// Adapters for component fields
+
+func botModelFromSenderContainingPayload(x interface{}) v1alpha1.ForgeUser {
+	switch x := x.(type) {
+	case *mergeRequestEventPayload:
+		return botModelFromUser(&x.User)
+
+	case *issueEventPayload:
+		return botModelFromUser(&x.User)
+
+	case *commentEventPayload:
+		return botModelFromUser(&x.User)
+
+	case *gitlab.PushEventPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.UserUsername,
+		}
+
+	case *gitlab.TagEventPayload:
+		return v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: x.UserUsername,
+		}
+
+	case *deploymentEventPayload:
+		return botModelFromUser(&x.User)
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromRepoContainingPayload(x interface{}) v1alpha1.Repo {
+	switch x := x.(type) {
+	case *mergeRequestEventPayload:
+		return botModelFromProject(&x.Project)
+
+	case *gitlab.PipelineEventPayload:
+		return botModelFromProject(&x.Project)
+
+	case *issueEventPayload:
+		return botModelFromProject(&x.Project)
+
+	case *commentEventPayload:
+		return botModelFromProject(&x.Project)
+
+	case *gitlab.PushEventPayload:
+		return botModelFromProject(&x.Project)
+
+	case *gitlab.TagEventPayload:
+		return botModelFromProject(&x.Project)
+
+	case *deploymentEventPayload:
+		return botModelFromProject(&x.Project)
+
+	case *releaseEventPayload:
+		return botModelFromProject(&x.Project)
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromPRContainingPayload(x interface{}) v1alpha1.PR {
+	switch x := x.(type) {
+	case *mergeRequestEventPayload:
+		return v1alpha1.PR{
+			Repo:   botModelFromRepoContainingPayload(x),
+			Number: int(x.ObjectAttributes.IID),
+			Title:  x.ObjectAttributes.Title,
+			Author: authorFromIDAndActor(x.ObjectAttributes.AuthorID, &x.User),
+			State:  issueStateFromMRState(x.ObjectAttributes.State),
+			URL:    x.ObjectAttributes.URL,
+			Body:   x.ObjectAttributes.Description,
+
+			BaseBranch: x.ObjectAttributes.TargetBranch,
+			HeadBranch: x.ObjectAttributes.SourceBranch,
+			HeadSHA:    x.ObjectAttributes.LastCommit.ID,
+			IsDraft:    x.ObjectAttributes.WorkInProgress,
+
+			Labels:             botModelFromLabels(x.Labels),
+			Assignees:          botModelFromAssignees(x.Assignees),
+			RequestedReviewers: botModelFromAssignees(x.Reviewers),
+
+			CreatedAt: x.ObjectAttributes.CreatedAt.Time,
+			UpdatedAt: x.ObjectAttributes.UpdatedAt.Time,
+
+			// line counts are not available in GitLab hooks
+		}
+
+	case *gitlab.PipelineEventPayload:
+		if x.MergeRequest.IID == 0 {
+			// not a merge request pipeline
+			return v1alpha1.PR{}
+		}
+
+		return v1alpha1.PR{
+			Repo:   botModelFromRepoContainingPayload(x),
+			Number: int(x.MergeRequest.IID),
+			Title:  x.MergeRequest.Title,
+			Author: authorFromIDAndActor(x.MergeRequest.AuthorID, &x.User),
+			State:  issueStateFromMRState(x.MergeRequest.State),
+			URL:    x.MergeRequest.URL,
+			Body:   x.MergeRequest.Description,
+
+			BaseBranch: x.MergeRequest.TargetBranch,
+			HeadBranch: x.MergeRequest.SourceBranch,
+			HeadSHA:    x.MergeRequest.LastCommit.ID,
+			IsDraft:    x.MergeRequest.WorkInProgress,
+
+			CreatedAt: x.MergeRequest.CreatedAt.Time,
+			UpdatedAt: x.MergeRequest.UpdatedAt.Time,
+		}
+
+	case *commentEventPayload:
+		url := x.MergeRequest.URL
+		if url == "" {
+			url = fmt.Sprintf("%s/-/merge_requests/%d", x.Project.WebURL, x.MergeRequest.IID)
+		}
+
+		return v1alpha1.PR{
+			Repo:   botModelFromRepoContainingPayload(x),
+			Number: int(x.MergeRequest.IID),
+			Title:  x.MergeRequest.Title,
+			Author: authorFromIDAndActor(x.MergeRequest.AuthorID, &x.User),
+			State:  issueStateFromMRState(x.MergeRequest.State),
+			URL:    url,
+			Body:   x.MergeRequest.Description,
+
+			BaseBranch: x.MergeRequest.TargetBranch,
+			HeadBranch: x.MergeRequest.SourceBranch,
+			HeadSHA:    x.MergeRequest.LastCommit.ID,
+			IsDraft:    x.MergeRequest.WorkInProgress,
+
+			CreatedAt: x.MergeRequest.CreatedAt.Time,
+			UpdatedAt: x.MergeRequest.UpdatedAt.Time,
+		}
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromIssueContainingPayload(x interface{}) v1alpha1.Issue {
+	switch x := x.(type) {
+	case *issueEventPayload:
+		return v1alpha1.Issue{
+			Repo:      botModelFromRepoContainingPayload(x),
+			Number:    int(x.ObjectAttributes.IID),
+			Title:     x.ObjectAttributes.Title,
+			Author:    authorFromIDAndActor(x.ObjectAttributes.AuthorID, &x.User),
+			State:     issueStateFromMRState(x.ObjectAttributes.State),
+			URL:       x.ObjectAttributes.URL,
+			Body:      x.ObjectAttributes.Description,
+			Labels:    botModelFromLabels(x.Labels),
+			Assignees: botModelFromAssignees(x.Assignees),
+			CreatedAt: x.ObjectAttributes.CreatedAt.Time,
+			UpdatedAt: x.ObjectAttributes.UpdatedAt.Time,
+		}
+
+	case *commentEventPayload:
+		// the issue URL is not included in note hooks
+		return v1alpha1.Issue{
+			Repo:      botModelFromRepoContainingPayload(x),
+			Number:    int(x.Issue.IID),
+			Title:     x.Issue.Title,
+			Author:    authorFromIDAndActor(x.Issue.AuthorID, &x.User),
+			State:     issueStateFromMRState(x.Issue.State),
+			URL:       fmt.Sprintf("%s/-/issues/%d", x.Project.WebURL, x.Issue.IID),
+			Body:      x.Issue.Description,
+			Labels:    botModelFromLabels(x.Issue.Labels),
+			CreatedAt: x.Issue.CreatedAt.Time,
+			UpdatedAt: x.Issue.UpdatedAt.Time,
+		}
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromCommentContainingPayload(x interface{}) v1alpha1.Comment {
+	switch x := x.(type) {
+	case *commentEventPayload:
+		return v1alpha1.Comment{
+			Author:    botModelFromUser(&x.User),
+			Body:      x.ObjectAttributes.Note,
+			URL:       x.ObjectAttributes.URL,
+			CreatedAt: x.ObjectAttributes.CreatedAt.Time,
+			Path:      x.ObjectAttributes.Position.NewPath,
+			Line:      int(x.ObjectAttributes.Position.NewLine),
+		}
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromCIRunContainingPayload(x interface{}) v1alpha1.CIRun {
+	switch x := x.(type) {
+	case *gitlab.PipelineEventPayload:
+		run := v1alpha1.CIRun{
+			Repo:  botModelFromRepoContainingPayload(x),
+			PR:    botModelFromPRContainingPayload(x),
+			State: ciStateFromPipelineStatus(x.ObjectAttributes.Status),
+			SHA:   x.ObjectAttributes.SHA,
+			URL:   x.ObjectAttributes.Url,
+		}
+		// branch pipelines are not associated with any MR
+		if x.MergeRequest.ID != 0 {
+			run.PRs = []v1alpha1.PR{run.PR}
+		}
+		return run
+
+	default:
+		panic("should never happen")
+	}
+}
+
+func botModelFromUser(x *gitlab.User) v1alpha1.ForgeUser {
+	return v1alpha1.ForgeUser{
+		Forge:    forgeType,
+		UserName: x.UserName,
+	}
+}
+
+func botModelFromAssignees(x []gitlab.Assignee) []v1alpha1.ForgeUser {
+	result := make([]v1alpha1.ForgeUser, len(x))
+	for i, u := range x {
+		result[i] = v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: u.Username,
+		}
+	}
+	return result
+}
+
+func botModelFromCommits(x []gitlab.Commit) []v1alpha1.Commit {
+	result := make([]v1alpha1.Commit, len(x))
+	for i, c := range x {
+		result[i] = v1alpha1.Commit{
+			SHA:     c.ID,
+			Message: c.Message,
+			URL:     c.URL,
+			// GitLab does not match commit authors to users
+			Author: v1alpha1.ForgeUser{
+				Forge: forgeType,
+			},
+			AuthorName:  c.Author.Name,
+			AuthorEmail: c.Author.Email,
+			Timestamp:   c.Timestamp.Time,
+		}
+	}
+	return result
+}
+
+func botModelFromLabels(x []gitlab.Label) []string {
+	result := make([]string, len(x))
+	for i, l := range x {
+		result[i] = l.Title
+	}
+	return result
+}
+
+func botModelFromProject(x *gitlab.Project) v1alpha1.Repo {
+	// GitLab projects can be nested arbitrarily deep in groups, treat the
+	// whole namespace path as the owner.
+	namespace, name := "", x.PathWithNamespace
+	if idx := strings.LastIndexByte(x.PathWithNamespace, '/'); idx >= 0 {
+		namespace = x.PathWithNamespace[:idx]
+		name = x.PathWithNamespace[idx+1:]
+	}
+
+	return v1alpha1.Repo{
+		User: v1alpha1.ForgeUser{
+			Forge:    forgeType,
+			UserName: namespace,
+		},
+		RepoName: name,
+	}
+}
+
+func botModelFromAPIMR(repo v1alpha1.Repo, x *apiMergeRequest) v1alpha1.PR {
+	return v1alpha1.PR{
+		Repo:   repo,
+		Number: x.IID,
+		Title:  x.Title,
+		Author: botModelFromAPIUser(&x.Author),
+		State:  issueStateFromMRState(x.State),
+		URL:    x.WebURL,
+		Body:   x.Description,
+
+		BaseBranch: x.TargetBranch,
+		HeadBranch: x.SourceBranch,
+		HeadSHA:    x.SHA,
+		IsDraft:    x.Draft,
+
+		Labels:             x.Labels,
+		Assignees:          botModelFromAPIUsers(x.Assignees),
+		RequestedReviewers: botModelFromAPIUsers(x.Reviewers),
+
+		CreatedAt: x.CreatedAt,
+		UpdatedAt: x.UpdatedAt,
+	}
+}
+
+func botModelFromAPIUser(x *apiUser) v1alpha1.ForgeUser {
+	return v1alpha1.ForgeUser{
+		Forge:    forgeType,
+		UserName: x.Username,
+	}
+}
+
+func botModelFromAPIUsers(x []apiUser) []v1alpha1.ForgeUser {
+	result := make([]v1alpha1.ForgeUser, len(x))
+	for i := range x {
+		result[i] = botModelFromAPIUser(&x[i])
+	}
+	return result
+}
+
+func botModelFromAPICommitStatus(x *apiCommitStatus) v1alpha1.Check {
+	return v1alpha1.Check{
+		Name:  x.Name,
+		State: ciStateFromPipelineStatus(x.Status),
+		URL:   x.TargetURL,
+	}
+}
+
+// projectFromRepo returns the full path of the repo's project, which is how
+// the API refers to projects in addition to their IDs.
+func projectFromRepo(repo v1alpha1.Repo) string {
+	return repo.User.UserName + "/" + repo.RepoName
+}
+
+// actionFromPayload returns the action of the raw event payload, which is
+// only present for MR, issue and release events.
+func actionFromPayload(payload []byte) string {
+	var x struct {
+		Action           string `json:"action"`
+		ObjectAttributes struct {
+			Action string `json:"action"`
+		} `json:"object_attributes"`
+	}
+	// best-effort
+	_ = json.Unmarshal(payload, &x)
+	if x.ObjectAttributes.Action != "" {
+		return x.ObjectAttributes.Action
+	}
+	return x.Action
+}
+
+// authorFromIDAndActor returns the author of a merge request or issue.
+//
+// GitLab hooks only carry the author's ID, so the author's user name is only
+// known if they happen to be the actor too. Otherwise it is looked up later in
+// ResolveEvent.
+func authorFromIDAndActor(authorID int64, actor *gitlab.User) v1alpha1.ForgeUser {
+	if actor.ID != 0 && actor.ID == authorID {
+		return botModelFromUser(actor)
+	}
+
+	return v1alpha1.ForgeUser{
+		Forge: forgeType,
+	}
+}
+
+func issueStateFromMRState(state string) v1alpha1.IssueState {
+	switch state {
+	case "opened", "locked":
+		return v1alpha1.IssueStateOpen
+	case "closed":
+		return v1alpha1.IssueStateClosed
+	case "merged":
+		return v1alpha1.IssueStateMerged
+	default:
+		return v1alpha1.IssueStateUnknown
+	}
+}
+
+// isNullSHA returns whether the SHA is all zeros, which GitLab uses for
+// denoting creation and deletion of refs in push hooks.
+func isNullSHA(sha string) bool {
+	return strings.Trim(sha, "0") == ""
+}
+
+// isFinishedPipelineStatus returns whether the pipeline status is final.
+func isFinishedPipelineStatus(status string) bool {
+	switch status {
+	case "success", "failed", "canceled", "skipped":
+		return true
+	default:
+		return false
+	}
+}
+
+func ciStateFromPipelineStatus(status string) v1alpha1.CIState {
+	switch status {
+	case "success":
+		return v1alpha1.CIStatePassed
+	case "failed":
+		return v1alpha1.CIStateFailed
+	case "canceled":
+		return v1alpha1.CIStateCanceled
+	case "skipped":
+		return v1alpha1.CIStateSkipped
+	case "created", "pending", "running":
+		return v1alpha1.CIStatePending
+	default:
+		return v1alpha1.CIStateUnknown
+	}
+}
+
+// commitStatusStateFromCIState maps CI states to the ones accepted by the
+// commit status API.
+func commitStatusStateFromCIState(state v1alpha1.CIState) string {
+	switch state {
+	case v1alpha1.CIStatePassed, v1alpha1.CIStateSkipped:
+		return "success"
+	case v1alpha1.CIStateFailed, v1alpha1.CIStateErrored:
+		return "failed"
+	case v1alpha1.CIStateCanceled:
+		return "canceled"
+	default:
+		return "pending"
+	}
+}
+
+func deploymentStateFromDeploymentStatus(status string) v1alpha1.DeploymentState {
+	switch status {
+	case "created":
+		return v1alpha1.DeploymentStatePending
+	case "running":
+		return v1alpha1.DeploymentStateInProgress
+	case "success":
+		return v1alpha1.DeploymentStateSuccess
+	case "failed":
+		return v1alpha1.DeploymentStateFailure
+	case "canceled":
+		return v1alpha1.DeploymentStateCanceled
+	default:
+		return v1alpha1.DeploymentStateUnknown
+	}
+}
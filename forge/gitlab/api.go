@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitlab
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const defaultAPIBaseURL = "https://gitlab.com/api/v4"
+
+// apiClient is a minimal GitLab REST API client.
+type apiClient struct {
+	httpClient *http.Client
+	baseURL    string
+	token      string
+}
+
+func newAPIClient(baseURL string, token string) *apiClient {
+	if baseURL == "" {
+		baseURL = defaultAPIBaseURL
+	}
+
+	return &apiClient{
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		token:      token,
+	}
+}
+
+type apiUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+type apiMergeRequest struct {
+	IID          int       `json:"iid"`
+	Title        string    `json:"title"`
+	Description  string    `json:"description"`
+	State        string    `json:"state"`
+	WebURL       string    `json:"web_url"`
+	Author       apiUser   `json:"author"`
+	SourceBranch string    `json:"source_branch"`
+	TargetBranch string    `json:"target_branch"`
+	SHA          string    `json:"sha"`
+	Draft        bool      `json:"draft"`
+	Labels       []string  `json:"labels"`
+	Assignees    []apiUser `json:"assignees"`
+	Reviewers    []apiUser `json:"reviewers"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+type apiApprovals struct {
+	ApprovedBy []struct {
+		User apiUser `json:"user"`
+	} `json:"approved_by"`
+}
+
+type apiCommitStatus struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	TargetURL   string `json:"target_url"`
+	Description string `json:"description"`
+}
+
+type apiCommitStatusOptions struct {
+	State       string `json:"state"`
+	Name        string `json:"name,omitempty"`
+	TargetURL   string `json:"target_url,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// getMR returns the MR of the given IID.
+func (c *apiClient) getMR(ctx context.Context, project string, iid int) (*apiMergeRequest, error) {
+	var result apiMergeRequest
+	err := c.get(ctx, projectPath(project, "/merge_requests/%d", iid), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// getApprovals returns the approval state of the given MR.
+func (c *apiClient) getApprovals(ctx context.Context, project string, iid int) (*apiApprovals, error) {
+	var result apiApprovals
+	err := c.get(ctx, projectPath(project, "/merge_requests/%d/approvals", iid), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// listCommitStatuses returns the statuses of the given commit, including
+// those of pipeline jobs.
+func (c *apiClient) listCommitStatuses(ctx context.Context, project string, sha string) ([]apiCommitStatus, error) {
+	var result []apiCommitStatus
+	err := c.get(
+		ctx,
+		projectPath(project, "/repository/commits/%s/statuses?per_page=100", url.PathEscape(sha)),
+		&result,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// getUser returns the user of the given ID.
+func (c *apiClient) getUser(ctx context.Context, id int64) (*apiUser, error) {
+	var result apiUser
+	err := c.get(ctx, fmt.Sprintf("/users/%d", id), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// findUserByUsername returns the user of the given user name.
+func (c *apiClient) findUserByUsername(ctx context.Context, username string) (*apiUser, error) {
+	var result []apiUser
+	err := c.get(ctx, "/users?username="+url.QueryEscape(username), &result)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("gitlab: user %q not found", username)
+	}
+	return &result[0], nil
+}
+
+// createNote comments on the given MR or issue, kind being either
+// "merge_requests" or "issues".
+func (c *apiClient) createNote(ctx context.Context, project string, kind string, iid int, body string) error {
+	in := struct {
+		Body string `json:"body"`
+	}{body}
+	return c.do(ctx, http.MethodPost, projectPath(project, "/%s/%d/notes", kind, iid), &in, nil)
+}
+
+// update updates the given MR or issue, kind being either "merge_requests" or
+// "issues".
+func (c *apiClient) update(ctx context.Context, project string, kind string, iid int, in interface{}) error {
+	return c.do(ctx, http.MethodPut, projectPath(project, "/%s/%d", kind, iid), in, nil)
+}
+
+func (c *apiClient) createCommitStatus(
+	ctx context.Context,
+	project string,
+	sha string,
+	opts *apiCommitStatusOptions,
+) error {
+	return c.do(ctx, http.MethodPost, projectPath(project, "/statuses/%s", url.PathEscape(sha)), opts, nil)
+}
+
+// projectPath returns the API path under the given project, with the format
+// applied to args for the rest. Projects are referred to by their full paths.
+func projectPath(project string, format string, args ...interface{}) string {
+	return "/projects/" + url.PathEscape(project) + fmt.Sprintf(format, args...)
+}
+
+func (c *apiClient) get(ctx context.Context, path string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, path, nil, out)
+}
+
+// do sends an API request with in as the JSON body if non-nil, decoding the
+// response into out if non-nil.
+func (c *apiClient) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("PRIVATE-TOKEN", c.token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("GitLab API returned status %d for %s %s", resp.StatusCode, method, path)
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
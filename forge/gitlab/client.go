@@ -0,0 +1,196 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+var (
+	ErrTokenRequired     = errors.New("gitlab: token is required for API access")
+	ErrUnsupportedTarget = errors.New("gitlab: unsupported target type")
+)
+
+type gitlabClient struct {
+	api *apiClient
+}
+
+var _ forge.IClient = (*gitlabClient)(nil)
+
+// NewClient returns a new GitLab API client for plugins to act on GitLab.
+//
+// The base URL is that of the REST API, like "https://gitlab.example.com/api/v4",
+// and defaults to the one of gitlab.com if empty.
+func NewClient(baseURL string, token string) (forge.IClient, error) {
+	if token == "" {
+		return nil, ErrTokenRequired
+	}
+
+	return &gitlabClient{
+		api: newAPIClient(baseURL, token),
+	}, nil
+}
+
+// PostComment comments on the given issue or MR.
+func (c *gitlabClient) PostComment(
+	ctx context.Context,
+	repo v1alpha1.Repo,
+	target v1alpha1.CommentTargetType,
+	number int,
+	body string,
+) error {
+	kind, err := kindFromTargetType(target)
+	if err != nil {
+		return err
+	}
+
+	return c.api.createNote(ctx, projectFromRepo(repo), kind, number, body)
+}
+
+// AddLabels adds labels to the given issue or MR.
+func (c *gitlabClient) AddLabels(
+	ctx context.Context,
+	repo v1alpha1.Repo,
+	target v1alpha1.CommentTargetType,
+	number int,
+	labels []string,
+) error {
+	kind, err := kindFromTargetType(target)
+	if err != nil {
+		return err
+	}
+
+	in := struct {
+		AddLabels string `json:"add_labels"`
+	}{strings.Join(labels, ",")}
+	return c.api.update(ctx, projectFromRepo(repo), kind, number, &in)
+}
+
+// RemoveLabels removes labels from the given issue or MR, ignoring those not
+// present.
+func (c *gitlabClient) RemoveLabels(
+	ctx context.Context,
+	repo v1alpha1.Repo,
+	target v1alpha1.CommentTargetType,
+	number int,
+	labels []string,
+) error {
+	kind, err := kindFromTargetType(target)
+	if err != nil {
+		return err
+	}
+
+	in := struct {
+		RemoveLabels string `json:"remove_labels"`
+	}{strings.Join(labels, ",")}
+	return c.api.update(ctx, projectFromRepo(repo), kind, number, &in)
+}
+
+// RequestReviewers adds the given users to the MR's reviewers.
+func (c *gitlabClient) RequestReviewers(ctx context.Context, repo v1alpha1.Repo, number int, reviewers []string) error {
+	project := projectFromRepo(repo)
+
+	// Reviewers can only be set as a whole by their IDs.
+	mr, err := c.api.getMR(ctx, project, number)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]int64, 0, len(mr.Reviewers)+len(reviewers))
+	for _, u := range mr.Reviewers {
+		ids = append(ids, u.ID)
+	}
+	for _, username := range reviewers {
+		u, err := c.api.findUserByUsername(ctx, username)
+		if err != nil {
+			return err
+		}
+		ids = append(ids, u.ID)
+	}
+
+	in := struct {
+		ReviewerIDs []int64 `json:"reviewer_ids"`
+	}{ids}
+	return c.api.update(ctx, project, "merge_requests", number, &in)
+}
+
+// SetCommitStatus sets a commit status on the given commit.
+func (c *gitlabClient) SetCommitStatus(
+	ctx context.Context,
+	repo v1alpha1.Repo,
+	sha string,
+	status v1alpha1.CommitStatus,
+) error {
+	return c.api.createCommitStatus(
+		ctx,
+		projectFromRepo(repo),
+		sha,
+		&apiCommitStatusOptions{
+			State:       commitStatusStateFromCIState(status.State),
+			Name:        status.Context,
+			TargetURL:   status.URL,
+			Description: status.Description,
+		},
+	)
+}
+
+// GetPR returns details of the given MR.
+func (c *gitlabClient) GetPR(ctx context.Context, repo v1alpha1.Repo, number int) (*v1alpha1.PR, error) {
+	mr, err := c.api.getMR(ctx, projectFromRepo(repo), number)
+	if err != nil {
+		return nil, err
+	}
+
+	result := botModelFromAPIMR(repo, mr)
+	return &result, nil
+}
+
+// ListReviews returns the approvals of the given MR, which are the only kind
+// of reviews GitLab tracks.
+func (c *gitlabClient) ListReviews(ctx context.Context, repo v1alpha1.Repo, number int) ([]v1alpha1.Review, error) {
+	approvals, err := c.api.getApprovals(ctx, projectFromRepo(repo), number)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]v1alpha1.Review, len(approvals.ApprovedBy))
+	for i := range approvals.ApprovedBy {
+		result[i] = v1alpha1.Review{
+			Author: botModelFromAPIUser(&approvals.ApprovedBy[i].User),
+			Type:   v1alpha1.ReviewTypeApprove,
+		}
+	}
+	return result, nil
+}
+
+// ListChecks returns the commit statuses of the given commit, including those
+// of pipeline jobs.
+func (c *gitlabClient) ListChecks(ctx context.Context, repo v1alpha1.Repo, sha string) ([]v1alpha1.Check, error) {
+	statuses, err := c.api.listCommitStatuses(ctx, projectFromRepo(repo), sha)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]v1alpha1.Check, len(statuses))
+	for i := range statuses {
+		result[i] = botModelFromAPICommitStatus(&statuses[i])
+	}
+	return result, nil
+}
+
+// kindFromTargetType returns the API path component of the target type.
+func kindFromTargetType(target v1alpha1.CommentTargetType) (string, error) {
+	switch target {
+	case v1alpha1.CommentTargetTypeIssue:
+		return "issues", nil
+	case v1alpha1.CommentTargetTypePR:
+		return "merge_requests", nil
+	default:
+		return "", ErrUnsupportedTarget
+	}
+}
@@ -0,0 +1,291 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitlab
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge/internal/apitest"
+)
+
+const testToken = "glpat-0123456789abcdef"
+
+// newTestServer starts a stand-in API server expecting exactly the given calls
+// in order, returning its API base URL. The paths of calls are relative to the
+// API base URL.
+func newTestServer(t *testing.T, calls ...apitest.Call) string {
+	t.Helper()
+
+	for i := range calls {
+		calls[i].Path = "/api/v4" + calls[i].Path
+	}
+	return apitest.NewServer(t, "PRIVATE-TOKEN", testToken, calls...) + "/api/v4/"
+}
+
+// newTestClient returns a client talking to a stand-in server, which expects
+// exactly the given calls in order.
+func newTestClient(t *testing.T, calls ...apitest.Call) *gitlabClient {
+	t.Helper()
+
+	client, err := NewClient(newTestServer(t, calls...), testToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client.(*gitlabClient)
+}
+
+func TestClientPostComment(t *testing.T) {
+	testcases := []struct {
+		target v1alpha1.CommentTargetType
+		path   string
+	}{
+		{v1alpha1.CommentTargetTypeIssue, "/projects/acme%2Fwidget/issues/17/notes"},
+		{v1alpha1.CommentTargetTypePR, "/projects/acme%2Fwidget/merge_requests/17/notes"},
+	}
+
+	for _, tc := range testcases {
+		c := newTestClient(t, apitest.Call{
+			Method: http.MethodPost,
+			Path:   tc.path,
+			Body:   `{"body":"LGTM"}`,
+			Status: http.StatusCreated,
+		})
+
+		err := c.PostComment(context.Background(), testRepo, tc.target, 17, "LGTM")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestClientPostCommentUnsupportedTarget(t *testing.T) {
+	c := newTestClient(t)
+
+	err := c.PostComment(context.Background(), testRepo, v1alpha1.CommentTargetTypeUnknown, 17, "LGTM")
+	if !errors.Is(err, ErrUnsupportedTarget) {
+		t.Fatalf("want ErrUnsupportedTarget, got %v", err)
+	}
+}
+
+func TestClientAddLabels(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodPut,
+		Path:   "/projects/acme%2Fwidget/merge_requests/17",
+		Body:   `{"add_labels":"bug,needs review"}`,
+	})
+
+	labels := []string{"bug", "needs review"}
+	err := c.AddLabels(context.Background(), testRepo, v1alpha1.CommentTargetTypePR, 17, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientRemoveLabels(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodPut,
+		Path:   "/projects/acme%2Fwidget/issues/17",
+		Body:   `{"remove_labels":"bug,needs review"}`,
+	})
+
+	labels := []string{"bug", "needs review"}
+	err := c.RemoveLabels(context.Background(), testRepo, v1alpha1.CommentTargetTypeIssue, 17, labels)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientRequestReviewers(t *testing.T) {
+	c := newTestClient(
+		t,
+		apitest.Call{
+			Method:   http.MethodGet,
+			Path:     "/projects/acme%2Fwidget/merge_requests/17",
+			Response: `{"iid": 17, "reviewers": [{"id": 4, "username": "carol"}]}`,
+		},
+		apitest.Call{
+			Method:   http.MethodGet,
+			Path:     "/users?username=dave",
+			Response: `[{"id": 5, "username": "dave"}]`,
+		},
+		apitest.Call{
+			Method: http.MethodPut,
+			Path:   "/projects/acme%2Fwidget/merge_requests/17",
+			Body:   `{"reviewer_ids":[4,5]}`,
+		},
+	)
+
+	err := c.RequestReviewers(context.Background(), testRepo, 17, []string{"dave"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientRequestReviewersUnknownUser(t *testing.T) {
+	c := newTestClient(
+		t,
+		apitest.Call{
+			Method:   http.MethodGet,
+			Path:     "/projects/acme%2Fwidget/merge_requests/17",
+			Response: `{"iid": 17, "reviewers": []}`,
+		},
+		apitest.Call{
+			Method:   http.MethodGet,
+			Path:     "/users?username=nobody",
+			Response: `[]`,
+		},
+	)
+
+	err := c.RequestReviewers(context.Background(), testRepo, 17, []string{"nobody"})
+	if err == nil {
+		t.Fatal("want error, got nil")
+	}
+}
+
+func TestClientSetCommitStatus(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodPost,
+		Path:   "/projects/acme%2Fwidget/statuses/5f1c0e7b",
+		Body: `{
+			"state": "failed",
+			"name": "ci/test",
+			"target_url": "https://ci.example.com/builds/42",
+			"description": "2 tests failed"
+		}`,
+		Status: http.StatusCreated,
+	})
+
+	err := c.SetCommitStatus(context.Background(), testRepo, "5f1c0e7b", v1alpha1.CommitStatus{
+		State:       v1alpha1.CIStateErrored,
+		Context:     "ci/test",
+		Description: "2 tests failed",
+		URL:         "https://ci.example.com/builds/42",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientGetPR(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/projects/acme%2Fwidget/merge_requests/17",
+		Response: `{
+			"iid": 17,
+			"title": "Draft: Retry uploads on transient errors",
+			"description": "Retries the upload on transient errors.",
+			"state": "opened",
+			"web_url": "https://gitlab.example.com/acme/widget/-/merge_requests/17",
+			"author": {"id": 2, "username": "alice"},
+			"source_branch": "fix-upload-retry",
+			"target_branch": "main",
+			"sha": "5f1c0e7b",
+			"draft": true,
+			"labels": ["bug"],
+			"assignees": [{"id": 3, "username": "bob"}],
+			"reviewers": [{"id": 4, "username": "carol"}],
+			"created_at": "2023-09-12T08:15:03.000Z",
+			"updated_at": "2023-09-12T09:02:41.000Z"
+		}`,
+	})
+
+	got, err := c.GetPR(context.Background(), testRepo, 17)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &v1alpha1.PR{
+		Repo:   testRepo,
+		Number: 17,
+		Title:  "Draft: Retry uploads on transient errors",
+		Author: v1alpha1.ForgeUser{Forge: forgeType, UserName: "alice"},
+		State:  v1alpha1.IssueStateOpen,
+		URL:    "https://gitlab.example.com/acme/widget/-/merge_requests/17",
+		Body:   "Retries the upload on transient errors.",
+
+		BaseBranch: "main",
+		HeadBranch: "fix-upload-retry",
+		HeadSHA:    "5f1c0e7b",
+		IsDraft:    true,
+
+		Labels:             []string{"bug"},
+		Assignees:          []v1alpha1.ForgeUser{{Forge: forgeType, UserName: "bob"}},
+		RequestedReviewers: []v1alpha1.ForgeUser{{Forge: forgeType, UserName: "carol"}},
+
+		CreatedAt: time.Date(2023, 9, 12, 8, 15, 3, 0, time.UTC),
+		UpdatedAt: time.Date(2023, 9, 12, 9, 2, 41, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestClientListReviews(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/projects/acme%2Fwidget/merge_requests/17/approvals",
+		Response: `{
+			"approved_by": [
+				{"user": {"id": 4, "username": "carol"}},
+				{"user": {"id": 5, "username": "dave"}}
+			]
+		}`,
+	})
+
+	got, err := c.ListReviews(context.Background(), testRepo, 17)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []v1alpha1.Review{
+		{
+			Author: v1alpha1.ForgeUser{Forge: forgeType, UserName: "carol"},
+			Type:   v1alpha1.ReviewTypeApprove,
+		},
+		{
+			Author: v1alpha1.ForgeUser{Forge: forgeType, UserName: "dave"},
+			Type:   v1alpha1.ReviewTypeApprove,
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestClientListChecks(t *testing.T) {
+	c := newTestClient(t, apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/projects/acme%2Fwidget/repository/commits/5f1c0e7b/statuses?per_page=100",
+		Response: `[
+			{
+				"name": "build",
+				"status": "success",
+				"target_url": "https://gitlab.example.com/acme/widget/-/jobs/1"
+			},
+			{
+				"name": "test",
+				"status": "running",
+				"target_url": "https://gitlab.example.com/acme/widget/-/jobs/2"
+			}
+		]`,
+	})
+
+	got, err := c.ListChecks(context.Background(), testRepo, "5f1c0e7b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []v1alpha1.Check{
+		{Name: "build", State: v1alpha1.CIStatePassed, URL: "https://gitlab.example.com/acme/widget/-/jobs/1"},
+		{Name: "test", State: v1alpha1.CIStatePending, URL: "https://gitlab.example.com/acme/widget/-/jobs/2"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -3,6 +3,9 @@
 package gitlab
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/go-playground/webhooks/v6/gitlab"
@@ -11,17 +14,25 @@ import (
 	"github.com/xen0n/brickbot/forge"
 )
 
-//nolint:deadcode,unused,varcheck // pending rework
 const forgeType = "gitlab"
 
+// releaseEvents is the release hook event, which the upstream library does
+// not know about.
+const releaseEvents gitlab.Event = "Release Hook"
+
 type gitlabForge struct {
 	hook *gitlab.Webhook
+	api  *apiClient
 }
 
 var _ forge.IForgeHook = (*gitlabForge)(nil)
 
 // New returns a new GitLab forge hook instance.
-func New(secret string) (forge.IForgeHook, error) {
+//
+// The API is used for looking up the authors of MRs and issues, which hooks
+// only carry the IDs of. The base URL defaults to the one of gitlab.com if
+// empty, and the token can be left empty if all users are public.
+func New(secret string, token string, apiBaseURL string) (forge.IForgeHook, error) {
 	hook, err := gitlab.New(
 		gitlab.Options.Secret(secret),
 	)
@@ -31,11 +42,28 @@ func New(secret string) (forge.IForgeHook, error) {
 
 	return &gitlabForge{
 		hook: hook,
+		api:  newAPIClient(apiBaseURL, token),
 	}, nil
 }
 
 // HookRequest hooks an incoming webhook request to trigger actions.
 func (f *gitlabForge) HookRequest(req *http.Request) (*v1alpha1.Event, error) {
+	body, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	e, err := f.hookRequest(req, body)
+	if e != nil {
+		e.SetDeliveryID(req.Header.Get("X-Gitlab-Event-UUID"))
+		e.SetForgeEvent(req.Header.Get("X-Gitlab-Event"), actionFromPayload(body))
+		e.SetRawPayload(body)
+	}
+	return e, err
+}
+
+func (f *gitlabForge) hookRequest(req *http.Request, body []byte) (*v1alpha1.Event, error) {
 	payload, err := f.hook.Parse(
 		req,
 		// XXX This is everything for now, I don't know exactly what GitLab is
@@ -45,18 +73,226 @@ func (f *gitlabForge) HookRequest(req *http.Request) (*v1alpha1.Event, error) {
 		gitlab.IssuesEvents,
 		gitlab.ConfidentialIssuesEvents,
 		gitlab.CommentEvents,
+		gitlab.ConfidentialCommentEvents,
 		gitlab.MergeRequestEvents,
 		gitlab.WikiPageEvents,
 		gitlab.PipelineEvents,
 		gitlab.BuildEvents,
 		gitlab.JobEvents,
 		gitlab.SystemHookEvents,
+		gitlab.DeploymentEvents,
 	)
 	if err != nil {
+		// The secret token is checked before the event type, so release
+		// hooks making it this far are authentic.
+		if err == gitlab.ErrEventNotFound && gitlab.Event(req.Header.Get("X-Gitlab-Event")) == releaseEvents {
+			return f.hookRelease(body)
+		}
 		return nil, err
 	}
 
-	// TODO
-	_ = payload
+	switch p := payload.(type) {
+	case gitlab.MergeRequestEventPayload:
+		var mr mergeRequestEventPayload
+		err := json.Unmarshal(body, &mr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch mr.ObjectAttributes.Action {
+		case "open", "reopen":
+			params := intoPROpenedParams(&mr)
+			return params.IntoEvent(), nil
+
+		case "close":
+			params := intoPRClosedParams(&mr)
+			return params.IntoEvent(), nil
+
+		case "merge":
+			params := intoPRMergedParams(&mr)
+			return params.IntoEvent(), nil
+
+		case "approved":
+			params := intoPRReviewedParams(&mr, v1alpha1.ReviewTypeApprove)
+			return params.IntoEvent(), nil
+
+		case "update":
+			// One update can change several things at once, e.g. the title
+			// and the reviewers when editing the MR, but only one event is
+			// returned per hook, so only the first change in the order below
+			// is reported.
+			//
+			// Toggling the draft status also changes the title (by adding or
+			// removing the "Draft: " prefix), so check for it first.
+			if c := mr.draftChange(); c != nil {
+				if c.Current {
+					params := intoPRWithdrawnParams(&mr)
+					return params.IntoEvent(), nil
+				}
+
+				params := intoPRReadyParams(&mr)
+				return params.IntoEvent(), nil
+			}
+
+			if mr.Changes.Title != nil {
+				params := intoPRRenamedParams(&mr, mr.Changes.Title.Previous)
+				return params.IntoEvent(), nil
+			}
+
+			if c := mr.Changes.Reviewers; c != nil {
+				if added := c.added(); len(added) > 0 {
+					params := intoPRReviewRequestedParams(&mr, added)
+					return params.IntoEvent(), nil
+				}
+
+				if removed := c.removed(); len(removed) > 0 {
+					params := intoPRReviewRequestRemovedParams(&mr, removed)
+					return params.IntoEvent(), nil
+				}
+			}
+
+			// Other updates are not interesting
+			return nil, nil
+
+		default:
+			// Currently no bot event for this action
+			return nil, nil
+		}
+
+	case gitlab.IssueEventPayload, gitlab.ConfidentialIssueEventPayload:
+		var issue issueEventPayload
+		err := json.Unmarshal(body, &issue)
+		if err != nil {
+			return nil, err
+		}
+
+		switch issue.ObjectAttributes.Action {
+		case "open":
+			params := intoIssueOpenedParams(&issue)
+			return params.IntoEvent(), nil
+
+		case "close":
+			params := intoIssueClosedParams(&issue)
+			return params.IntoEvent(), nil
+
+		case "reopen":
+			params := intoIssueReopenedParams(&issue)
+			return params.IntoEvent(), nil
+
+		case "update":
+			// As with MRs, only the first change in the order below is
+			// reported if several are made at once.
+			if issue.Changes.Title != nil {
+				params := intoIssueRenamedParams(&issue, issue.Changes.Title.Previous)
+				return params.IntoEvent(), nil
+			}
+
+			if c := issue.Changes.Assignees; c != nil {
+				if added := c.added(); len(added) > 0 {
+					params := intoIssueAssignedParams(&issue, added)
+					return params.IntoEvent(), nil
+				}
+			}
+
+			if c := issue.Changes.Labels; c != nil {
+				if added := c.added(); len(added) > 0 {
+					params := intoIssueLabeledParams(&issue, added)
+					return params.IntoEvent(), nil
+				}
+			}
+
+			// Other updates are not interesting
+			return nil, nil
+
+		default:
+			// Currently no bot event for this action
+			return nil, nil
+		}
+
+	case gitlab.CommentEventPayload, gitlab.ConfidentialCommentEventPayload:
+		var comment commentEventPayload
+		err := json.Unmarshal(body, &comment)
+		if err != nil {
+			return nil, err
+		}
+
+		if comment.ObjectAttributes.System {
+			// Notes generated by GitLab itself, e.g. "changed the title"
+			return nil, nil
+		}
+
+		switch comment.ObjectAttributes.NotebookType {
+		case "Issue", "MergeRequest":
+			params := intoCommentCreatedParams(&comment)
+			return params.IntoEvent(), nil
+
+		default:
+			// Comments on commits and snippets are not interesting
+			return nil, nil
+		}
+
+	case gitlab.PushEventPayload:
+		switch {
+		case isNullSHA(p.Before):
+			params := intoBranchCreatedParams(&p)
+			return params.IntoEvent(), nil
+
+		case isNullSHA(p.After):
+			params := intoBranchDeletedParams(&p)
+			return params.IntoEvent(), nil
+
+		default:
+			params := intoPushParams(&p)
+			return params.IntoEvent(), nil
+		}
+
+	case gitlab.TagEventPayload:
+		if !isNullSHA(p.Before) {
+			// Tag deletions and updates are not interesting
+			return nil, nil
+		}
+
+		params := intoTagCreatedParams(&p)
+		return params.IntoEvent(), nil
+
+	case gitlab.PipelineEventPayload:
+		if !isFinishedPipelineStatus(p.ObjectAttributes.Status) {
+			// Pipeline still in progress
+			return nil, nil
+		}
+
+		params := intoCIFinishedParams(&p)
+		return params.IntoEvent(), nil
+
+	case gitlab.DeploymentEventPayload:
+		var deployment deploymentEventPayload
+		err := json.Unmarshal(body, &deployment)
+		if err != nil {
+			return nil, err
+		}
+
+		// Deployments are created with the "running" status, so there is no
+		// separate creation event.
+		params := intoDeploymentStatusChangedParams(&deployment)
+		return params.IntoEvent(), nil
+	}
+
+	// Currently not handled
 	return nil, nil
 }
+
+func (f *gitlabForge) hookRelease(body []byte) (*v1alpha1.Event, error) {
+	var release releaseEventPayload
+	err := json.Unmarshal(body, &release)
+	if err != nil {
+		return nil, err
+	}
+
+	if release.Action != "create" || release.UpcomingRelease {
+		// Release updates and deletions are not interesting
+		return nil, nil
+	}
+
+	params := intoReleasePublishedParams(&release)
+	return params.IntoEvent(), nil
+}
@@ -0,0 +1,234 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitlab
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+const testSecret = "Sup3rS3cr3tStr1ng"
+
+// hookFixture feeds the recorded hook payload in testdata to a new forge hook
+// instance.
+func hookFixture(t *testing.T, event string, name string) *v1alpha1.Event {
+	t.Helper()
+
+	fh, err := New(testSecret, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hookFixtureWith(t, fh, event, name)
+}
+
+// hookFixtureWith is like hookFixture, but with the given forge hook.
+func hookFixtureWith(t *testing.T, fh forge.IForgeHook, event string, name string) *v1alpha1.Event {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/gitlab", bytes.NewReader(body))
+	req.Header.Set("X-Gitlab-Event", event)
+	req.Header.Set("X-Gitlab-Token", testSecret)
+	req.Header.Set("X-Gitlab-Event-UUID", "0b5f3a8e-4c1d-4e0f-9a57-3f1c2d7e8b90")
+
+	e, err := fh.HookRequest(req)
+	if err != nil {
+		t.Fatalf("HookRequest: %v", err)
+	}
+	return e
+}
+
+var testRepo = v1alpha1.Repo{
+	User: v1alpha1.ForgeUser{
+		Forge:    forgeType,
+		UserName: "acme",
+	},
+	RepoName: "widget",
+}
+
+// testPR returns the PR in the fixtures, in the given state.
+func testPR(state v1alpha1.IssueState, title string, isDraft bool, updatedAt time.Time) v1alpha1.PR {
+	return v1alpha1.PR{
+		Repo:   testRepo,
+		Number: 17,
+		Title:  title,
+		Author: v1alpha1.ForgeUser{Forge: forgeType, UserName: "alice"},
+		State:  state,
+		URL:    "https://gitlab.example.com/acme/widget/-/merge_requests/17",
+		Body:   "Retries the upload on transient errors.",
+
+		BaseBranch: "main",
+		HeadBranch: "fix-upload-retry",
+		HeadSHA:    "5f1c0e7b6d3a8e4f2b9c1d0a7e6f5b4c3d2e1f0a",
+		IsDraft:    isDraft,
+
+		Labels:             []string{"bug"},
+		Assignees:          []v1alpha1.ForgeUser{{Forge: forgeType, UserName: "bob"}},
+		RequestedReviewers: []v1alpha1.ForgeUser{{Forge: forgeType, UserName: "carol"}},
+
+		CreatedAt: time.Date(2023, 9, 12, 8, 15, 3, 0, time.UTC),
+		UpdatedAt: updatedAt,
+	}
+}
+
+// withUnknownAuthor returns the PR as seen in hooks acted on by others than
+// its author, which carry only the ID of the author, until ResolveEvent looks
+// it up.
+func withUnknownAuthor(pr v1alpha1.PR) v1alpha1.PR {
+	pr.Author = v1alpha1.ForgeUser{Forge: forgeType}
+	return pr
+}
+
+func TestMergeRequestHooks(t *testing.T) {
+	created := time.Date(2023, 9, 12, 8, 15, 3, 0, time.UTC)
+	updated := time.Date(2023, 9, 12, 9, 2, 41, 0, time.UTC)
+	const title = "Retry uploads on transient errors"
+
+	alice := v1alpha1.ForgeUser{Forge: forgeType, UserName: "alice"}
+	bob := v1alpha1.ForgeUser{Forge: forgeType, UserName: "bob"}
+	carol := v1alpha1.ForgeUser{Forge: forgeType, UserName: "carol"}
+
+	testcases := []struct {
+		fixture string
+		// want is the params of the expected event, or nil if no event is
+		// expected.
+		want interface{}
+	}{
+		{
+			fixture: "mr-open.json",
+			want: &v1alpha1.PROpenedParams{
+				Actor: alice,
+				PR:    testPR(v1alpha1.IssueStateOpen, title, false, created),
+			},
+		},
+		{
+			fixture: "mr-reopen.json",
+			want: &v1alpha1.PROpenedParams{
+				Actor: alice,
+				PR:    testPR(v1alpha1.IssueStateOpen, title, false, updated),
+			},
+		},
+		{
+			fixture: "mr-close.json",
+			want: &v1alpha1.PRClosedParams{
+				Actor: alice,
+				PR:    testPR(v1alpha1.IssueStateClosed, title, false, updated),
+			},
+		},
+		{
+			fixture: "mr-merge.json",
+			want: &v1alpha1.PRMergedParams{
+				Actor: bob,
+				PR:    withUnknownAuthor(testPR(v1alpha1.IssueStateMerged, title, false, updated)),
+			},
+		},
+		{
+			fixture: "mr-update-title.json",
+			want: &v1alpha1.PRRenamedParams{
+				Actor:    alice,
+				PR:       testPR(v1alpha1.IssueStateOpen, "Retry uploads on transient network errors", false, updated),
+				OldTitle: title,
+			},
+		},
+		{
+			// only the first of several changes is reported
+			fixture: "mr-update-title-reviewers.json",
+			want: &v1alpha1.PRRenamedParams{
+				Actor:    alice,
+				PR:       testPR(v1alpha1.IssueStateOpen, "Retry uploads on transient network errors", false, updated),
+				OldTitle: title,
+			},
+		},
+		{
+			// the title change from the draft prefix is not a rename
+			fixture: "mr-update-draft.json",
+			want: &v1alpha1.PRWithdrawnParams{
+				PR: testPR(v1alpha1.IssueStateOpen, "Draft: "+title, true, updated),
+			},
+		},
+		{
+			fixture: "mr-update-ready.json",
+			want: &v1alpha1.PRReadyParams{
+				Actor: alice,
+				PR:    testPR(v1alpha1.IssueStateOpen, title, false, updated),
+			},
+		},
+		{
+			fixture: "mr-update-labels.json",
+			want:    nil,
+		},
+		{
+			fixture: "mr-approved.json",
+			want: &v1alpha1.PRReviewedParams{
+				Actor:  carol,
+				PR:     withUnknownAuthor(testPR(v1alpha1.IssueStateOpen, title, false, updated)),
+				Review: v1alpha1.ReviewTypeApprove,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.fixture, func(t *testing.T) {
+			e := hookFixture(t, "Merge Request Hook", tc.fixture)
+			if tc.want == nil {
+				if e != nil {
+					t.Fatalf("want no event, got %+v", e)
+				}
+				return
+			}
+
+			if e == nil {
+				t.Fatal("want event, got none")
+			}
+			assertEventParams(t, e, tc.want)
+		})
+	}
+}
+
+// assertEventParams checks that the event is of the type of want, with params
+// equal to it.
+func assertEventParams(t *testing.T, e *v1alpha1.Event, want interface{}) {
+	t.Helper()
+
+	var got interface{}
+	var ok bool
+	switch want.(type) {
+	case *v1alpha1.PROpenedParams:
+		got, ok = e.PROpened()
+	case *v1alpha1.PRClosedParams:
+		got, ok = e.PRClosed()
+	case *v1alpha1.PRMergedParams:
+		got, ok = e.PRMerged()
+	case *v1alpha1.PRRenamedParams:
+		got, ok = e.PRRenamed()
+	case *v1alpha1.PRReviewedParams:
+		got, ok = e.PRReviewed()
+	case *v1alpha1.PRReadyParams:
+		got, ok = e.PRReady()
+	case *v1alpha1.PRWithdrawnParams:
+		got, ok = e.PRWithdrawn()
+	default:
+		t.Fatalf("unsupported params type %T", want)
+	}
+
+	if !ok {
+		t.Fatalf("want %T, got event of type %d", want, e.Type())
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("params mismatch\n got: %+v\nwant: %+v", got, want)
+	}
+}
@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitlab
+
+import (
+	"time"
+
+	"github.com/go-playground/webhooks/v6/gitlab"
+)
+
+// Supplementary payload types for fields missing from the upstream webhook
+// library's payload structs. These are decoded from the raw request body
+// after the upstream library has verified and parsed the request.
+
+type stringChange struct {
+	Previous string `json:"previous"`
+	Current  string `json:"current"`
+}
+
+type boolChange struct {
+	Previous bool `json:"previous"`
+	Current  bool `json:"current"`
+}
+
+type assigneesChange struct {
+	Previous []gitlab.Assignee `json:"previous"`
+	Current  []gitlab.Assignee `json:"current"`
+}
+
+// added returns the users present in Current but not in Previous.
+func (x *assigneesChange) added() []gitlab.Assignee {
+	return assigneesDifference(x.Current, x.Previous)
+}
+
+// removed returns the users present in Previous but not in Current.
+func (x *assigneesChange) removed() []gitlab.Assignee {
+	return assigneesDifference(x.Previous, x.Current)
+}
+
+func assigneesDifference(a []gitlab.Assignee, b []gitlab.Assignee) []gitlab.Assignee {
+	seen := make(map[string]struct{}, len(b))
+	for _, u := range b {
+		seen[u.Username] = struct{}{}
+	}
+
+	var result []gitlab.Assignee
+	for _, u := range a {
+		if _, ok := seen[u.Username]; !ok {
+			result = append(result, u)
+		}
+	}
+	return result
+}
+
+type labelsChange struct {
+	Previous []gitlab.Label `json:"previous"`
+	Current  []gitlab.Label `json:"current"`
+}
+
+// added returns the labels present in Current but not in Previous.
+func (x *labelsChange) added() []gitlab.Label {
+	seen := make(map[string]struct{}, len(x.Previous))
+	for _, l := range x.Previous {
+		seen[l.Title] = struct{}{}
+	}
+
+	var result []gitlab.Label
+	for _, l := range x.Current {
+		if _, ok := seen[l.Title]; !ok {
+			result = append(result, l)
+		}
+	}
+	return result
+}
+
+type mergeRequestChanges struct {
+	Title *stringChange `json:"title"`
+	Draft *boolChange   `json:"draft"`
+	// older GitLab versions call this "work_in_progress"
+	WorkInProgress *boolChange      `json:"work_in_progress"`
+	Labels         *labelsChange    `json:"labels"`
+	Reviewers      *assigneesChange `json:"reviewers"`
+}
+
+// mergeRequestEventPayload is gitlab.MergeRequestEventPayload with the
+// missing fields added.
+type mergeRequestEventPayload struct {
+	gitlab.MergeRequestEventPayload
+	Reviewers []gitlab.Assignee `json:"reviewers"`
+	// shadows the upstream field
+	Changes mergeRequestChanges `json:"changes"`
+}
+
+// draftChange returns the draft status change of the merge request if any.
+func (x *mergeRequestEventPayload) draftChange() *boolChange {
+	if x.Changes.Draft != nil {
+		return x.Changes.Draft
+	}
+	return x.Changes.WorkInProgress
+}
+
+type issueChanges struct {
+	Title     *stringChange    `json:"title"`
+	Assignees *assigneesChange `json:"assignees"`
+	Labels    *labelsChange    `json:"labels"`
+}
+
+// issueEventPayload is gitlab.IssueEventPayload with the missing fields
+// added.
+type issueEventPayload struct {
+	gitlab.IssueEventPayload
+	Labels []gitlab.Label `json:"labels"`
+	// shadows the upstream field
+	Changes issueChanges `json:"changes"`
+}
+
+// commentIssue is gitlab.Issue with the missing fields added.
+type commentIssue struct {
+	gitlab.Issue
+	Labels []gitlab.Label `json:"labels"`
+}
+
+// commentEventPayload is gitlab.CommentEventPayload with the missing fields
+// added.
+type commentEventPayload struct {
+	gitlab.CommentEventPayload
+	// shadows the upstream field
+	Issue commentIssue `json:"issue"`
+}
+
+// deploymentEventPayload is gitlab.DeploymentEventPayload with the missing
+// fields added.
+type deploymentEventPayload struct {
+	gitlab.DeploymentEventPayload
+	Ref string `json:"ref"`
+}
+
+// releaseEventPayload is the payload of release hooks, which the upstream
+// library does not support at all.
+type releaseEventPayload struct {
+	ObjectKind  string         `json:"object_kind"`
+	Action      string         `json:"action"`
+	Name        string         `json:"name"`
+	Tag         string         `json:"tag"`
+	Description string         `json:"description"`
+	URL         string         `json:"url"`
+	CreatedAt   time.Time      `json:"created_at"`
+	Project     gitlab.Project `json:"project"`
+	// upcoming releases are created with a release date in the future
+	UpcomingRelease bool `json:"upcoming_release"`
+}
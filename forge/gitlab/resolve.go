@@ -0,0 +1,157 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitlab
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge"
+)
+
+var _ forge.IEventResolver = (*gitlabForge)(nil)
+
+// NeedsResolving returns whether the event is to be completed with API
+// lookups, which is the case if the author of its MR or issue is unknown.
+//
+// GitLab hooks only carry the ID of the author, or for pipeline hooks not even
+// that, so the author's user name is only known from the hook if they happen
+// to be the actor too.
+func (f *gitlabForge) NeedsResolving(e *v1alpha1.Event) bool {
+	if params, ok := e.CIFinished(); ok {
+		return len(params.Run.PRs) > 0 && params.Run.PR.Author.UserName == ""
+	}
+
+	author := authorOfEvent(e)
+	return author != nil && author.UserName == "" && authorIDFromPayload(e.RawPayload()) != 0
+}
+
+// ResolveEvent fills in the author of the event's MR or issue. For CIFinished
+// events, the whole MR is looked up, as pipeline hooks carry only some of its
+// fields.
+func (f *gitlabForge) ResolveEvent(ctx context.Context, e *v1alpha1.Event) (*v1alpha1.Event, error) {
+	if params, ok := e.CIFinished(); ok {
+		run := &params.Run
+		if len(run.PRs) == 0 {
+			return e, nil
+		}
+
+		mr, err := f.api.getMR(ctx, projectFromRepo(run.Repo), run.PR.Number)
+		if err != nil {
+			return nil, err
+		}
+
+		// GitLab pipelines are associated with at most one MR
+		run.PR = botModelFromAPIMR(run.Repo, mr)
+		run.PRs = []v1alpha1.PR{run.PR}
+		return e, nil
+	}
+
+	author := authorOfEvent(e)
+	id := authorIDFromPayload(e.RawPayload())
+	if author == nil || id == 0 {
+		return e, nil
+	}
+
+	user, err := f.api.getUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	*author = botModelFromAPIUser(user)
+	return e, nil
+}
+
+// authorOfEvent returns the author field of the MR or issue of the event, or
+// nil if there is none.
+func authorOfEvent(e *v1alpha1.Event) *v1alpha1.ForgeUser {
+	switch e.Type() {
+	case v1alpha1.EventTypePROpened:
+		params, _ := e.PROpened()
+		return &params.PR.Author
+	case v1alpha1.EventTypePRClosed:
+		params, _ := e.PRClosed()
+		return &params.PR.Author
+	case v1alpha1.EventTypePRMerged:
+		params, _ := e.PRMerged()
+		return &params.PR.Author
+	case v1alpha1.EventTypePRRenamed:
+		params, _ := e.PRRenamed()
+		return &params.PR.Author
+	case v1alpha1.EventTypePRReviewed:
+		params, _ := e.PRReviewed()
+		return &params.PR.Author
+	case v1alpha1.EventTypePRReady:
+		params, _ := e.PRReady()
+		return &params.PR.Author
+	case v1alpha1.EventTypePRWithdrawn:
+		params, _ := e.PRWithdrawn()
+		return &params.PR.Author
+	case v1alpha1.EventTypePRReviewRequested:
+		params, _ := e.PRReviewRequested()
+		return &params.PR.Author
+	case v1alpha1.EventTypePRReviewRequestRemoved:
+		params, _ := e.PRReviewRequestRemoved()
+		return &params.PR.Author
+
+	case v1alpha1.EventTypeIssueOpened:
+		params, _ := e.IssueOpened()
+		return &params.Issue.Author
+	case v1alpha1.EventTypeIssueClosed:
+		params, _ := e.IssueClosed()
+		return &params.Issue.Author
+	case v1alpha1.EventTypeIssueReopened:
+		params, _ := e.IssueReopened()
+		return &params.Issue.Author
+	case v1alpha1.EventTypeIssueAssigned:
+		params, _ := e.IssueAssigned()
+		return &params.Issue.Author
+	case v1alpha1.EventTypeIssueLabeled:
+		params, _ := e.IssueLabeled()
+		return &params.Issue.Author
+	case v1alpha1.EventTypeIssueRenamed:
+		params, _ := e.IssueRenamed()
+		return &params.Issue.Author
+
+	case v1alpha1.EventTypeCommentCreated:
+		params, _ := e.CommentCreated()
+		switch params.Target {
+		case v1alpha1.CommentTargetTypeIssue:
+			return &params.Issue.Author
+		case v1alpha1.CommentTargetTypePR:
+			return &params.PR.Author
+		}
+	}
+
+	return nil
+}
+
+// authorIDFromPayload returns the ID of the author of the MR or issue of the
+// raw event payload, or 0 if there is none.
+func authorIDFromPayload(payload []byte) int64 {
+	var x struct {
+		ObjectAttributes struct {
+			AuthorID int64 `json:"author_id"`
+		} `json:"object_attributes"`
+		MergeRequest struct {
+			AuthorID int64 `json:"author_id"`
+		} `json:"merge_request"`
+		Issue struct {
+			AuthorID int64 `json:"author_id"`
+		} `json:"issue"`
+	}
+	// best-effort
+	_ = json.Unmarshal(payload, &x)
+
+	// The object attributes are those of the note in comment hooks, so look
+	// at the MR and issue first.
+	switch {
+	case x.MergeRequest.AuthorID != 0:
+		return x.MergeRequest.AuthorID
+	case x.Issue.AuthorID != 0:
+		return x.Issue.AuthorID
+	default:
+		return x.ObjectAttributes.AuthorID
+	}
+}
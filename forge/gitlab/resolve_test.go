@@ -0,0 +1,156 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package gitlab
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/forge/internal/apitest"
+)
+
+// newTestForge returns a forge hook talking to a stand-in server, which
+// expects exactly the given calls in order.
+func newTestForge(t *testing.T, calls ...apitest.Call) *gitlabForge {
+	t.Helper()
+
+	fh, err := New(testSecret, testToken, newTestServer(t, calls...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fh.(*gitlabForge)
+}
+
+// resolveFixture feeds the recorded hook payload in testdata to the forge
+// hook, and resolves the event if needed.
+func resolveFixture(t *testing.T, f *gitlabForge, event string, name string) *v1alpha1.Event {
+	t.Helper()
+
+	e := hookFixtureWith(t, f, event, name)
+	if e == nil {
+		t.Fatal("want event, got none")
+	}
+	if !f.NeedsResolving(e) {
+		return e
+	}
+
+	e, err := f.ResolveEvent(context.Background(), e)
+	if err != nil {
+		t.Fatalf("ResolveEvent: %v", err)
+	}
+	return e
+}
+
+func TestResolveAuthor(t *testing.T) {
+	updated := time.Date(2023, 9, 12, 9, 2, 41, 0, time.UTC)
+	const title = "Retry uploads on transient errors"
+
+	getAlice := apitest.Call{
+		Method:   http.MethodGet,
+		Path:     "/users/2",
+		Response: `{"id": 2, "username": "alice"}`,
+	}
+
+	testcases := []struct {
+		fixture string
+		calls   []apitest.Call
+		want    interface{}
+	}{
+		{
+			// acted on by the author, so nothing to look up
+			fixture: "mr-close.json",
+			want: &v1alpha1.PRClosedParams{
+				Actor: v1alpha1.ForgeUser{Forge: forgeType, UserName: "alice"},
+				PR:    testPR(v1alpha1.IssueStateClosed, title, false, updated),
+			},
+		},
+		{
+			fixture: "mr-merge.json",
+			calls:   []apitest.Call{getAlice},
+			want: &v1alpha1.PRMergedParams{
+				Actor: v1alpha1.ForgeUser{Forge: forgeType, UserName: "bob"},
+				PR:    testPR(v1alpha1.IssueStateMerged, title, false, updated),
+			},
+		},
+		{
+			fixture: "mr-approved.json",
+			calls:   []apitest.Call{getAlice},
+			want: &v1alpha1.PRReviewedParams{
+				Actor:  v1alpha1.ForgeUser{Forge: forgeType, UserName: "carol"},
+				PR:     testPR(v1alpha1.IssueStateOpen, title, false, updated),
+				Review: v1alpha1.ReviewTypeApprove,
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.fixture, func(t *testing.T) {
+			f := newTestForge(t, tc.calls...)
+			e := resolveFixture(t, f, "Merge Request Hook", tc.fixture)
+			assertEventParams(t, e, tc.want)
+		})
+	}
+}
+
+func TestResolvePipelineMR(t *testing.T) {
+	f := newTestForge(t, apitest.Call{
+		Method: http.MethodGet,
+		Path:   "/projects/acme%2Fwidget/merge_requests/17",
+		Response: `{
+			"iid": 17,
+			"title": "Retry uploads on transient errors",
+			"description": "Retries the upload on transient errors.",
+			"state": "opened",
+			"web_url": "https://gitlab.example.com/acme/widget/-/merge_requests/17",
+			"author": {"id": 2, "username": "alice"},
+			"source_branch": "fix-upload-retry",
+			"target_branch": "main",
+			"sha": "5f1c0e7b6d3a8e4f2b9c1d0a7e6f5b4c3d2e1f0a",
+			"labels": ["bug"],
+			"assignees": [{"id": 3, "username": "bob"}],
+			"reviewers": [{"id": 4, "username": "carol"}],
+			"created_at": "2023-09-12T08:15:03.000Z",
+			"updated_at": "2023-09-12T09:11:52.000Z"
+		}`,
+	})
+
+	e := resolveFixture(t, f, "Pipeline Hook", "pipeline-mr-failed.json")
+	params, ok := e.CIFinished()
+	if !ok {
+		t.Fatalf("want CIFinished event, got event of type %d", e.Type())
+	}
+
+	pr := testPR(
+		v1alpha1.IssueStateOpen,
+		"Retry uploads on transient errors",
+		false,
+		time.Date(2023, 9, 12, 9, 11, 52, 0, time.UTC),
+	)
+	want := v1alpha1.CIRun{
+		Repo:  testRepo,
+		PR:    pr,
+		PRs:   []v1alpha1.PR{pr},
+		State: v1alpha1.CIStateFailed,
+		SHA:   "5f1c0e7b6d3a8e4f2b9c1d0a7e6f5b4c3d2e1f0a",
+		URL:   "https://gitlab.example.com/acme/widget/-/pipelines/1187",
+	}
+	if !reflect.DeepEqual(params.Run, want) {
+		t.Errorf("run mismatch\n got: %+v\nwant: %+v", params.Run, want)
+	}
+}
+
+func TestResolveBranchPipeline(t *testing.T) {
+	f := newTestForge(t)
+
+	params := v1alpha1.CIFinishedParams{
+		Run: v1alpha1.CIRun{Repo: testRepo, State: v1alpha1.CIStatePassed},
+	}
+	if f.NeedsResolving(params.IntoEvent()) {
+		t.Error("want branch pipelines not resolved, as they have no MR")
+	}
+}
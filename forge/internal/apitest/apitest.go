@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package apitest provides a stand-in forge API server for testing API
+// clients.
+package apitest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Call is an API request expected by the stand-in server, along with the
+// response to it.
+type Call struct {
+	Method string
+	// Path is the escaped path, with the query if any.
+	Path string
+	// Body is the expected JSON body, or empty if there should be none.
+	Body string
+
+	// Status defaults to 200.
+	Status   int
+	Response string
+}
+
+// NewServer starts a stand-in API server expecting exactly the given calls in
+// order, each with the given header set to the given value for
+// authentication, returning its URL. The server is closed at the end of the
+// test.
+func NewServer(t *testing.T, authHeader string, authValue string, calls ...Call) string {
+	t.Helper()
+
+	var mu sync.Mutex
+	next := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if next >= len(calls) {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.RequestURI())
+			rw.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		c := calls[next]
+		next++
+
+		checkRequest(t, r, &c, authHeader, authValue)
+
+		status := c.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		rw.WriteHeader(status)
+		_, _ = io.WriteString(rw, c.Response)
+	}))
+	t.Cleanup(func() {
+		srv.Close()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if next != len(calls) {
+			t.Errorf("got %d requests, want %d", next, len(calls))
+		}
+	})
+
+	return srv.URL
+}
+
+func checkRequest(t *testing.T, r *http.Request, want *Call, authHeader string, authValue string) {
+	t.Helper()
+
+	uri := r.URL.RequestURI()
+	if r.Method != want.Method || uri != want.Path {
+		t.Errorf("got request %s %s, want %s %s", r.Method, uri, want.Method, want.Path)
+	}
+	if got := r.Header.Get(authHeader); got != authValue {
+		t.Errorf("%s %s: got %s %q, want %q", r.Method, uri, authHeader, got, authValue)
+	}
+
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Errorf("%s %s: reading body: %v", r.Method, uri, err)
+		return
+	}
+	if want.Body == "" {
+		if len(body) != 0 {
+			t.Errorf("%s %s: got body %s, want none", r.Method, uri, body)
+		}
+		return
+	}
+
+	if got := r.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("%s %s: got Content-Type %q, want application/json", r.Method, uri, got)
+	}
+
+	var gotBody, wantBody interface{}
+	if err := json.Unmarshal(body, &gotBody); err != nil {
+		t.Errorf("%s %s: got invalid JSON body %s: %v", r.Method, uri, body, err)
+		return
+	}
+	if err := json.Unmarshal([]byte(want.Body), &wantBody); err != nil {
+		t.Errorf("invalid expected body %s: %v", want.Body, err)
+		return
+	}
+	if !reflect.DeepEqual(gotBody, wantBody) {
+		t.Errorf("%s %s: got body %s, want %s", r.Method, uri, body, want.Body)
+	}
+}
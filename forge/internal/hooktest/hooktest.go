@@ -0,0 +1,72 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package hooktest provides helpers for testing forge hooks against recorded
+// payloads.
+package hooktest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// Params is implemented by the params of all bot events.
+type Params interface {
+	IntoEvent() *v1alpha1.Event
+}
+
+// Fixture returns the content of the named file in testdata.
+func Fixture(t *testing.T, name string) []byte {
+	t.Helper()
+
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// AssertEvent checks that the event is of the type of want, with params equal
+// to it, or that there is no event if want is nil. Event metadata is not
+// compared.
+func AssertEvent(t *testing.T, got *v1alpha1.Event, want Params) {
+	t.Helper()
+
+	if want == nil || reflect.ValueOf(want).IsNil() {
+		if got != nil {
+			t.Errorf("want no event, got %s", describe(got))
+		}
+		return
+	}
+	if got == nil {
+		t.Fatalf("want %T, got no event", want)
+	}
+
+	w := want.IntoEvent()
+	w.SetID(got.ID())
+	w.SetReceivedAt(got.ReceivedAt())
+	w.SetForgeInstance(got.ForgeInstance())
+	w.SetForgeEvent(got.ForgeEvent(), got.ForgeAction())
+	w.SetDeliveryID(got.DeliveryID())
+	w.SetRawPayload(got.RawPayload())
+
+	if !reflect.DeepEqual(got, w) {
+		t.Errorf("event mismatch\n got: %s\nwant: %s", describe(got), describe(w))
+	}
+}
+
+// describe returns the JSON of the event, without the raw payload for
+// brevity.
+func describe(e *v1alpha1.Event) string {
+	c := *e
+	c.SetRawPayload(nil)
+	b, err := json.Marshal(&c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
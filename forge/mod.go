@@ -3,9 +3,12 @@
 package forge
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 
 	"github.com/xen0n/brickbot/bot/v1alpha1"
+	"github.com/xen0n/brickbot/bot/v1alpha2"
 )
 
 // IForgeHook is the interface that all forge hooks implement.
@@ -13,3 +16,31 @@ type IForgeHook interface {
 	// HookRequest hooks an incoming webhook request to trigger actions.
 	HookRequest(req *http.Request) (*v1alpha1.Event, error)
 }
+
+// IEventResolver is optionally implemented by forge hooks whose events need
+// API lookups to complete. The lookups are done off the request path: such
+// events are first queued for ResolveEvent, and only the resolved events are
+// queued for the bot plugins.
+type IEventResolver interface {
+	// NeedsResolving returns whether the event from HookRequest is to be
+	// resolved with ResolveEvent.
+	NeedsResolving(e *v1alpha1.Event) bool
+	// ResolveEvent completes the event, or returns nil if it turns out to
+	// be of no interest. Failures are retried.
+	ResolveEvent(ctx context.Context, e *v1alpha1.Event) (*v1alpha1.Event, error)
+}
+
+// IClient is the interface that all forge API clients implement.
+type IClient = v1alpha2.IForgeClient
+
+// ActionFromPayload returns the top-level "action" field of the JSON payload,
+// which is where most forges put the action of events, or the empty string if
+// there is none.
+func ActionFromPayload(payload []byte) string {
+	var x struct {
+		Action string `json:"action"`
+	}
+	// best-effort
+	_ = json.Unmarshal(payload, &x)
+	return x.Action
+}
@@ -4,15 +4,16 @@ go 1.20
 
 require (
 	github.com/BurntSushi/toml v1.3.2
+	github.com/cenkalti/backoff/v4 v4.2.1
 	github.com/go-playground/webhooks/v6 v6.3.0
 	github.com/prometheus/client_golang v1.17.0
 	github.com/rs/zerolog v1.31.0
 	github.com/xen0n/go-workwx v1.6.0
+	go.etcd.io/bbolt v1.3.7
 )
 
 require (
 	github.com/beorn7/perks v1.0.1 // indirect
-	github.com/cenkalti/backoff/v4 v4.2.1 // indirect
 	github.com/cespare/xxhash/v2 v2.2.0 // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
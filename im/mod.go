@@ -2,7 +2,7 @@
 
 package im
 
-import "github.com/xen0n/brickbot/bot/v1alpha1"
+import "github.com/xen0n/brickbot/bot/v1alpha2"
 
 // IProvider is abstraction for IM backends.
-type IProvider = v1alpha1.IIMProvider
+type IProvider = v1alpha2.IIMProvider
@@ -3,6 +3,7 @@
 package wecom
 
 import (
+	"context"
 	"errors"
 
 	"github.com/xen0n/go-workwx"
@@ -10,6 +11,8 @@ import (
 	"github.com/xen0n/brickbot/im"
 )
 
+// wecomProvider sends messages through WeCom. Contexts are not honored, as
+// go-workwx does not support them.
 type wecomProvider struct {
 	app *workwx.WorkwxApp
 }
@@ -40,7 +43,7 @@ func New(
 	}, nil
 }
 
-func (p *wecomProvider) SendTextToPerson(userID string, text string) error {
+func (p *wecomProvider) SendTextToPerson(_ context.Context, userID string, text string) error {
 	rcpt := workwx.Recipient{
 		UserIDs: []string{userID},
 	}
@@ -53,7 +56,7 @@ func (p *wecomProvider) SendTextToPerson(userID string, text string) error {
 	return nil
 }
 
-func (p *wecomProvider) SendTextToChat(chatID string, text string) error {
+func (p *wecomProvider) SendTextToChat(_ context.Context, chatID string, text string) error {
 	rcpt := workwx.Recipient{
 		ChatID: chatID,
 	}
@@ -66,7 +69,7 @@ func (p *wecomProvider) SendTextToChat(chatID string, text string) error {
 	return nil
 }
 
-func (p *wecomProvider) SendMarkdownToPerson(userID string, md string) error {
+func (p *wecomProvider) SendMarkdownToPerson(_ context.Context, userID string, md string) error {
 	rcpt := workwx.Recipient{
 		UserIDs: []string{userID},
 	}
@@ -79,7 +82,7 @@ func (p *wecomProvider) SendMarkdownToPerson(userID string, md string) error {
 	return nil
 }
 
-func (p *wecomProvider) SendMarkdownToChat(chatID string, md string) error {
+func (p *wecomProvider) SendMarkdownToChat(_ context.Context, chatID string, md string) error {
 	rcpt := workwx.Recipient{
 		ChatID: chatID,
 	}
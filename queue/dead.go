@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package queue
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+var ErrDeadLetterNotFound = errors.New("queue: dead letter not found")
+
+// DeadLetter is an event that failed all attempts, or failed to decode.
+type DeadLetter struct {
+	// ID identifies the dead letter for re-driving.
+	ID uint64 `json:"id"`
+	// Event is nil if it fails to decode, in which case Raw is the event or
+	// the whole record as stored.
+	Event     *v1alpha1.Event `json:"event"`
+	Raw       []byte          `json:"raw,omitempty"`
+	Target    string          `json:"target,omitempty"`
+	Attempts  int             `json:"attempts"`
+	LastError string          `json:"last_error"`
+	FailedAt  time.Time       `json:"failed_at"`
+}
+
+// ListDeadLetters returns all dead-lettered events, oldest first, including
+// those failing to decode.
+func (q *Queue) ListDeadLetters() ([]DeadLetter, error) {
+	var result []DeadLetter
+	err := q.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketDead).ForEach(func(k, v []byte) error {
+			var r record
+			err := json.Unmarshal(v, &r)
+			if err != nil {
+				return err
+			}
+
+			l := DeadLetter{
+				ID:        seqFromKey(k),
+				Raw:       r.Raw,
+				Target:    r.Target,
+				Attempts:  r.Attempts,
+				LastError: r.LastError,
+				FailedAt:  r.FailedAt,
+			}
+
+			var e v1alpha1.Event
+			if json.Unmarshal(r.Event, &e) == nil {
+				l.Event = &e
+			} else if l.Raw == nil {
+				l.Raw = r.Event
+			}
+
+			result = append(result, l)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Redrive puts the dead-lettered event of the given ID back into the queue,
+// with a fresh number of attempts.
+func (q *Queue) Redrive(id uint64) error {
+	key := keyFromSeq(id)
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		dead := tx.Bucket(bucketDead)
+		v := dead.Get(key)
+		if v == nil {
+			return ErrDeadLetterNotFound
+		}
+
+		var r record
+		err := json.Unmarshal(v, &r)
+		if err != nil {
+			return err
+		}
+
+		err = dead.Delete(key)
+		if err != nil {
+			return err
+		}
+
+		// The ID is the event's original position in the queue, which is
+		// free as sequence numbers are never reused.
+		return putRecord(tx.Bucket(bucketPending), id, &record{Event: r.Event, Target: r.Target})
+	})
+	if err != nil {
+		return err
+	}
+
+	q.wakeUp()
+	return nil
+}
+
+// RedriveAll puts all dead-lettered events back into the queue, returning the
+// number of events re-driven.
+func (q *Queue) RedriveAll() (int, error) {
+	letters, err := q.ListDeadLetters()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, l := range letters {
+		err = q.Redrive(l.ID)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(letters), nil
+}
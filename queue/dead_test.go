@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// deadLetter dead-letters the first pending event, returning its ID.
+func deadLetter(t *testing.T, q *Queue) uint64 {
+	t.Helper()
+
+	opts := RunOptions{Retry: RetryPolicy{MaxAttempts: 1}}
+	item, _, err := q.next(nil, time.Now(), &opts)
+	if err != nil || item == nil {
+		t.Fatalf("next: got %v, %v", item, err)
+	}
+
+	err = q.finish(item, errors.New("boom"), &opts)
+	if err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	return item.seq
+}
+
+func TestRedrive(t *testing.T) {
+	q := newTestQueue(t)
+	enqueue(t, q, 1, "bot")
+	enqueue(t, q, 2, "bot")
+
+	id := deadLetter(t, q)
+	checkLen(t, q, 1)
+
+	err := q.Redrive(id)
+	if err != nil {
+		t.Fatalf("Redrive: %v", err)
+	}
+	checkLen(t, q, 2)
+
+	letters, err := q.ListDeadLetters()
+	if err != nil || len(letters) != 0 {
+		t.Errorf("ListDeadLetters: got %v, %v, want none", letters, err)
+	}
+
+	// back at its original position, with a fresh number of attempts
+	item, _, err := q.next(nil, time.Now(), &RunOptions{})
+	if err != nil || item == nil {
+		t.Fatalf("next: got %v, %v", item, err)
+	}
+	if item.seq != id || prNumberOf(t, item.event) != 1 {
+		t.Errorf("want event 1 first after re-driving")
+	}
+	if item.record.Attempts != 0 || item.record.LastError != "" || item.record.Target != "bot" {
+		t.Errorf("re-driven record: got %+v", item.record)
+	}
+
+	err = q.Redrive(id)
+	if !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Errorf("Redrive again: want %v, got %v", ErrDeadLetterNotFound, err)
+	}
+}
+
+func TestRedriveAll(t *testing.T) {
+	q := newTestQueue(t)
+	enqueue(t, q, 1, "a", "b")
+
+	deadLetter(t, q)
+	deadLetter(t, q)
+	checkLen(t, q, 0)
+
+	n, err := q.RedriveAll()
+	if err != nil || n != 2 {
+		t.Errorf("RedriveAll: got %d, %v, want 2", n, err)
+	}
+	checkLen(t, q, 2)
+
+	n, err = q.RedriveAll()
+	if err != nil || n != 0 {
+		t.Errorf("RedriveAll again: got %d, %v, want 0", n, err)
+	}
+}
+
+func TestDeadLetterUndecodable(t *testing.T) {
+	q := newTestQueue(t)
+
+	garbage := []byte("not JSON")
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPending)
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(keyFromSeq(seq), garbage)
+	})
+	if err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	enqueue(t, q, 2, "bot")
+
+	var undecodable []uint64
+	opts := RunOptions{
+		OnUndecodable: func(id uint64, target string, err error) {
+			undecodable = append(undecodable, id)
+		},
+	}
+
+	// skipped for the next event
+	item, _, err := q.next(nil, time.Now(), &opts)
+	if err != nil || item == nil {
+		t.Fatalf("next: got %v, %v", item, err)
+	}
+	if prNumberOf(t, item.event) != 2 {
+		t.Errorf("want event 2 after the undecodable one")
+	}
+	if len(undecodable) != 1 || undecodable[0] != 1 {
+		t.Errorf("OnUndecodable: got %v, want [1]", undecodable)
+	}
+	checkLen(t, q, 1)
+
+	letters, err := q.ListDeadLetters()
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Event != nil || string(letters[0].Raw) != string(garbage) {
+		t.Errorf("ListDeadLetters: got %+v, want the raw record", letters)
+	}
+}
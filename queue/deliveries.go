@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package queue
+
+import (
+	"encoding/json"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// bucketDeliveries maps keys of recent webhook deliveries to when they are
+// first seen, as big-endian Unix nanoseconds.
+var bucketDeliveries = []byte("deliveries")
+
+// EnqueueDelivery is like Enqueue, but drops the event if the delivery of the
+// given key is already seen within the TTL, returning whether it is a
+// duplicate.
+//
+// The delivery is recorded in the same transaction the event is queued in, so
+// it is remembered across crashes exactly when the event is.
+func (q *Queue) EnqueueDelivery(
+	key string,
+	ttl time.Duration,
+	e *v1alpha1.Event,
+	targets ...string,
+) (bool, error) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+
+	now := time.Now()
+	var duplicate bool
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		deliveries := tx.Bucket(bucketDeliveries)
+		if v := deliveries.Get([]byte(key)); v != nil && now.Sub(timeFromBytes(v)) < ttl {
+			duplicate = true
+			return nil
+		}
+
+		err := deliveries.Put([]byte(key), bytesFromTime(now))
+		if err != nil {
+			return err
+		}
+
+		b := tx.Bucket(bucketPending)
+		for _, t := range targets {
+			err := putRecord(b, 0, &record{Event: data, Target: t})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil || duplicate {
+		return duplicate, err
+	}
+
+	q.wakeUp()
+	return false, nil
+}
+
+// PruneDeliveries forgets the deliveries seen longer than the TTL ago,
+// returning the number of deliveries forgotten.
+func (q *Queue) PruneDeliveries(ttl time.Duration) (int, error) {
+	now := time.Now()
+	var result int
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		deliveries := tx.Bucket(bucketDeliveries)
+
+		// deleting while iterating with cursors skips items
+		var expired [][]byte
+		err := deliveries.ForEach(func(k, v []byte) error {
+			if now.Sub(timeFromBytes(v)) >= ttl {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			err := deliveries.Delete(k)
+			if err != nil {
+				return err
+			}
+		}
+		result = len(expired)
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,540 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+// Package queue implements the durable queue of bot events between the
+// webhook endpoints and the bot plugins.
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"sync"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+var (
+	// bucketPending holds events ready to be handled, keyed by sequence
+	// number.
+	bucketPending = []byte("pending")
+	// bucketDelayed holds events waiting for retries, keyed by when they are
+	// due followed by sequence number, so that the earliest is the first.
+	bucketDelayed = []byte("delayed")
+	bucketDead    = []byte("dead")
+)
+
+// record is how events are persisted, along with their delivery state.
+type record struct {
+	Event json.RawMessage `json:"event"`
+	// Target is the name of the handler the event is queued for, empty for
+	// events queued before targets are introduced.
+	Target string `json:"target,omitempty"`
+	// Attempts is the number of failed attempts so far.
+	Attempts int `json:"attempts,omitempty"`
+	// NotBefore is when the next attempt is due, after failed attempts.
+	NotBefore time.Time `json:"not_before,omitempty"`
+	LastError string    `json:"last_error,omitempty"`
+	// FailedAt is when the event is dead-lettered.
+	FailedAt time.Time `json:"failed_at,omitempty"`
+	// Raw is the record as stored, if it is dead-lettered for failing to
+	// decode.
+	Raw []byte `json:"raw,omitempty"`
+}
+
+// Queue is a durable FIFO queue of bot events, persisted in a bbolt database
+// file.
+//
+// Events are only removed after being handled, so events being handled when
+// the process dies are handled again after restart; that is, delivery is
+// at-least-once.
+type Queue struct {
+	db *bolt.DB
+	// notify is signaled on every enqueue, for waking up the dispatcher.
+	notify chan struct{}
+}
+
+// Open opens the queue persisted at the given path, creating it if necessary.
+func Open(path string) (*Queue, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{bucketPending, bucketDelayed, bucketDead, bucketDeliveries} {
+			_, err := tx.CreateBucketIfNotExists(name)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Queue{
+		db:     db,
+		notify: make(chan struct{}, 1),
+	}, nil
+}
+
+// Close closes the queue. It must not be called while Run is running.
+func (q *Queue) Close() error {
+	return q.db.Close()
+}
+
+// Enqueue durably appends the event to the queue, once for each of the
+// targets, so that each target handles it, retries and dead-letters it
+// independently of the others.
+func (q *Queue) Enqueue(e *v1alpha1.Event, targets ...string) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucketPending)
+		for _, t := range targets {
+			err := putRecord(b, 0, &record{Event: data, Target: t})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	q.wakeUp()
+	return nil
+}
+
+func (q *Queue) wakeUp() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+		// the dispatcher is already going to look
+	}
+}
+
+// RunOptions controls how Run dispatches events.
+type RunOptions struct {
+	// Concurrency is the maximum number of events handled at the same time,
+	// defaulting to 1.
+	Concurrency int
+	Retry       RetryPolicy
+
+	// OnRetry, if non-nil, is called when a failed event is scheduled for
+	// another attempt after the given delay.
+	OnRetry func(e *v1alpha1.Event, target string, attempts int, err error, delay time.Duration)
+	// OnDead, if non-nil, is called when a failed event is dead-lettered.
+	OnDead func(e *v1alpha1.Event, target string, attempts int, err error)
+	// OnUndecodable, if non-nil, is called when a queued event fails to
+	// decode, for example after a downgrade, and is dead-lettered with the
+	// given ID. The target is empty if the whole record fails to decode.
+	OnUndecodable func(id uint64, target string, err error)
+}
+
+// Run dispatches queued events to handle, along with the targets they are
+// queued for, until ctx is canceled. Events being
+// handled are waited for before returning, and are passed ctx so they can be
+// canceled too.
+//
+// Events are removed from the queue once handled successfully. Failed events
+// are retried according to the retry policy, and dead-lettered once retries
+// are exhausted. Events failing after ctx is canceled are kept as is, to be
+// handled again on the next run.
+func (q *Queue) Run(
+	ctx context.Context,
+	opts RunOptions,
+	handle func(ctx context.Context, target string, e *v1alpha1.Event) error,
+) error {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	slots := make(chan struct{}, concurrency)
+	var mu sync.Mutex
+	inflight := make(map[uint64]struct{})
+
+	for {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil
+		}
+
+		mu.Lock()
+		item, wait, err := q.next(inflight, time.Now(), &opts)
+		if err == nil && item != nil {
+			inflight[item.seq] = struct{}{}
+		}
+		mu.Unlock()
+		if err != nil {
+			<-slots
+			return err
+		}
+
+		if item == nil {
+			// Nothing to do for now, wait for new events or the next retry.
+			<-slots
+
+			var timer *time.Timer
+			var timerC <-chan time.Time
+			if wait > 0 {
+				timer = time.NewTimer(wait)
+				timerC = timer.C
+			}
+
+			select {
+			case <-q.notify:
+			case <-timerC:
+			case <-ctx.Done():
+				return nil
+			}
+
+			if timer != nil {
+				timer.Stop()
+			}
+			continue
+		}
+
+		wg.Add(1)
+		go func(item *queuedItem) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			err := handle(ctx, item.record.Target, item.event)
+
+			// Failing to update the queue only causes the event to be
+			// handled again, which is allowed.
+			if err == nil || ctx.Err() == nil {
+				_ = q.finish(item, err, &opts)
+			}
+
+			mu.Lock()
+			delete(inflight, item.seq)
+			mu.Unlock()
+
+			if err != nil {
+				// retries may well be due earlier than what the
+				// dispatcher is waiting for
+				q.wakeUp()
+			}
+		}(item)
+	}
+}
+
+type queuedItem struct {
+	seq    uint64
+	event  *v1alpha1.Event
+	record record
+}
+
+// next returns the first queued event due and not being handled. If there is
+// none, a nil item is returned, along with how long to wait for the earliest
+// retry if any, or 0 otherwise.
+//
+// Only the returned event is decoded; events failing to decode are
+// dead-lettered.
+func (q *Queue) next(
+	inflight map[uint64]struct{},
+	now time.Time,
+	opts *RunOptions,
+) (*queuedItem, time.Duration, error) {
+	err := q.promoteDue(now)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for {
+		seq, v, err := q.firstPending(inflight)
+		if err != nil {
+			return nil, 0, err
+		}
+		if v == nil {
+			break
+		}
+
+		item, err := decodeItem(seq, v)
+		if err != nil {
+			err = q.deadLetterUndecodable(seq, v, err, now, opts)
+			if err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		if item.record.NotBefore.After(now) {
+			// retry queued by older versions in place
+			err = q.db.Update(func(tx *bolt.Tx) error {
+				return delayRecord(tx, seq, &item.record)
+			})
+			if err != nil {
+				return nil, 0, err
+			}
+			continue
+		}
+
+		return item, 0, nil
+	}
+
+	wait, err := q.earliestDelay(now)
+	if err != nil {
+		return nil, 0, err
+	}
+	return nil, wait, nil
+}
+
+// firstPending returns the first pending record not being handled, or a nil
+// value if there is none. Only events being handled are skipped, which are at
+// most as many as the concurrency.
+func (q *Queue) firstPending(inflight map[uint64]struct{}) (uint64, []byte, error) {
+	var seq uint64
+	var result []byte
+	err := q.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucketPending).Cursor()
+		for k, v := c.First(); k != nil; k, v = c.Next() {
+			if _, ok := inflight[seqFromKey(k)]; ok {
+				continue
+			}
+
+			seq = seqFromKey(k)
+			// only valid during the transaction
+			result = append([]byte(nil), v...)
+			return nil
+		}
+		return nil
+	})
+	return seq, result, err
+}
+
+func decodeItem(seq uint64, v []byte) (*queuedItem, error) {
+	var r record
+	err := json.Unmarshal(v, &r)
+	if err != nil {
+		return nil, err
+	}
+
+	var e v1alpha1.Event
+	err = json.Unmarshal(r.Event, &e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &queuedItem{
+		seq:    seq,
+		event:  &e,
+		record: r,
+	}, nil
+}
+
+// deadLetterUndecodable moves the pending record that fails to decode to the
+// dead letters, keeping it as is for inspection.
+func (q *Queue) deadLetterUndecodable(seq uint64, v []byte, decodeErr error, now time.Time, opts *RunOptions) error {
+	var r record
+	if json.Unmarshal(v, &r) != nil {
+		r = record{Raw: v}
+	}
+	r.LastError = decodeErr.Error()
+	r.FailedAt = now
+
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		err := tx.Bucket(bucketPending).Delete(keyFromSeq(seq))
+		if err != nil {
+			return err
+		}
+		return putRecord(tx.Bucket(bucketDead), seq, &r)
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.OnUndecodable != nil {
+		opts.OnUndecodable(seq, r.Target, decodeErr)
+	}
+	return nil
+}
+
+// promoteDue moves the delayed records due by now back to pending, at their
+// original positions.
+func (q *Queue) promoteDue(now time.Time) error {
+	due := bytesFromTime(now)
+
+	var hasDue bool
+	err := q.db.View(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(bucketDelayed).Cursor().First()
+		hasDue = k != nil && bytes.Compare(k[:8], due) <= 0
+		return nil
+	})
+	if err != nil || !hasDue {
+		return err
+	}
+
+	return q.db.Update(func(tx *bolt.Tx) error {
+		delayed := tx.Bucket(bucketDelayed)
+		pending := tx.Bucket(bucketPending)
+
+		c := delayed.Cursor()
+		for k, v := c.First(); k != nil && bytes.Compare(k[:8], due) <= 0; k, v = c.First() {
+			err := pending.Put(k[8:], v)
+			if err != nil {
+				return err
+			}
+			err = c.Delete()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// earliestDelay returns how long until the earliest delayed record is due, or
+// 0 if there is none.
+func (q *Queue) earliestDelay(now time.Time) (time.Duration, error) {
+	var result time.Duration
+	err := q.db.View(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(bucketDelayed).Cursor().First()
+		if k == nil {
+			return nil
+		}
+
+		result = timeFromBytes(k[:8]).Sub(now)
+		if result <= 0 {
+			// due already, look again soon
+			result = time.Millisecond
+		}
+		return nil
+	})
+	return result, err
+}
+
+// finish updates the queue according to the result of handling the item.
+func (q *Queue) finish(item *queuedItem, handleErr error, opts *RunOptions) error {
+	key := keyFromSeq(item.seq)
+
+	if handleErr == nil {
+		return q.db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket(bucketPending).Delete(key)
+		})
+	}
+
+	now := time.Now()
+	r := item.record
+	r.Attempts++
+	r.LastError = handleErr.Error()
+
+	if r.Attempts >= opts.Retry.maxAttempts() {
+		r.NotBefore = time.Time{}
+		r.FailedAt = now
+		err := q.db.Update(func(tx *bolt.Tx) error {
+			err := tx.Bucket(bucketPending).Delete(key)
+			if err != nil {
+				return err
+			}
+			return putRecord(tx.Bucket(bucketDead), item.seq, &r)
+		})
+		if err != nil {
+			return err
+		}
+
+		if opts.OnDead != nil {
+			opts.OnDead(item.event, r.Target, r.Attempts, handleErr)
+		}
+		return nil
+	}
+
+	delay := opts.Retry.delay(r.Attempts)
+	r.NotBefore = now.Add(delay)
+	err := q.db.Update(func(tx *bolt.Tx) error {
+		return delayRecord(tx, item.seq, &r)
+	})
+	if err != nil {
+		return err
+	}
+
+	if opts.OnRetry != nil {
+		opts.OnRetry(item.event, r.Target, r.Attempts, handleErr, delay)
+	}
+	return nil
+}
+
+// Len returns the number of queued events, including those being handled or
+// waiting for retries, but not dead-lettered ones.
+func (q *Queue) Len() (int, error) {
+	var result int
+	err := q.db.View(func(tx *bolt.Tx) error {
+		result = tx.Bucket(bucketPending).Stats().KeyN + tx.Bucket(bucketDelayed).Stats().KeyN
+		return nil
+	})
+	return result, err
+}
+
+// delayRecord moves the pending record of the given sequence number to the
+// delayed ones, due at its NotBefore.
+func delayRecord(tx *bolt.Tx, seq uint64, r *record) error {
+	key := keyFromSeq(seq)
+	err := tx.Bucket(bucketPending).Delete(key)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket(bucketDelayed).Put(append(bytesFromTime(r.NotBefore), key...), data)
+}
+
+// putRecord puts the record into the bucket at the given sequence number, or
+// a newly allocated one if seq is 0.
+func putRecord(b *bolt.Bucket, seq uint64, r *record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if seq == 0 {
+		seq, err = b.NextSequence()
+		if err != nil {
+			return err
+		}
+	}
+	return b.Put(keyFromSeq(seq), data)
+}
+
+// Keys are big-endian sequence numbers, so that they sort in FIFO order.
+
+func keyFromSeq(seq uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], seq)
+	return b[:]
+}
+
+func seqFromKey(k []byte) uint64 {
+	return binary.BigEndian.Uint64(k)
+}
+
+// Times are big-endian Unix nanoseconds, so that they sort in order too.
+
+func bytesFromTime(t time.Time) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(t.UnixNano()))
+	return b[:]
+}
+
+func timeFromBytes(b []byte) time.Time {
+	return time.Unix(0, int64(binary.BigEndian.Uint64(b)))
+}
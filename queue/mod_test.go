@@ -0,0 +1,394 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+// testTimeout bounds how long tests wait for the dispatcher.
+const testTimeout = 5 * time.Second
+
+func openTestQueue(t *testing.T, path string) *Queue {
+	t.Helper()
+
+	q, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	return q
+}
+
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+
+	q := openTestQueue(t, filepath.Join(t.TempDir(), "queue.db"))
+	t.Cleanup(func() { _ = q.Close() })
+	return q
+}
+
+// newTestEvent returns an event told apart by the PR number.
+func newTestEvent(number int) *v1alpha1.Event {
+	params := v1alpha1.PROpenedParams{
+		PR: v1alpha1.PR{Number: number},
+	}
+	return params.IntoEvent()
+}
+
+func prNumberOf(t *testing.T, e *v1alpha1.Event) int {
+	t.Helper()
+
+	params, ok := e.PROpened()
+	if !ok {
+		t.Fatalf("unexpected event type %v", e.Type())
+	}
+	return params.PR.Number
+}
+
+func enqueue(t *testing.T, q *Queue, number int, targets ...string) {
+	t.Helper()
+
+	err := q.Enqueue(newTestEvent(number), targets...)
+	if err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+}
+
+func checkLen(t *testing.T, q *Queue, want int) {
+	t.Helper()
+
+	got, err := q.Len()
+	if err != nil {
+		t.Fatalf("Len: %v", err)
+	}
+	if got != want {
+		t.Errorf("Len: got %d, want %d", got, want)
+	}
+}
+
+type handled struct {
+	target string
+	number int
+}
+
+// runQueue runs the queue in the background, returning a function canceling
+// the run and waiting for Run to return.
+func runQueue(
+	t *testing.T,
+	q *Queue,
+	opts RunOptions,
+	handle func(ctx context.Context, target string, e *v1alpha1.Event) error,
+) func() {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- q.Run(ctx, opts, handle)
+	}()
+
+	return func() {
+		t.Helper()
+
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("Run: %v", err)
+			}
+		case <-time.After(testTimeout):
+			t.Fatalf("Run did not return after cancellation")
+		}
+	}
+}
+
+// collect returns a handler sending what is handled to the returned channel.
+func collect(t *testing.T) (chan handled, func(context.Context, string, *v1alpha1.Event) error) {
+	ch := make(chan handled, 16)
+	return ch, func(_ context.Context, target string, e *v1alpha1.Event) error {
+		ch <- handled{target: target, number: prNumberOf(t, e)}
+		return nil
+	}
+}
+
+func receive(t *testing.T, ch chan handled, n int) []handled {
+	t.Helper()
+
+	var result []handled
+	for len(result) < n {
+		select {
+		case x := <-ch:
+			result = append(result, x)
+		case <-time.After(testTimeout):
+			t.Fatalf("got %d handled events, want %d", len(result), n)
+		}
+	}
+	return result
+}
+
+func TestEnqueueRun(t *testing.T) {
+	q := newTestQueue(t)
+
+	for i := 1; i <= 3; i++ {
+		enqueue(t, q, i, "bot")
+	}
+	checkLen(t, q, 3)
+
+	ch, handle := collect(t)
+	stop := runQueue(t, q, RunOptions{}, handle)
+	got := receive(t, ch, 3)
+
+	want := []handled{{"bot", 1}, {"bot", 2}, {"bot", 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// events enqueued while running are handled too
+	enqueue(t, q, 4, "bot")
+	got = receive(t, ch, 1)
+	if got[0] != (handled{"bot", 4}) {
+		t.Errorf("got %v, want event 4", got[0])
+	}
+
+	stop()
+	checkLen(t, q, 0)
+}
+
+func TestEnqueueTargets(t *testing.T) {
+	q := newTestQueue(t)
+
+	enqueue(t, q, 1, "a", "b")
+	checkLen(t, q, 2)
+
+	// no targets, nothing queued
+	enqueue(t, q, 2)
+	checkLen(t, q, 2)
+
+	ch := make(chan handled, 16)
+	stop := runQueue(t, q, RunOptions{Retry: RetryPolicy{MaxAttempts: 1}},
+		func(_ context.Context, target string, e *v1alpha1.Event) error {
+			ch <- handled{target: target, number: prNumberOf(t, e)}
+			if target == "b" {
+				return errors.New("b is broken")
+			}
+			return nil
+		})
+	got := receive(t, ch, 2)
+	stop()
+
+	want := []handled{{"a", 1}, {"b", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	// each target fails on its own
+	checkLen(t, q, 0)
+	letters, err := q.ListDeadLetters()
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(letters) != 1 || letters[0].Target != "b" {
+		t.Errorf("want one dead letter for b, got %+v", letters)
+	}
+}
+
+func TestPromoteDue(t *testing.T) {
+	q := newTestQueue(t)
+
+	enqueue(t, q, 1, "bot")
+	enqueue(t, q, 2, "bot")
+
+	now := time.Now()
+	item, _, err := q.next(nil, now, &RunOptions{})
+	if err != nil || item == nil {
+		t.Fatalf("next: got %v, %v", item, err)
+	}
+	if n := prNumberOf(t, item.event); n != 1 {
+		t.Fatalf("next: got event %d, want 1", n)
+	}
+
+	// delay the first event
+	due := now.Add(time.Minute)
+	item.record.NotBefore = due
+	err = q.db.Update(func(tx *bolt.Tx) error {
+		return delayRecord(tx, item.seq, &item.record)
+	})
+	if err != nil {
+		t.Fatalf("delayRecord: %v", err)
+	}
+	checkLen(t, q, 2)
+
+	// not due yet
+	err = q.promoteDue(now)
+	if err != nil {
+		t.Fatalf("promoteDue: %v", err)
+	}
+	wait, err := q.earliestDelay(now)
+	if err != nil {
+		t.Fatalf("earliestDelay: %v", err)
+	}
+	if wait != time.Minute {
+		t.Errorf("earliestDelay: got %v, want %v", wait, time.Minute)
+	}
+	_, v, err := q.firstPending(nil)
+	if err != nil {
+		t.Fatalf("firstPending: %v", err)
+	}
+	if item, _ := decodeItem(0, v); prNumberOf(t, item.event) != 2 {
+		t.Errorf("want event 2 pending first while event 1 is delayed")
+	}
+
+	// due, and back to its original position
+	err = q.promoteDue(due)
+	if err != nil {
+		t.Fatalf("promoteDue: %v", err)
+	}
+	wait, err = q.earliestDelay(due)
+	if err != nil || wait != 0 {
+		t.Errorf("earliestDelay: got %v, %v, want nothing delayed", wait, err)
+	}
+	seq, v, err := q.firstPending(nil)
+	if err != nil {
+		t.Fatalf("firstPending: %v", err)
+	}
+	if item, _ := decodeItem(seq, v); seq != item.seq || prNumberOf(t, item.event) != 1 {
+		t.Errorf("want event 1 pending first after promotion")
+	}
+	checkLen(t, q, 2)
+}
+
+func TestNextSkipsInflight(t *testing.T) {
+	q := newTestQueue(t)
+
+	enqueue(t, q, 1, "bot")
+	enqueue(t, q, 2, "bot")
+
+	first, _, err := q.next(nil, time.Now(), &RunOptions{})
+	if err != nil || first == nil {
+		t.Fatalf("next: got %v, %v", first, err)
+	}
+
+	inflight := map[uint64]struct{}{first.seq: {}}
+	second, _, err := q.next(inflight, time.Now(), &RunOptions{})
+	if err != nil || second == nil {
+		t.Fatalf("next: got %v, %v", second, err)
+	}
+	if prNumberOf(t, second.event) != 2 {
+		t.Errorf("want event 2 while event 1 is being handled")
+	}
+
+	inflight[second.seq] = struct{}{}
+	third, wait, err := q.next(inflight, time.Now(), &RunOptions{})
+	if err != nil || third != nil || wait != 0 {
+		t.Errorf("next: got %v, %v, %v, want nothing", third, wait, err)
+	}
+}
+
+// TestRedeliveryAfterReopen checks events are delivered at least once, that
+// is, events being handled when the run is canceled are kept, and handled
+// again by the next run, even after reopening the queue.
+func TestRedeliveryAfterReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.db")
+	q := openTestQueue(t, path)
+
+	enqueue(t, q, 1, "bot")
+	enqueue(t, q, 2, "bot")
+
+	var failed bool
+	var once sync.Once
+	started := make(chan struct{})
+	stop := runQueue(t, q, RunOptions{
+		OnRetry: func(*v1alpha1.Event, string, int, error, time.Duration) { failed = true },
+		OnDead:  func(*v1alpha1.Event, string, int, error) { failed = true },
+	}, func(ctx context.Context, _ string, e *v1alpha1.Event) error {
+		once.Do(func() { close(started) })
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	select {
+	case <-started:
+	case <-time.After(testTimeout):
+		t.Fatalf("event not handled")
+	}
+	stop()
+
+	if failed {
+		t.Errorf("canceled event retried or dead-lettered")
+	}
+	checkLen(t, q, 2)
+
+	err := q.Close()
+	if err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	q = openTestQueue(t, path)
+	defer q.Close()
+	checkLen(t, q, 2)
+
+	ch, handle := collect(t)
+	stop = runQueue(t, q, RunOptions{}, handle)
+	got := receive(t, ch, 2)
+	stop()
+
+	want := []handled{{"bot", 1}, {"bot", 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	checkLen(t, q, 0)
+
+	letters, err := q.ListDeadLetters()
+	if err != nil || len(letters) != 0 {
+		t.Errorf("ListDeadLetters: got %v, %v, want none", letters, err)
+	}
+}
+
+func TestRunConcurrency(t *testing.T) {
+	q := newTestQueue(t)
+
+	for i := 1; i <= 3; i++ {
+		enqueue(t, q, i, "bot")
+	}
+
+	var mu sync.Mutex
+	var running, maxRunning int
+	release := make(chan struct{})
+	ch, record := collect(t)
+	stop := runQueue(t, q, RunOptions{Concurrency: 2}, func(ctx context.Context, target string, e *v1alpha1.Event) error {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		_ = record(ctx, target, e)
+		<-release
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		return nil
+	})
+
+	receive(t, ch, 2)
+	close(release)
+	receive(t, ch, 1)
+	stop()
+
+	if maxRunning != 2 {
+		t.Errorf("got %d events handled at the same time, want 2", maxRunning)
+	}
+	checkLen(t, q, 0)
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package queue
+
+import (
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+)
+
+// Defaults of RetryPolicy.
+const (
+	DefaultMaxAttempts     = 5
+	DefaultInitialInterval = 10 * time.Second
+	DefaultMaxInterval     = 10 * time.Minute
+)
+
+// RetryPolicy controls retries of failed events, with exponential backoff and
+// jitter. Zero fields take the defaults.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts before an event is
+	// dead-lettered, including the first one. 1 means no retries.
+	MaxAttempts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+}
+
+func (p *RetryPolicy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return DefaultMaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+// delay returns the delay before the next attempt, after the given number of
+// failed attempts.
+func (p *RetryPolicy) delay(attempts int) time.Duration {
+	b := backoff.NewExponentialBackOff()
+	b.InitialInterval = DefaultInitialInterval
+	if p.InitialInterval > 0 {
+		b.InitialInterval = p.InitialInterval
+	}
+	b.MaxInterval = DefaultMaxInterval
+	if p.MaxInterval > 0 {
+		b.MaxInterval = p.MaxInterval
+	}
+	b.Multiplier = 2
+	// never give up, as the number of attempts is limited instead
+	b.MaxElapsedTime = 0
+	b.Reset()
+
+	var result time.Duration
+	for i := 0; i < attempts; i++ {
+		result = b.NextBackOff()
+	}
+	return result
+}
@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: GPL-3.0-or-later
+
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/xen0n/brickbot/bot/v1alpha1"
+)
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	var p RetryPolicy
+	if got := p.maxAttempts(); got != DefaultMaxAttempts {
+		t.Errorf("maxAttempts: got %d, want %d", got, DefaultMaxAttempts)
+	}
+
+	// with jitter of up to 50% either way
+	if got := p.delay(1); got < DefaultInitialInterval/2 || got > DefaultInitialInterval*3/2 {
+		t.Errorf("delay(1): got %v, want around %v", got, DefaultInitialInterval)
+	}
+}
+
+func TestRetryPolicyDelay(t *testing.T) {
+	p := RetryPolicy{
+		MaxAttempts:     10,
+		InitialInterval: time.Second,
+		MaxInterval:     10 * time.Second,
+	}
+
+	testcases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		// capped
+		{5, 10 * time.Second},
+		{9, 10 * time.Second},
+	}
+
+	for _, tc := range testcases {
+		// the jitter is random, so try a few times
+		for i := 0; i < 20; i++ {
+			got := p.delay(tc.attempts)
+			if got < tc.want/2 || got > tc.want*3/2 {
+				t.Errorf("delay(%d): got %v, want around %v", tc.attempts, got, tc.want)
+				break
+			}
+		}
+	}
+}
+
+func TestFinishRetry(t *testing.T) {
+	q := newTestQueue(t)
+	enqueue(t, q, 1, "bot")
+
+	var retried []int
+	var delays []time.Duration
+	opts := RunOptions{
+		Retry: RetryPolicy{MaxAttempts: 3, InitialInterval: time.Minute},
+		OnRetry: func(e *v1alpha1.Event, target string, attempts int, err error, delay time.Duration) {
+			if target != "bot" || prNumberOf(t, e) != 1 || err.Error() != "boom" {
+				t.Errorf("OnRetry: unexpected call with %s, %v", target, err)
+			}
+			retried = append(retried, attempts)
+			delays = append(delays, delay)
+		},
+		OnDead: func(*v1alpha1.Event, string, int, error) {
+			t.Errorf("OnDead: unexpected call")
+		},
+	}
+
+	item, _, err := q.next(nil, time.Now(), &opts)
+	if err != nil || item == nil {
+		t.Fatalf("next: got %v, %v", item, err)
+	}
+
+	before := time.Now()
+	err = q.finish(item, errors.New("boom"), &opts)
+	if err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+
+	if len(retried) != 1 || retried[0] != 1 {
+		t.Fatalf("OnRetry: got attempts %v, want [1]", retried)
+	}
+	checkLen(t, q, 1)
+
+	// waiting for the retry, not pending
+	item, wait, err := q.next(nil, time.Now(), &opts)
+	if err != nil || item != nil {
+		t.Fatalf("next: got %v, %v, want nothing due", item, err)
+	}
+	if wait <= 0 || wait > delays[0] {
+		t.Errorf("next: got wait %v, want up to %v", wait, delays[0])
+	}
+
+	// due after the delay, with the failure recorded
+	item, _, err = q.next(nil, before.Add(delays[0]+time.Second), &opts)
+	if err != nil || item == nil {
+		t.Fatalf("next: got %v, %v, want the retry", item, err)
+	}
+	if item.record.Attempts != 1 || item.record.LastError != "boom" || item.record.Target != "bot" {
+		t.Errorf("retried record: got %+v", item.record)
+	}
+	if item.record.NotBefore.Before(before.Add(delays[0])) {
+		t.Errorf("retried record: due at %v, before the delay", item.record.NotBefore)
+	}
+}
+
+func TestFinishDead(t *testing.T) {
+	q := newTestQueue(t)
+	enqueue(t, q, 1, "bot")
+
+	var dead []int
+	opts := RunOptions{
+		Retry: RetryPolicy{MaxAttempts: 2, InitialInterval: time.Minute},
+		OnDead: func(e *v1alpha1.Event, target string, attempts int, err error) {
+			if target != "bot" || prNumberOf(t, e) != 1 || err.Error() != "boom 2" {
+				t.Errorf("OnDead: unexpected call with %s, %v", target, err)
+			}
+			dead = append(dead, attempts)
+		},
+	}
+
+	now := time.Now()
+	for i := 1; i <= 2; i++ {
+		item, _, err := q.next(nil, now, &opts)
+		if err != nil || item == nil {
+			t.Fatalf("attempt %d: next: got %v, %v", i, item, err)
+		}
+
+		err = q.finish(item, fmt.Errorf("boom %d", i), &opts)
+		if err != nil {
+			t.Fatalf("attempt %d: finish: %v", i, err)
+		}
+		now = now.Add(time.Hour)
+	}
+
+	if len(dead) != 1 || dead[0] != 2 {
+		t.Fatalf("OnDead: got attempts %v, want [2]", dead)
+	}
+	checkLen(t, q, 0)
+
+	letters, err := q.ListDeadLetters()
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("ListDeadLetters: got %d, want 1", len(letters))
+	}
+
+	l := letters[0]
+	if l.Event == nil || prNumberOf(t, l.Event) != 1 {
+		t.Errorf("dead letter: got event %v, want event 1", l.Event)
+	}
+	if l.Target != "bot" || l.Attempts != 2 || l.LastError != "boom 2" || l.FailedAt.IsZero() {
+		t.Errorf("dead letter: got %+v", l)
+	}
+}
+
+// TestRunRetries checks events are retried with backoff until they succeed or
+// run out of attempts.
+func TestRunRetries(t *testing.T) {
+	q := newTestQueue(t)
+	enqueue(t, q, 1, "flaky")
+	enqueue(t, q, 2, "broken")
+
+	attempts := make(map[string]int)
+	dead := make(chan string, 1)
+	ch := make(chan handled, 16)
+	stop := runQueue(t, q, RunOptions{
+		Retry: RetryPolicy{MaxAttempts: 3, InitialInterval: time.Millisecond, MaxInterval: 10 * time.Millisecond},
+		OnDead: func(_ *v1alpha1.Event, target string, _ int, _ error) {
+			dead <- target
+		},
+	}, func(_ context.Context, target string, e *v1alpha1.Event) error {
+		attempts[target]++
+		if target == "flaky" && attempts[target] == 2 {
+			ch <- handled{target: target, number: prNumberOf(t, e)}
+			return nil
+		}
+		return errors.New("boom")
+	})
+
+	receive(t, ch, 1)
+	select {
+	case target := <-dead:
+		if target != "broken" {
+			t.Errorf("OnDead: got %s, want broken", target)
+		}
+	case <-time.After(testTimeout):
+		t.Fatalf("event not dead-lettered")
+	}
+	stop()
+
+	if attempts["flaky"] != 2 || attempts["broken"] != 3 {
+		t.Errorf("got attempts %v", attempts)
+	}
+	checkLen(t, q, 0)
+}